@@ -0,0 +1,97 @@
+package cache
+
+// ARC, with the sizes of the lists measured in cost.
+type arc struct {
+	c int64 // Capacity.
+	p int64 // Target cost of t1, adapted by the ghost hits.
+
+	t1 list // Resident, seen once recently.
+	t2 list // Resident, seen at least twice recently.
+	b1 list // Ghosts evicted from t1, with keys and costs only.
+	b2 list // Ghosts evicted from t2.
+
+	ghosts map[interface{}]*entry
+}
+
+func newARC(c int64) *arc {
+	p := &arc{c: c}
+	p.clear()
+	return p
+}
+
+func (p *arc) add(e *entry) {
+	g := p.ghosts[e.key]
+	if g == nil {
+		p.t1.pushFront(e)
+		return
+	}
+	// A ghost hit: the list it was evicted from should have been larger.
+	if g.list == &p.b1 {
+		p.p += ratio(p.b2.cost, p.b1.cost) * g.cost
+		if p.p > p.c {
+			p.p = p.c
+		}
+	} else {
+		p.p -= ratio(p.b1.cost, p.b2.cost) * g.cost
+		if p.p < 0 {
+			p.p = 0
+		}
+	}
+	p.dropGhost(g)
+	p.t2.pushFront(e)
+}
+
+// Return max(a / b, 1).
+func ratio(a, b int64) int64 {
+	if b <= 0 || a <= b {
+		return 1
+	}
+	return a / b
+}
+
+func (p *arc) touch(e *entry) {
+	e.list.remove(e)
+	p.t2.pushFront(e)
+}
+
+func (p *arc) remove(e *entry) {
+	e.list.remove(e)
+}
+
+func (p *arc) evict() *entry {
+	from, ghostList := &p.t2, &p.b2
+	if p.t1.n > 0 && (p.t1.cost > p.p || p.t2.n == 0) {
+		from, ghostList = &p.t1, &p.b1
+	}
+	e := from.back()
+	if e == nil {
+		return nil
+	}
+	from.remove(e)
+	g := &entry{key: e.key, cost: e.cost}
+	ghostList.pushFront(g)
+	p.ghosts[g.key] = g
+	// Keep t1 + b1 <= c, and t1 + t2 + b1 + b2 <= 2c.
+	for p.b1.n > 0 && p.t1.cost+p.b1.cost > p.c {
+		p.dropGhost(p.b1.back())
+	}
+	for p.b2.n > 0 &&
+		p.t1.cost+p.t2.cost+p.b1.cost+p.b2.cost > 2*p.c {
+		p.dropGhost(p.b2.back())
+	}
+	return e
+}
+
+func (p *arc) dropGhost(g *entry) {
+	g.list.remove(g)
+	delete(p.ghosts, g.key)
+}
+
+func (p *arc) clear() {
+	p.p = 0
+	p.t1.init()
+	p.t2.init()
+	p.b1.init()
+	p.b2.init()
+	p.ghosts = make(map[interface{}]*entry)
+}
@@ -0,0 +1,405 @@
+// Package cache provides in-memory caches with LRU, LFU, ARC
+// and 2Q eviction policies.
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/internal/expiry"
+)
+
+// Why an item leaves the cache.
+type EvictReason int8
+
+const (
+	EvictCapacity EvictReason = iota // Evicted by the policy to make room.
+	EvictExpired                     // Its TTL is over.
+	EvictDeleted                     // Deleted by Delete or Clear.
+	EvictReplaced                    // Its value is replaced by Put.
+)
+
+var evictReasonNames = [...]string{"capacity", "expired", "deleted",
+	"replaced"}
+
+func (r EvictReason) String() string {
+	if r >= 0 && int(r) < len(evictReasonNames) {
+		return evictReasonNames[r]
+	}
+	return "unknown"
+}
+
+type Options struct {
+	Policy Policy
+
+	// The capacity, as the maximum total cost of the items. Required.
+	MaxCost int64
+
+	// The cost of an item. Default: 1 for each item,
+	// so that MaxCost is the maximum number of items.
+	Cost func(key, value interface{}) int64
+
+	// The default time to live of the items. 0 means no expiry.
+	TTL time.Duration
+
+	// Called with each item leaving the cache, or with the old value
+	// when it is replaced. It is called without the lock held,
+	// after the operation causing it.
+	OnEvict func(key, value interface{}, reason EvictReason)
+
+	// The clock. Default: time.Now.
+	Now func() time.Time
+}
+
+type entry struct {
+	key, value interface{}
+	cost       int64
+	exp        *expiry.Entry // nil if the item doesn't expire.
+
+	// For list-based policies.
+	prev, next *entry
+	list       *list
+
+	// For PolicyLFU.
+	freq, tick uint64
+	idx        int
+}
+
+// Order by frequency, and then by recency, for PolicyLFU.
+func (e *entry) Less(another interface{}) bool {
+	a := another.(*entry)
+	return e.freq < a.freq || e.freq == a.freq && e.tick < a.tick
+}
+
+func (e *entry) Index() int {
+	return e.idx
+}
+
+func (e *entry) UpdateIndex(idx int) {
+	e.idx = idx
+}
+
+// An item leaving the cache, to be reported to OnEvict.
+type eviction struct {
+	key, value interface{}
+	reason     EvictReason
+}
+
+type Cache struct {
+	policy  policy
+	maxCost int64
+	costFn  func(key, value interface{}) int64
+	ttl     time.Duration
+	onEvict func(key, value interface{}, reason EvictReason)
+	now     func() time.Time
+
+	items map[interface{}]*entry
+	cost  int64
+	exp   *expiry.Index
+	stats Stats
+	calls map[interface{}]*call // In-flight loads of GetOrLoad.
+
+	lock    *sync.Mutex
+	pending []eviction // Reported to onEvict after unlock.
+}
+
+func New(opts *Options, isSync bool) *Cache {
+	c, err := NewE(opts, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Same as New, but return an error instead of panic.
+func NewE(opts *Options, isSync bool) (*Cache, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("%w: options are nil, and max cost is required",
+			gocontainer.ErrZeroCapacity)
+	} else if opts.MaxCost < 0 {
+		return nil, fmt.Errorf("%w: max cost %d",
+			gocontainer.ErrNegativeCapacity, opts.MaxCost)
+	} else if opts.MaxCost == 0 {
+		return nil, fmt.Errorf("%w: max cost is required",
+			gocontainer.ErrZeroCapacity)
+	}
+	p, err := newPolicy(opts.Policy, opts.MaxCost)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		policy:  p,
+		maxCost: opts.MaxCost,
+		costFn:  opts.Cost,
+		ttl:     opts.TTL,
+		onEvict: opts.OnEvict,
+		now:     opts.Now,
+		items:   make(map[interface{}]*entry),
+		exp:     expiry.NewIndex(),
+		calls:   make(map[interface{}]*call),
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+	if isSync {
+		c.lock = new(sync.Mutex)
+	}
+	return c, nil
+}
+
+// Return the number of items.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.lockAndPurge()
+	defer c.unlock()
+	return len(c.items)
+}
+
+// Return the total cost of the items.
+func (c *Cache) Cost() int64 {
+	if c == nil {
+		return 0
+	}
+	c.lockAndPurge()
+	defer c.unlock()
+	return c.cost
+}
+
+// Return the value of key, and record the access for the policy.
+func (c *Cache) Get(key interface{}) (value interface{}, ok bool) {
+	if c == nil {
+		return
+	}
+	c.lockAndPurge()
+	defer c.unlock()
+	return c.get(key)
+}
+
+// Same as Get, but neither record the access nor update the stats.
+func (c *Cache) Peek(key interface{}) (value interface{}, ok bool) {
+	if c == nil {
+		return
+	}
+	c.lockAndPurge()
+	defer c.unlock()
+	if e := c.items[key]; e != nil {
+		return e.value, true
+	}
+	return
+}
+
+// Set the value of key, with the default TTL.
+// It panics if the cost of the item is negative or greater than MaxCost.
+func (c *Cache) Put(key, value interface{}) {
+	if err := c.TryPut(key, value); err != nil {
+		panic(err)
+	}
+}
+
+// Same as Put, but return an error instead of panic.
+func (c *Cache) TryPut(key, value interface{}) error {
+	if c == nil {
+		return gocontainer.ErrNilContainer
+	}
+	return c.TryPutWithTTL(key, value, c.ttl)
+}
+
+// Same as Put, but the item expires after ttl. 0 means no expiry.
+func (c *Cache) PutWithTTL(key, value interface{}, ttl time.Duration) {
+	if err := c.TryPutWithTTL(key, value, ttl); err != nil {
+		panic(err)
+	}
+}
+
+// Same as PutWithTTL, but return an error instead of panic.
+// It returns an error wrapping gocontainer.ErrFull if the cost of the item
+// is greater than MaxCost.
+func (c *Cache) TryPutWithTTL(key, value interface{},
+	ttl time.Duration) error {
+	if c == nil {
+		return gocontainer.ErrNilContainer
+	}
+	c.lockAndPurge()
+	defer c.unlock()
+	c.invalidateLoad(key)
+	return c.put(key, value, ttl)
+}
+
+// Delete key, and return true if it was in the cache.
+func (c *Cache) Delete(key interface{}) bool {
+	if c == nil {
+		return false
+	}
+	c.lockAndPurge()
+	defer c.unlock()
+	c.invalidateLoad(key)
+	e := c.items[key]
+	if e == nil {
+		return false
+	}
+	c.policy.remove(e)
+	c.removeEntry(e, EvictDeleted)
+	return true
+}
+
+// Remove the expired items, and return the number of them.
+// Expired items are also removed lazily when the cache is accessed,
+// so it's unnecessary to call it in most cases.
+func (c *Cache) Sweep() int {
+	if c == nil {
+		return 0
+	}
+	c.lockOnly()
+	defer c.unlock()
+	return c.purge()
+}
+
+// Delete all the items. The stats are kept.
+func (c *Cache) Clear() {
+	if c == nil {
+		return
+	}
+	c.lockAndPurge()
+	defer c.unlock()
+	for _, cl := range c.calls {
+		cl.isStale = true
+	}
+	for _, e := range c.items {
+		c.pending = append(c.pending, eviction{e.key, e.value, EvictDeleted})
+	}
+	c.items = make(map[interface{}]*entry)
+	c.cost = 0
+	c.exp.Clear()
+	c.policy.clear()
+}
+
+// Lock the cache, and remove the expired items.
+func (c *Cache) lockAndPurge() {
+	c.lockOnly()
+	c.purge()
+}
+
+func (c *Cache) lockOnly() {
+	if c.lock != nil {
+		c.lock.Lock()
+	}
+}
+
+// Remove the expired items, and return the number of them.
+// Call it with the lock held.
+func (c *Cache) purge() int {
+	if c.exp.Len() == 0 {
+		return 0
+	}
+	n := 0
+	c.exp.PopExpired(c.now(), func(item interface{}) {
+		e := item.(*entry)
+		e.exp = nil
+		c.policy.remove(e)
+		c.removeEntry(e, EvictExpired)
+		n++
+	})
+	c.stats.Expirations += int64(n)
+	return n
+}
+
+// Unlock the cache, and then report the pending evictions.
+func (c *Cache) unlock() {
+	pending := c.pending
+	c.pending = nil
+	if c.lock != nil {
+		c.lock.Unlock()
+	}
+	if c.onEvict != nil {
+		for _, ev := range pending {
+			c.onEvict(ev.key, ev.value, ev.reason)
+		}
+	}
+}
+
+// Call it with the lock held.
+func (c *Cache) get(key interface{}) (value interface{}, ok bool) {
+	e := c.items[key]
+	if e == nil {
+		c.stats.Misses++
+		return
+	}
+	c.stats.Hits++
+	c.policy.touch(e)
+	return e.value, true
+}
+
+// Call it with the lock held.
+func (c *Cache) put(key, value interface{}, ttl time.Duration) error {
+	cost := int64(1)
+	if c.costFn != nil {
+		cost = c.costFn(key, value)
+	}
+	if cost < 0 {
+		return fmt.Errorf("gocontainer: cost %d of key %v is negative",
+			cost, key)
+	} else if cost > c.maxCost {
+		return fmt.Errorf("%w: cost %d of key %v > max cost %d",
+			gocontainer.ErrFull, cost, key, c.maxCost)
+	}
+	e := c.items[key]
+	if e != nil {
+		c.pending = append(c.pending, eviction{key, e.value, EvictReplaced})
+		c.cost += cost - e.cost
+		if e.list != nil {
+			e.list.cost += cost - e.cost
+		}
+		e.value, e.cost = value, cost
+		c.exp.Remove(e.exp)
+		e.exp = nil
+		c.policy.touch(e)
+		c.setTTL(e, ttl)
+		// It may evict e itself if it has grown too costly.
+		c.evict(0)
+		return nil
+	}
+	// Make room before adding it, so that it is not evicted at once.
+	c.evict(cost)
+	e = &entry{key: key, value: value, cost: cost}
+	c.items[key] = e
+	c.cost += cost
+	c.policy.add(e)
+	c.setTTL(e, ttl)
+	return nil
+}
+
+// Call it with the lock held.
+func (c *Cache) setTTL(e *entry, ttl time.Duration) {
+	if ttl > 0 {
+		e.exp = c.exp.Add(e, c.now().Add(ttl))
+	}
+}
+
+// Evict items until there is room for the extra cost.
+// Call it with the lock held.
+func (c *Cache) evict(extra int64) {
+	for c.cost+extra > c.maxCost {
+		e := c.policy.evict()
+		if e == nil {
+			panic(fmt.Errorf("gocontainer: cost %d > max cost %d, "+
+				"but the policy has nothing to evict",
+				c.cost+extra, c.maxCost))
+		}
+		c.removeEntry(e, EvictCapacity)
+		c.stats.Evictions++
+	}
+}
+
+// Remove e, which is detached from the policy, from the cache.
+// Call it with the lock held.
+func (c *Cache) removeEntry(e *entry, reason EvictReason) {
+	delete(c.items, e.key)
+	c.cost -= e.cost
+	c.exp.Remove(e.exp)
+	e.exp = nil
+	c.pending = append(c.pending, eviction{e.key, e.value, reason})
+}
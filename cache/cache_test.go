@@ -0,0 +1,360 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+func keysOf(c *Cache, n int) []int {
+	var keys []int
+	for i := 0; i < n; i++ {
+		if _, ok := c.Peek(i); ok {
+			keys = append(keys, i)
+		}
+	}
+	return keys
+}
+
+func checkKeys(t *testing.T, c *Cache, n int, want ...int) {
+	t.Helper()
+	keys := keysOf(c, n)
+	if fmt.Sprint(keys) != fmt.Sprint(want) {
+		t.Fatalf("Keys: %v, want %v", keys, want)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	c := New(&Options{Policy: PolicyLRU, MaxCost: 3}, false)
+	for i := 0; i < 3; i++ {
+		c.Put(i, i)
+	}
+	c.Get(0)
+	c.Put(3, 3)
+	checkKeys(t, c, 4, 0, 2, 3)
+	c.Put(2, 20) // Replace, and make 2 the most recently used.
+	c.Put(4, 4)
+	checkKeys(t, c, 5, 2, 3, 4)
+	if v, ok := c.Get(2); !ok || v != 20 {
+		t.Errorf("Get(2) = %v, %t", v, ok)
+	}
+	s := c.Stats()
+	if s.Hits != 2 || s.Misses != 0 || s.Evictions != 2 {
+		t.Errorf("Stats: %+v", s)
+	}
+}
+
+func TestLFU(t *testing.T) {
+	c := New(&Options{Policy: PolicyLFU, MaxCost: 3}, false)
+	for i := 0; i < 3; i++ {
+		c.Put(i, i)
+	}
+	c.Get(0)
+	c.Get(0)
+	c.Get(2)
+	// 1 is the least frequently used.
+	c.Put(3, 3)
+	checkKeys(t, c, 4, 0, 2, 3)
+	// 2 and 3 are equally frequently used, and 2 is less recently used.
+	c.Get(3)
+	c.Put(4, 4)
+	checkKeys(t, c, 5, 0, 3, 4)
+}
+
+func TestCost(t *testing.T) {
+	c := New(&Options{
+		MaxCost: 10,
+		Cost: func(key, value interface{}) int64 {
+			return int64(len(value.(string)))
+		},
+	}, false)
+	c.Put(0, "aaaa")
+	c.Put(1, "bbbb")
+	c.Put(2, "cc")
+	if c.Cost() != 10 || c.Len() != 3 {
+		t.Fatalf("Cost: %d, Len: %d", c.Cost(), c.Len())
+	}
+	c.Put(3, "d")
+	checkKeys(t, c, 4, 1, 2, 3)
+	c.Put(2, "cccccc") // Grows, and pushes 1 out.
+	checkKeys(t, c, 4, 2, 3)
+	if c.Cost() != 7 {
+		t.Errorf("Cost: %d", c.Cost())
+	}
+	if err := c.TryPut(4, "eeeeeeeeeee"); !errors.Is(err, gocontainer.ErrFull) {
+		t.Errorf("TryPut: %v", err)
+	}
+	checkKeys(t, c, 5, 2, 3)
+}
+
+// A scan of items accessed once should not flush the frequently used ones.
+func TestScanResistance(t *testing.T) {
+	for _, p := range []Policy{PolicyARC, Policy2Q} {
+		t.Run(p.String(), func(t *testing.T) {
+			c := New(&Options{Policy: p, MaxCost: 100}, false)
+			hot := func() {
+				for i := 0; i < 20; i++ {
+					if _, ok := c.Get(i); !ok {
+						c.Put(i, i)
+					}
+				}
+			}
+			cold := func(start, n int) {
+				for i := start; i < start+n; i++ {
+					c.Put(i, i)
+				}
+			}
+			// Let the hot set be seen again after some other items.
+			hot()
+			hot()
+			cold(1000, 100)
+			hot()
+			// A long scan of items seen once.
+			cold(2000, 500)
+			c.ResetStats()
+			for i := 0; i < 20; i++ {
+				c.Get(i)
+			}
+			if s := c.Stats(); s.Hits < 18 {
+				t.Errorf("Hits of the hot set: %d", s.Hits)
+			}
+			if c.Len() != 100 || c.Cost() != 100 {
+				t.Errorf("Len: %d, Cost: %d", c.Len(), c.Cost())
+			}
+		})
+	}
+	// LRU is not resistant.
+	c := New(&Options{Policy: PolicyLRU, MaxCost: 100}, false)
+	for i := 0; i < 20; i++ {
+		c.Put(i, i)
+	}
+	for i := 0; i < 200; i++ {
+		c.Put(1000+i, i)
+	}
+	checkKeys(t, c, 20)
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	type ev struct {
+		key    interface{}
+		reason EvictReason
+	}
+	var evs []ev
+	c := New(&Options{
+		MaxCost: 2,
+		TTL:     time.Minute,
+		OnEvict: func(key, value interface{}, reason EvictReason) {
+			evs = append(evs, ev{key, reason})
+		},
+		Now: func() time.Time { return now },
+	}, true)
+	c.Put(0, 0)
+	c.PutWithTTL(1, 1, 0) // Never expires.
+	now = now.Add(30 * time.Second)
+	c.Put(0, 10) // Replaced, and its TTL is renewed.
+	now = now.Add(time.Minute - time.Second)
+	checkKeys(t, c, 2, 0, 1)
+	now = now.Add(time.Second)
+	checkKeys(t, c, 2, 1)
+	c.Put(2, 2)
+	c.Put(3, 3) // Evicts 1.
+	c.Delete(2)
+	now = now.Add(time.Hour)
+	if n := c.Sweep(); n != 1 {
+		t.Errorf("Sweep: %d", n)
+	}
+	want := []ev{{0, EvictReplaced}, {0, EvictExpired}, {1, EvictCapacity},
+		{2, EvictDeleted}, {3, EvictExpired}}
+	if fmt.Sprint(evs) != fmt.Sprint(want) {
+		t.Errorf("Evictions: %v, want %v", evs, want)
+	}
+	if s := c.Stats(); s.Expirations != 2 || s.Evictions != 1 {
+		t.Errorf("Stats: %+v", s)
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := New(&Options{Policy: PolicyARC, MaxCost: 10}, true)
+	var calls int32
+	release := make(chan struct{})
+	load := func(key interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		if key == -1 {
+			return nil, errors.New("not found")
+		}
+		return key.(int) * 10, nil
+	}
+	const n = 8
+	var wg sync.WaitGroup
+	results := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := c.GetOrLoad(7, load)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = v
+		}(i)
+	}
+	for c.Stats().SharedLoads < n-1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("Loads: %d", calls)
+	}
+	for _, v := range results {
+		if v != 70 {
+			t.Errorf("Results: %v", results)
+			break
+		}
+	}
+	if v, err := c.GetOrLoad(7, load); v != 70 || err != nil || calls != 1 {
+		t.Errorf("GetOrLoad(7) = %v, %v", v, err)
+	}
+	if _, err := c.GetOrLoad(-1, load); err == nil {
+		t.Error("No error")
+	}
+	if _, ok := c.Peek(-1); ok {
+		t.Error("Failed load is cached")
+	}
+	s := c.Stats()
+	if s.Loads != 2 || s.LoadErrors != 1 || s.SharedLoads != n-1 ||
+		s.Hits != 1 || s.Misses != n+1 {
+		t.Errorf("Stats: %+v", s)
+	}
+}
+
+func TestGetOrLoadPanic(t *testing.T) {
+	c := New(&Options{MaxCost: 10}, true)
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Recovered: %v", r)
+			}
+		}()
+		c.GetOrLoad(0, func(interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+	// The failed call doesn't block later ones.
+	v, err := c.GetOrLoad(0, func(interface{}) (interface{}, error) {
+		return 1, nil
+	})
+	if v != 1 || err != nil {
+		t.Errorf("GetOrLoad = %v, %v", v, err)
+	}
+}
+
+func TestGetOrLoadStale(t *testing.T) {
+	type ev struct {
+		key, value interface{}
+		reason     EvictReason
+	}
+	var evs []ev
+	c := New(&Options{MaxCost: 10, OnEvict: func(key, value interface{},
+		reason EvictReason) {
+		evs = append(evs, ev{key, value, reason})
+	}}, true)
+	// load is called without the lock, so it can modify the cache.
+	v, err := c.GetOrLoad(1, func(interface{}) (interface{}, error) {
+		c.Put(1, "fresh")
+		return "stale", nil
+	})
+	if v != "stale" || err != nil {
+		t.Errorf("GetOrLoad(1) = %v, %v", v, err)
+	}
+	if v, ok := c.Peek(1); v != "fresh" || !ok {
+		t.Errorf("Peek(1) = %v, %t, want fresh", v, ok)
+	}
+	c.GetOrLoad(2, func(interface{}) (interface{}, error) {
+		c.Put(2, "deleted")
+		c.Delete(2)
+		return "stale", nil
+	})
+	c.GetOrLoad(3, func(interface{}) (interface{}, error) {
+		c.Clear()
+		return "stale", nil
+	})
+	if v, ok := c.Peek(2); ok {
+		t.Errorf("Peek(2) = %v, deleted during the load", v)
+	}
+	if v, ok := c.Peek(3); ok {
+		t.Errorf("Peek(3) = %v, cleared during the load", v)
+	}
+	want := []ev{{2, "deleted", EvictDeleted}, {1, "fresh", EvictDeleted}}
+	if fmt.Sprint(evs) != fmt.Sprint(want) {
+		t.Errorf("Evictions: %v, want %v", evs, want)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	if err := c.TryPut(1, 1); !errors.Is(err, gocontainer.ErrNilContainer) {
+		t.Errorf("TryPut: %v", err)
+	}
+	err := c.TryPutWithTTL(1, 1, time.Second)
+	if !errors.Is(err, gocontainer.ErrNilContainer) {
+		t.Errorf("TryPutWithTTL: %v", err)
+	}
+	if c.Len() != 0 || c.Delete(1) {
+		t.Error("Len or Delete on a nil cache.")
+	}
+}
+
+func TestNewE(t *testing.T) {
+	for _, tc := range []struct {
+		opts *Options
+		want error
+	}{
+		{nil, gocontainer.ErrZeroCapacity},
+		{&Options{}, gocontainer.ErrZeroCapacity},
+		{&Options{MaxCost: -1}, gocontainer.ErrNegativeCapacity},
+	} {
+		if _, err := NewE(tc.opts, false); !errors.Is(err, tc.want) {
+			t.Errorf("NewE(%+v): %v, want %v", tc.opts, err, tc.want)
+		}
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	for _, p := range []Policy{PolicyLRU, PolicyLFU, PolicyARC, Policy2Q} {
+		c := New(&Options{Policy: p, MaxCost: 50, TTL: time.Millisecond},
+			true)
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					k := (i*7 + g) % 120
+					switch i % 4 {
+					case 0:
+						c.Put(k, i)
+					case 1:
+						c.Delete(k)
+					default:
+						c.GetOrLoad(k, func(key interface{}) (
+							interface{}, error) {
+							return key, nil
+						})
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		if c.Len() > 50 || int64(c.Len()) != c.Cost() {
+			t.Errorf("%v: Len: %d, Cost: %d", p, c.Len(), c.Cost())
+		}
+	}
+}
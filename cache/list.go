@@ -0,0 +1,45 @@
+package cache
+
+// Intrusive doubly linked list of entries, with the total cost of them.
+// The front is the most recently used end.
+type list struct {
+	root entry // Sentinel. root.next is the front, root.prev is the back.
+	n    int
+	cost int64
+}
+
+func (l *list) init() {
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	l.n, l.cost = 0, 0
+}
+
+func (l *list) back() *entry {
+	if l.n == 0 {
+		return nil
+	}
+	return l.root.prev
+}
+
+func (l *list) pushFront(e *entry) {
+	e.prev = &l.root
+	e.next = l.root.next
+	e.prev.next = e
+	e.next.prev = e
+	e.list = l
+	l.n++
+	l.cost += e.cost
+}
+
+func (l *list) remove(e *entry) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next, e.list = nil, nil, nil
+	l.n--
+	l.cost -= e.cost
+}
+
+func (l *list) moveToFront(e *entry) {
+	l.remove(e)
+	l.pushFront(e)
+}
@@ -0,0 +1,88 @@
+package cache
+
+import "fmt"
+
+// An in-flight load of GetOrLoad.
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+
+	// The key is put or deleted during the load,
+	// so the loaded value is not cached.
+	isStale bool
+}
+
+// Return the value of key. If it is not in the cache, call load
+// to get the value, and put it into the cache if load returns no error,
+// unless key is put or deleted during the load.
+//
+// Concurrent calls for the same key share the same load:
+// only the first one calls load, and the others wait for its result.
+// load is called without the lock held.
+// If load panics, the waiting calls get an error, and the panic
+// is propagated to the calling one.
+func (c *Cache) GetOrLoad(key interface{},
+	load func(key interface{}) (value interface{}, err error)) (
+	interface{}, error) {
+	if c == nil {
+		return load(key)
+	}
+	c.lockAndPurge()
+	if v, ok := c.get(key); ok {
+		c.unlock()
+		return v, nil
+	}
+	if cl := c.calls[key]; cl != nil {
+		c.stats.SharedLoads++
+		c.unlock()
+		<-cl.done
+		return cl.value, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.calls[key] = cl
+	c.stats.Loads++
+	c.unlock()
+
+	c.doLoad(key, cl, load)
+	return cl.value, cl.err
+}
+
+func (c *Cache) doLoad(key interface{}, cl *call,
+	load func(key interface{}) (value interface{}, err error)) {
+	isFinished := false
+	defer func() {
+		if !isFinished {
+			r := recover()
+			cl.err = fmt.Errorf("gocontainer: load of key %v panicked: %v",
+				key, r)
+			c.finishLoad(key, cl)
+			panic(r)
+		}
+	}()
+	cl.value, cl.err = load(key)
+	isFinished = true
+	c.finishLoad(key, cl)
+}
+
+// Put the loaded value into the cache, and wake up the waiting calls.
+func (c *Cache) finishLoad(key interface{}, cl *call) {
+	c.lockAndPurge()
+	delete(c.calls, key)
+	if cl.err != nil {
+		c.stats.LoadErrors++
+	} else if !cl.isStale {
+		// The value is still returned if it is too costly to be cached.
+		c.put(key, cl.value, c.ttl)
+	}
+	c.unlock()
+	close(cl.done)
+}
+
+// Keep the in-flight load of key, if any, from caching its value.
+// Call it with the lock held.
+func (c *Cache) invalidateLoad(key interface{}) {
+	if cl := c.calls[key]; cl != nil {
+		cl.isStale = true
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"container/heap"
+	"fmt"
+
+	iheap "github.com/donyori/gocontainer/internal/heap"
+)
+
+// The eviction policy of a Cache.
+type Policy int8
+
+const (
+	// Least recently used.
+	PolicyLRU Policy = iota
+
+	// Least frequently used, with the least recently used one
+	// among the equally frequently used ones. O(log(n)) time per operation,
+	// based on an indexed min-heap.
+	PolicyLFU
+
+	// Adaptive replacement cache, which balances recency and frequency,
+	// and is resistant to scans.
+	// See "ARC: A Self-Tuning, Low Overhead Replacement Cache"
+	// by Megiddo and Modha.
+	PolicyARC
+
+	// Full version of 2Q, which admits an item to the main LRU list
+	// only if it is accessed again after it is evicted from a FIFO,
+	// so it is resistant to scans.
+	// See "2Q: A Low Overhead High Performance Buffer Management
+	// Replacement Algorithm" by Johnson and Shasha.
+	Policy2Q
+)
+
+var policyNames = [...]string{"LRU", "LFU", "ARC", "2Q"}
+
+func (p Policy) String() string {
+	if p >= 0 && int(p) < len(policyNames) {
+		return policyNames[p]
+	}
+	return fmt.Sprintf("Policy(%d)", int8(p))
+}
+
+// policy decides which entry to evict. All the methods are called
+// with the lock of the cache held.
+type policy interface {
+	// Called after e is added to the cache.
+	add(e *entry)
+
+	// Called after e is hit, or its value is replaced.
+	touch(e *entry)
+
+	// Called after e is deleted or expired.
+	remove(e *entry)
+
+	// Detach and return the entry to evict, or nil if there is none.
+	evict() *entry
+
+	clear()
+}
+
+func newPolicy(p Policy, maxCost int64) (policy, error) {
+	switch p {
+	case PolicyLRU:
+		l := new(lru)
+		l.l.init()
+		return l, nil
+	case PolicyLFU:
+		return &lfu{h: iheap.NewMinHeap(0, true)}, nil
+	case PolicyARC:
+		return newARC(maxCost), nil
+	case Policy2Q:
+		return newTwoQ(maxCost), nil
+	default:
+		return nil, fmt.Errorf("gocontainer: unknown cache policy %v", p)
+	}
+}
+
+type lru struct {
+	l list
+}
+
+func (p *lru) add(e *entry) {
+	p.l.pushFront(e)
+}
+
+func (p *lru) touch(e *entry) {
+	p.l.moveToFront(e)
+}
+
+func (p *lru) remove(e *entry) {
+	p.l.remove(e)
+}
+
+func (p *lru) evict() *entry {
+	e := p.l.back()
+	if e != nil {
+		p.l.remove(e)
+	}
+	return e
+}
+
+func (p *lru) clear() {
+	p.l.init()
+}
+
+type lfu struct {
+	h    *iheap.MinHeap // Of *entry, ordered by (freq, tick).
+	tick uint64
+}
+
+func (p *lfu) add(e *entry) {
+	p.tick++
+	e.freq, e.tick = 1, p.tick
+	heap.Push(p.h, e)
+}
+
+func (p *lfu) touch(e *entry) {
+	p.tick++
+	e.freq++
+	e.tick = p.tick
+	heap.Fix(p.h, e.idx)
+}
+
+func (p *lfu) remove(e *entry) {
+	heap.Remove(p.h, e.idx)
+}
+
+func (p *lfu) evict() *entry {
+	if p.h.Len() == 0 {
+		return nil
+	}
+	return heap.Pop(p.h).(*entry)
+}
+
+func (p *lfu) clear() {
+	p.h.Clear()
+	p.tick = 0
+}
@@ -0,0 +1,42 @@
+package cache
+
+type Stats struct {
+	Hits        int64 // Get and GetOrLoad calls finding the key.
+	Misses      int64 // Get and GetOrLoad calls not finding the key.
+	Evictions   int64 // Items evicted by the policy.
+	Expirations int64 // Items removed because of their TTL.
+	Loads       int64 // Calls of the loader by GetOrLoad.
+	LoadErrors  int64 // Calls of the loader returning an error.
+	SharedLoads int64 // GetOrLoad calls waiting for an in-flight load.
+}
+
+// Return Hits / (Hits + Misses), or 0 if there is no access.
+func (s Stats) HitRatio() float64 {
+	if n := s.Hits + s.Misses; n > 0 {
+		return float64(s.Hits) / float64(n)
+	}
+	return 0
+}
+
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	if c.lock != nil {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+	}
+	return c.stats
+}
+
+// Reset the stats to zero.
+func (c *Cache) ResetStats() {
+	if c == nil {
+		return
+	}
+	if c.lock != nil {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+	}
+	c.stats = Stats{}
+}
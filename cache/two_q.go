@@ -0,0 +1,77 @@
+package cache
+
+// 2Q, with the sizes of the queues measured in cost.
+type twoQ struct {
+	kin  int64 // Target cost of a1in.
+	kout int64 // Maximum cost of a1out.
+
+	a1in  list // Resident FIFO of the items seen once.
+	a1out list // Ghost FIFO of the items evicted from a1in.
+	am    list // Resident LRU of the items seen again.
+
+	ghosts map[interface{}]*entry
+}
+
+func newTwoQ(c int64) *twoQ {
+	// The parameters recommended by the paper.
+	p := &twoQ{kin: c / 4, kout: c / 2}
+	if p.kin < 1 {
+		p.kin = 1
+	}
+	if p.kout < 1 {
+		p.kout = 1
+	}
+	p.clear()
+	return p
+}
+
+func (p *twoQ) add(e *entry) {
+	if g := p.ghosts[e.key]; g != nil {
+		p.dropGhost(g)
+		p.am.pushFront(e)
+		return
+	}
+	p.a1in.pushFront(e)
+}
+
+func (p *twoQ) touch(e *entry) {
+	// A hit in a1in is not counted, as it is likely correlated.
+	if e.list == &p.am {
+		p.am.moveToFront(e)
+	}
+}
+
+func (p *twoQ) remove(e *entry) {
+	e.list.remove(e)
+}
+
+func (p *twoQ) evict() *entry {
+	if p.a1in.n > 0 && (p.a1in.cost > p.kin || p.am.n == 0) {
+		e := p.a1in.back()
+		p.a1in.remove(e)
+		g := &entry{key: e.key, cost: e.cost}
+		p.a1out.pushFront(g)
+		p.ghosts[g.key] = g
+		for p.a1out.cost > p.kout {
+			p.dropGhost(p.a1out.back())
+		}
+		return e
+	}
+	e := p.am.back()
+	if e != nil {
+		p.am.remove(e)
+	}
+	return e
+}
+
+func (p *twoQ) dropGhost(g *entry) {
+	g.list.remove(g)
+	delete(p.ghosts, g.key)
+}
+
+func (p *twoQ) clear() {
+	p.a1in.init()
+	p.a1out.init()
+	p.am.init()
+	p.ghosts = make(map[interface{}]*entry)
+}
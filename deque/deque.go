@@ -0,0 +1,438 @@
+// Package deque provides a double-ended queue based on a growable
+// ring buffer, which can also be bounded.
+package deque
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+const minCap = 8
+
+type Deque struct {
+	buf    []interface{} // Ring buffer.
+	head   int           // Position of the front item in buf.
+	n      int           // Number of items.
+	maxLen int           // 0 if unbounded.
+
+	// If true, pushing to a full bounded deque drops the item
+	// at the other end, instead of returning gocontainer.ErrFull.
+	isOverwrite bool
+
+	lock *sync.RWMutex
+
+	// Closed and reset to nil on mutation, to wake up the blocked
+	// goroutines. Created by the first of them.
+	waitCh chan struct{}
+}
+
+// Create an unbounded deque, which grows as needed.
+func NewDeque(capacity int, isSync bool) *Deque {
+	d, err := NewDequeE(capacity, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Same as NewDeque, but return an error instead of panic.
+func NewDequeE(capacity int, isSync bool) (*Deque, error) {
+	if capacity < 0 {
+		return nil, newNegativeCapacityError(capacity)
+	}
+	d := new(Deque)
+	if isSync {
+		d.lock = new(sync.RWMutex)
+	}
+	if capacity > 0 {
+		d.buf = make([]interface{}, capacity)
+	}
+	return d, nil
+}
+
+// Create a deque holding at most maxLen items.
+// When it is full, pushing an item drops the item at the other end
+// if isOverwrite is true, e.g., PushBack drops the front item,
+// which is the oldest one for a FIFO queue.
+// Otherwise, pushing returns an error wrapping gocontainer.ErrFull,
+// or waits for space in the blocking variants.
+func NewBoundedDeque(maxLen int, isOverwrite, isSync bool) *Deque {
+	d, err := NewBoundedDequeE(maxLen, isOverwrite, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Same as NewBoundedDeque, but return an error instead of panic.
+func NewBoundedDequeE(maxLen int, isOverwrite, isSync bool) (
+	*Deque, error) {
+	if maxLen < 0 {
+		return nil, fmt.Errorf("%w: max length %d",
+			gocontainer.ErrNegativeCapacity, maxLen)
+	} else if maxLen == 0 {
+		return nil, fmt.Errorf("%w: max length of a bounded deque",
+			gocontainer.ErrZeroCapacity)
+	}
+	d := &Deque{maxLen: maxLen, isOverwrite: isOverwrite}
+	if isSync {
+		d.lock = new(sync.RWMutex)
+	}
+	return d, nil
+}
+
+func (d *Deque) Len() int {
+	if d == nil {
+		return 0
+	}
+	if d.lock != nil {
+		d.lock.RLock()
+		defer d.lock.RUnlock()
+	}
+	return d.n
+}
+
+func (d *Deque) Cap() int {
+	if d == nil {
+		return 0
+	}
+	if d.lock != nil {
+		d.lock.RLock()
+		defer d.lock.RUnlock()
+	}
+	return len(d.buf)
+}
+
+// Return the maximum length, or 0 if the deque is unbounded.
+func (d *Deque) MaxLen() int {
+	if d == nil {
+		return 0
+	}
+	return d.maxLen
+}
+
+func (d *Deque) PushFront(x interface{}) {
+	if err := d.TryPushFront(x); err != nil {
+		panic(err)
+	}
+}
+
+// Same as PushFront, but return an error wrapping gocontainer.ErrFull
+// instead of panic if the deque is full and not in overwrite mode,
+// or gocontainer.ErrNilContainer if d is nil.
+func (d *Deque) TryPushFront(x interface{}) error {
+	if d == nil {
+		return gocontainer.ErrNilContainer
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.push(x, true)
+}
+
+func (d *Deque) PushBack(x interface{}) {
+	if err := d.TryPushBack(x); err != nil {
+		panic(err)
+	}
+}
+
+// Same as PushBack, but return an error wrapping gocontainer.ErrFull
+// instead of panic if the deque is full and not in overwrite mode,
+// or gocontainer.ErrNilContainer if d is nil.
+func (d *Deque) TryPushBack(x interface{}) error {
+	if d == nil {
+		return gocontainer.ErrNilContainer
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.push(x, false)
+}
+
+func (d *Deque) PopFront() (x interface{}, ok bool) {
+	if d == nil {
+		return
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.pop(true)
+}
+
+func (d *Deque) PopBack() (x interface{}, ok bool) {
+	if d == nil {
+		return
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.pop(false)
+}
+
+func (d *Deque) Front() (x interface{}, ok bool) {
+	return d.peek(true)
+}
+
+func (d *Deque) Back() (x interface{}, ok bool) {
+	return d.peek(false)
+}
+
+func (d *Deque) peek(isFront bool) (x interface{}, ok bool) {
+	if d == nil {
+		return
+	}
+	if d.lock != nil {
+		d.lock.RLock()
+		defer d.lock.RUnlock()
+	}
+	if d.n == 0 {
+		return
+	}
+	if isFront {
+		return d.buf[d.head], true
+	}
+	return d.buf[d.pos(d.n-1)], true
+}
+
+// Return the i-th item from the front. At(0) is the front item,
+// and At(Len() - 1) is the back item.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if i is out of range.
+func (d *Deque) At(i int) interface{} {
+	if d == nil {
+		panic(newOutOfRangeError(i, 0))
+	}
+	if d.lock != nil {
+		d.lock.RLock()
+		defer d.lock.RUnlock()
+	}
+	d.checkIndex(i)
+	return d.buf[d.pos(i)]
+}
+
+// Replace the i-th item from the front with x.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if i is out of range.
+func (d *Deque) Set(i int, x interface{}) {
+	if d == nil {
+		panic(newOutOfRangeError(i, 0))
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	d.checkIndex(i)
+	d.buf[d.pos(i)] = x
+}
+
+// Rotate the deque by k steps to the back:
+// the back item becomes the front one for k = 1.
+// If k is negative, rotate by -k steps to the front.
+// O(min(|k|, Len() - |k|)) time, or O(1) if the buffer is full.
+func (d *Deque) Rotate(k int) {
+	if d == nil {
+		return
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	if d.n <= 1 {
+		return
+	}
+	k %= d.n
+	if k < 0 {
+		k += d.n
+	}
+	if k == 0 {
+		return
+	}
+	if d.n == len(d.buf) {
+		d.head = d.pos(d.n - k)
+	} else if k <= d.n/2 {
+		for ; k > 0; k-- {
+			back := d.pos(d.n - 1)
+			d.head = d.pos(len(d.buf) - 1)
+			d.buf[d.head], d.buf[back] = d.buf[back], nil
+		}
+	} else {
+		for k = d.n - k; k > 0; k-- {
+			tail := d.pos(d.n)
+			d.buf[tail], d.buf[d.head] = d.buf[d.head], nil
+			d.head = d.pos(1)
+		}
+	}
+	d.mutated()
+}
+
+// Call f with the items from the front to the back, with their indices,
+// until f returns true. f must NOT modify the deque.
+func (d *Deque) Scan(f func(i int, x interface{}) (doesStop bool)) {
+	d.scan(false, f)
+}
+
+// Same as Scan, but from the back to the front.
+func (d *Deque) ReverseScan(f func(i int, x interface{}) (doesStop bool)) {
+	d.scan(true, f)
+}
+
+func (d *Deque) scan(isReverse bool,
+	f func(i int, x interface{}) (doesStop bool)) {
+	if d == nil || f == nil {
+		return
+	}
+	if d.lock != nil {
+		d.lock.RLock()
+		defer d.lock.RUnlock()
+	}
+	for j := 0; j < d.n; j++ {
+		i := j
+		if isReverse {
+			i = d.n - 1 - j
+		}
+		if f(i, d.buf[d.pos(i)]) {
+			return
+		}
+	}
+}
+
+// Return the items from the front to the back, in a new slice.
+func (d *Deque) Slice() []interface{} {
+	if d == nil {
+		return nil
+	}
+	if d.lock != nil {
+		d.lock.RLock()
+		defer d.lock.RUnlock()
+	}
+	xs := make([]interface{}, d.n)
+	d.copyTo(xs)
+	return xs
+}
+
+func (d *Deque) Clear() {
+	if d == nil {
+		return
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	for i := 0; i < d.n; i++ {
+		d.buf[d.pos(i)] = nil // To avoid potential memory leak.
+	}
+	d.head, d.n = 0, 0
+	d.mutated()
+}
+
+// Return the position in buf of the i-th item from the front.
+func (d *Deque) pos(i int) int {
+	i += d.head
+	if i >= len(d.buf) {
+		i -= len(d.buf)
+	}
+	return i
+}
+
+func (d *Deque) checkIndex(i int) {
+	if i < 0 || i >= d.n {
+		panic(newOutOfRangeError(i, d.n))
+	}
+}
+
+// Copy the items to xs, which must be long enough.
+func (d *Deque) copyTo(xs []interface{}) {
+	if d.head+d.n <= len(d.buf) {
+		copy(xs, d.buf[d.head:d.head+d.n])
+	} else {
+		k := copy(xs, d.buf[d.head:])
+		copy(xs[k:], d.buf[:d.n-k])
+	}
+}
+
+// Resize buf to newCap (>= d.n), and move the front item to position 0.
+func (d *Deque) resize(newCap int) {
+	buf := make([]interface{}, newCap)
+	d.copyTo(buf)
+	d.buf, d.head = buf, 0
+}
+
+// Call it with the lock held.
+func (d *Deque) isFull() bool {
+	return d.maxLen > 0 && d.n >= d.maxLen
+}
+
+// Call it with the lock held.
+func (d *Deque) push(x interface{}, isFront bool) error {
+	if d.isFull() {
+		if !d.isOverwrite {
+			return fmt.Errorf("%w: max length %d", gocontainer.ErrFull,
+				d.maxLen)
+		}
+		d.pop(!isFront)
+	}
+	if d.n == len(d.buf) {
+		newCap := len(d.buf) * 2
+		if newCap < minCap {
+			newCap = minCap
+		}
+		if d.maxLen > 0 && newCap > d.maxLen {
+			newCap = d.maxLen
+		}
+		d.resize(newCap)
+	}
+	if isFront {
+		d.head = d.pos(len(d.buf) - 1)
+		d.buf[d.head] = x
+	} else {
+		d.buf[d.pos(d.n)] = x
+	}
+	d.n++
+	d.mutated()
+	return nil
+}
+
+// Call it with the lock held.
+func (d *Deque) pop(isFront bool) (x interface{}, ok bool) {
+	if d.n == 0 {
+		return
+	}
+	var p int
+	if isFront {
+		p = d.head
+		d.head = d.pos(1)
+	} else {
+		p = d.pos(d.n - 1)
+	}
+	x, d.buf[p] = d.buf[p], nil
+	d.n--
+	// Shrink if the buffer is mostly empty.
+	if len(d.buf) > minCap*4 && d.n <= len(d.buf)/4 {
+		d.resize(len(d.buf) / 2)
+	}
+	d.mutated()
+	return x, true
+}
+
+// Call it after each mutation, with the lock held.
+// It wakes up the blocked goroutines.
+func (d *Deque) mutated() {
+	if d.waitCh != nil {
+		close(d.waitCh)
+		d.waitCh = nil
+	}
+}
+
+func newNegativeCapacityError(capacity int) error {
+	return fmt.Errorf("%w: %d", gocontainer.ErrNegativeCapacity, capacity)
+}
+
+func newOutOfRangeError(i, n int) error {
+	return fmt.Errorf("%w: index %d, length %d", gocontainer.ErrOutOfRange, i, n)
+}
@@ -0,0 +1,216 @@
+package deque
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+func checkDeque(t *testing.T, d *Deque, want []int) {
+	t.Helper()
+	xs := d.Slice()
+	if len(xs) != len(want) || d.Len() != len(want) {
+		t.Fatalf("Slice: %v, want %v", xs, want)
+	}
+	for i, x := range xs {
+		if x != want[i] || d.At(i) != want[i] {
+			t.Fatalf("Slice: %v, want %v", xs, want)
+		}
+	}
+}
+
+func TestDeque(t *testing.T) {
+	d := NewDeque(0, true)
+	var want []int
+	// Wrap around and grow several times.
+	for i := 0; i < 20; i++ {
+		if i%3 == 0 {
+			d.PushFront(i)
+			want = append([]int{i}, want...)
+		} else {
+			d.PushBack(i)
+			want = append(want, i)
+		}
+	}
+	checkDeque(t, d, want)
+	if x, ok := d.Front(); !ok || x != want[0] {
+		t.Errorf("Front() = %v, %t", x, ok)
+	}
+	if x, ok := d.Back(); !ok || x != want[len(want)-1] {
+		t.Errorf("Back() = %v, %t", x, ok)
+	}
+	for _, k := range []int{1, 3, -2, 17, -40, 0} {
+		d.Rotate(k)
+		n := len(want)
+		r := ((k % n) + n) % n
+		want = append(want[n-r:], want[:n-r]...)
+		checkDeque(t, d, want)
+	}
+	d.Set(2, -1)
+	want[2] = -1
+	var scanned []int
+	d.ReverseScan(func(i int, x interface{}) bool {
+		if x != want[i] {
+			t.Fatalf("ReverseScan: %d: %v != %d", i, x, want[i])
+		}
+		scanned = append(scanned, i)
+		return len(scanned) == 3
+	})
+	if len(scanned) != 3 || scanned[0] != len(want)-1 {
+		t.Errorf("ReverseScan: %v", scanned)
+	}
+	for len(want) > 0 {
+		x, ok := d.PopFront()
+		if !ok || x != want[0] {
+			t.Fatalf("PopFront() = %v, %t, want %d", x, ok, want[0])
+		}
+		want = want[1:]
+		if len(want) > 0 {
+			x, ok = d.PopBack()
+			if !ok || x != want[len(want)-1] {
+				t.Fatalf("PopBack() = %v, %t", x, ok)
+			}
+			want = want[:len(want)-1]
+		}
+	}
+	if _, ok := d.PopBack(); ok || d.Len() != 0 {
+		t.Fatal("The deque is not empty.")
+	}
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, gocontainer.ErrOutOfRange) {
+				t.Errorf("At(0) on an empty deque panics with %v", err)
+			}
+		}()
+		d.At(0)
+	}()
+}
+
+func TestDequeShrink(t *testing.T) {
+	d := NewDeque(0, false)
+	for i := 0; i < 1000; i++ {
+		d.PushBack(i)
+	}
+	for i := 0; i < 990; i++ {
+		d.PopFront()
+	}
+	if c := d.Cap(); c > 64 {
+		t.Errorf("Cap(%d) is not shrunk", c)
+	}
+	checkDeque(t, d, []int{990, 991, 992, 993, 994, 995, 996, 997, 998, 999})
+}
+
+func TestBoundedDeque(t *testing.T) {
+	d := NewBoundedDeque(3, false, false)
+	for i := 0; i < 3; i++ {
+		d.PushBack(i)
+	}
+	if err := d.TryPushFront(3); !errors.Is(err, gocontainer.ErrFull) {
+		t.Fatalf("err(%v) is not ErrFull", err)
+	}
+	checkDeque(t, d, []int{0, 1, 2})
+	d.Rotate(1)
+	checkDeque(t, d, []int{2, 0, 1})
+
+	d = NewBoundedDeque(3, true, false)
+	for i := 0; i < 5; i++ {
+		d.PushBack(i) // Drop the oldest ones.
+	}
+	checkDeque(t, d, []int{2, 3, 4})
+	d.PushFront(5) // Drop 4.
+	checkDeque(t, d, []int{5, 2, 3})
+	if d.Cap() != 3 {
+		t.Errorf("Cap(%d) != 3", d.Cap())
+	}
+	if _, err := NewBoundedDequeE(0, true, false); !errors.Is(err,
+		gocontainer.ErrZeroCapacity) {
+		t.Errorf("err(%v) is not ErrZeroCapacity", err)
+	}
+	if _, err := NewBoundedDequeE(-1, true, false); !errors.Is(err,
+		gocontainer.ErrNegativeCapacity) {
+		t.Errorf("err(%v) is not ErrNegativeCapacity", err)
+	}
+}
+
+func TestNilDeque(t *testing.T) {
+	var d *Deque
+	if err := d.TryPushFront(1); !errors.Is(err,
+		gocontainer.ErrNilContainer) {
+		t.Errorf("err(%v) is not ErrNilContainer", err)
+	}
+	if err := d.TryPushBack(1); !errors.Is(err,
+		gocontainer.ErrNilContainer) {
+		t.Errorf("err(%v) is not ErrNilContainer", err)
+	}
+	d.Rotate(1)
+	if _, ok := d.PopFront(); ok || d.Len() != 0 {
+		t.Error("Nil deque is not empty.")
+	}
+	for _, f := range []func(){
+		func() { d.At(0) },
+		func() { d.Set(0, 1) },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, gocontainer.ErrOutOfRange) {
+					t.Errorf("err(%v) is not ErrOutOfRange", err)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestDequeWait(t *testing.T) {
+	if _, err := NewDeque(0, false).PopFrontWait(
+		context.Background()); !errors.Is(err, gocontainer.ErrNotSync) {
+		t.Errorf("err(%v) is not ErrNotSync", err)
+	}
+	d := NewBoundedDeque(2, false, true)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if _, err := d.PopBackWait(ctx); !errors.Is(err,
+		context.DeadlineExceeded) {
+		t.Fatalf("err(%v) is not DeadlineExceeded", err)
+	}
+
+	// A bounded FIFO channel.
+	const n = 100
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			x, err := d.PopFrontWait(context.Background())
+			if err != nil || x != i {
+				t.Errorf("PopFrontWait() = %v, %v, want %d", x, err, i)
+				return
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		if err := d.PushBackWait(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout")
+	}
+}
+
+func BenchmarkDequeFIFO(b *testing.B) {
+	d := NewDeque(0, false)
+	for i := 0; i < b.N; i++ {
+		d.PushBack(i)
+		if d.Len() > 1024 {
+			d.PopFront()
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package deque
+
+import (
+	"context"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Same as PushFront, but if the deque is full, wait until there is space
+// or ctx is done. It doesn't wait in overwrite mode.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the deque is not created with isSync = true.
+func (d *Deque) PushFrontWait(ctx context.Context, x interface{}) error {
+	return d.wait(ctx, func() bool {
+		return d.push(x, true) == nil
+	})
+}
+
+// Same as PushBack, but if the deque is full, wait until there is space
+// or ctx is done. It doesn't wait in overwrite mode.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the deque is not created with isSync = true.
+func (d *Deque) PushBackWait(ctx context.Context, x interface{}) error {
+	return d.wait(ctx, func() bool {
+		return d.push(x, false) == nil
+	})
+}
+
+// Same as PopFront, but if the deque is empty, wait until an item
+// is pushed or ctx is done.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the deque is not created with isSync = true.
+func (d *Deque) PopFrontWait(ctx context.Context) (x interface{}, err error) {
+	err = d.wait(ctx, func() (ok bool) {
+		x, ok = d.pop(true)
+		return
+	})
+	return
+}
+
+// Same as PopBack, but if the deque is empty, wait until an item
+// is pushed or ctx is done.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the deque is not created with isSync = true.
+func (d *Deque) PopBackWait(ctx context.Context) (x interface{}, err error) {
+	err = d.wait(ctx, func() (ok bool) {
+		x, ok = d.pop(false)
+		return
+	})
+	return
+}
+
+// Call try with the lock held, until it returns true, or ctx is done.
+// try is called again after each mutation of the deque.
+func (d *Deque) wait(ctx context.Context, try func() bool) error {
+	if d == nil || d.lock == nil {
+		return gocontainer.ErrNotSync
+	}
+	for {
+		d.lock.Lock()
+		if try() {
+			d.lock.Unlock()
+			return nil
+		}
+		if d.waitCh == nil {
+			d.waitCh = make(chan struct{})
+		}
+		waitCh := d.waitCh
+		d.lock.Unlock()
+		select {
+		case <-waitCh:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
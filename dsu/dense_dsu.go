@@ -0,0 +1,247 @@
+// Package dsu provides disjoint-set (union-find) structures,
+// over dense ints or arbitrary keys, optionally with rollback.
+package dsu
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// DenseDSU is a disjoint-set structure over the elements 0, 1, ..., n-1,
+// initially all in their own sets.
+//
+// Unions are by size. Without rollback, Find uses path compression,
+// and the operations take nearly O(1) amortized time.
+// With rollback, there is no path compression, so that the unions
+// can be undone, and the operations take O(log(n)) time.
+//
+// A nil DenseDSU has no elements, and is not created with rollback.
+type DenseDSU struct {
+	f    forest
+	lock *sync.Mutex // Find modifies the forest by path compression.
+}
+
+// Create a DenseDSU of n elements.
+// It panics if n is negative.
+func NewDenseDSU(n int, isSync bool) *DenseDSU {
+	return newDenseDSU(n, false, isSync)
+}
+
+// Create a DenseDSU of n elements, whose unions can be undone.
+// It panics if n is negative.
+func NewRollbackDenseDSU(n int, isSync bool) *DenseDSU {
+	return newDenseDSU(n, true, isSync)
+}
+
+func newDenseDSU(n int, isRollback, isSync bool) *DenseDSU {
+	if n < 0 {
+		panic(fmt.Errorf("%w: %d", gocontainer.ErrNegativeCapacity, n))
+	}
+	d := new(DenseDSU)
+	d.f.init(n, isRollback)
+	if isSync {
+		d.lock = new(sync.Mutex)
+	}
+	return d
+}
+
+// Return the number of elements.
+func (d *DenseDSU) Len() int {
+	if d == nil {
+		return 0
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.f.len()
+}
+
+// Return the number of sets.
+func (d *DenseDSU) NumSets() int {
+	if d == nil {
+		return 0
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.f.nSets
+}
+
+// Add a new element in its own set, and return it.
+// It is not undone by Rollback.
+// It panics with gocontainer.ErrNilContainer if d is nil.
+func (d *DenseDSU) Add() int {
+	if d == nil {
+		panic(gocontainer.ErrNilContainer)
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.f.add()
+}
+
+// Return the representative of the set of x.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if x is out of range.
+func (d *DenseDSU) Find(x int) int {
+	if d == nil {
+		panic(newOutOfRangeError(x, 0))
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	d.checkIndex(x)
+	return d.f.find(x)
+}
+
+// Merge the sets of a and b, and return true if they were different sets.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if a or b is out of range.
+func (d *DenseDSU) Union(a, b int) bool {
+	if d == nil {
+		panic(newOutOfRangeError(a, 0))
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	d.checkIndex(a)
+	d.checkIndex(b)
+	return d.f.union(a, b)
+}
+
+// Return true if a and b are in the same set.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if a or b is out of range.
+func (d *DenseDSU) Connected(a, b int) bool {
+	if d == nil {
+		panic(newOutOfRangeError(a, 0))
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	d.checkIndex(a)
+	d.checkIndex(b)
+	return d.f.find(a) == d.f.find(b)
+}
+
+// Return the size of the set of x.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if x is out of range.
+func (d *DenseDSU) SetSize(x int) int {
+	if d == nil {
+		panic(newOutOfRangeError(x, 0))
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	d.checkIndex(x)
+	return d.f.size[d.f.find(x)]
+}
+
+// Return the members of the set of x, starting from x,
+// in O(size of the set) time.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if x is out of range.
+func (d *DenseDSU) Members(x int) []int {
+	if d == nil {
+		panic(newOutOfRangeError(x, 0))
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	d.checkIndex(x)
+	members := make([]int, 0, d.f.size[d.f.find(x)])
+	d.f.members(x, func(y int) {
+		members = append(members, y)
+	})
+	return members
+}
+
+// Return all the sets. Each set starts from its least element,
+// and the sets are ordered by their least elements.
+func (d *DenseDSU) Sets() [][]int {
+	if d == nil {
+		return nil
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.f.sets()
+}
+
+// Return a snapshot, to which the unions after it can be rolled back.
+// It panics if the DenseDSU is not created with rollback.
+func (d *DenseDSU) Snapshot() int {
+	if d == nil {
+		panic(gocontainer.ErrNoRollback)
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	if !d.f.isRollback {
+		panic(gocontainer.ErrNoRollback)
+	}
+	return len(d.f.history)
+}
+
+// Undo the unions after snapshot.
+// It panics if the DenseDSU is not created with rollback,
+// or with an error wrapping gocontainer.ErrOutOfRange
+// if the unions before snapshot have been undone.
+func (d *DenseDSU) Rollback(snapshot int) {
+	if err := d.TryRollback(snapshot); err != nil {
+		panic(err)
+	}
+}
+
+// Same as Rollback, but return an error instead of panic.
+func (d *DenseDSU) TryRollback(snapshot int) error {
+	if d == nil {
+		return gocontainer.ErrNoRollback
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.f.rollback(snapshot)
+}
+
+// Undo the last union, and return false if there is none.
+// It panics if the DenseDSU is not created with rollback.
+func (d *DenseDSU) Undo() bool {
+	if d == nil {
+		panic(gocontainer.ErrNoRollback)
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	if !d.f.isRollback {
+		panic(gocontainer.ErrNoRollback)
+	}
+	return d.f.undo()
+}
+
+// Call it with the lock held.
+func (d *DenseDSU) checkIndex(x int) {
+	if x < 0 || x >= d.f.len() {
+		panic(newOutOfRangeError(x, d.f.len()))
+	}
+}
+
+func newOutOfRangeError(x, n int) error {
+	return fmt.Errorf("%w: %d with %d elements",
+		gocontainer.ErrOutOfRange, x, n)
+}
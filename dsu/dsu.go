@@ -0,0 +1,270 @@
+package dsu
+
+import (
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// DSU is a disjoint-set structure over arbitrary keys,
+// which must be usable as map keys.
+// It is a DenseDSU with a map from the keys to the elements,
+// and has the same time complexity.
+//
+// A nil DSU has no keys, and is not created with rollback.
+type DSU struct {
+	ids  map[interface{}]int
+	keys []interface{} // Indexed by the elements.
+	f    forest
+	lock *sync.Mutex
+}
+
+func NewDSU(isSync bool) *DSU {
+	return newDSU(false, isSync)
+}
+
+// Create a DSU whose unions can be undone.
+// The keys added are not removed by Rollback or Undo.
+func NewRollbackDSU(isSync bool) *DSU {
+	return newDSU(true, isSync)
+}
+
+func newDSU(isRollback, isSync bool) *DSU {
+	d := &DSU{ids: make(map[interface{}]int)}
+	d.f.init(0, isRollback)
+	if isSync {
+		d.lock = new(sync.Mutex)
+	}
+	return d
+}
+
+// Return the number of keys.
+func (d *DSU) Len() int {
+	if d == nil {
+		return 0
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return len(d.keys)
+}
+
+// Return the number of sets.
+func (d *DSU) NumSets() int {
+	if d == nil {
+		return 0
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.f.nSets
+}
+
+// Add key in its own set, and return true if it is new.
+// It panics if key is nil,
+// or with gocontainer.ErrNilContainer if d is nil.
+func (d *DSU) Add(key interface{}) (isAdded bool) {
+	if d == nil {
+		panic(gocontainer.ErrNilContainer)
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	_, isAdded = d.id(key)
+	return
+}
+
+func (d *DSU) Contains(key interface{}) bool {
+	if d == nil {
+		return false
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	_, ok := d.ids[key]
+	return ok
+}
+
+// Return the representative key of the set of key.
+// ok is false if key is not in the DSU.
+func (d *DSU) Find(key interface{}) (rep interface{}, ok bool) {
+	if d == nil {
+		return
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	x, ok := d.ids[key]
+	if !ok {
+		return
+	}
+	return d.keys[d.f.find(x)], true
+}
+
+// Merge the sets of a and b, and return true if they were different sets.
+// The keys not in the DSU are added first.
+// It panics if a or b is nil,
+// or with gocontainer.ErrNilContainer if d is nil.
+func (d *DSU) Union(a, b interface{}) bool {
+	if d == nil {
+		panic(gocontainer.ErrNilContainer)
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	x, _ := d.id(a)
+	y, _ := d.id(b)
+	return d.f.union(x, y)
+}
+
+// Return true if a and b are in the same set.
+// It returns false if a or b is not in the DSU.
+func (d *DSU) Connected(a, b interface{}) bool {
+	if d == nil {
+		return false
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	x, ok := d.ids[a]
+	if !ok {
+		return false
+	}
+	y, ok := d.ids[b]
+	return ok && d.f.find(x) == d.f.find(y)
+}
+
+// Return the size of the set of key, or 0 if key is not in the DSU.
+func (d *DSU) SetSize(key interface{}) int {
+	if d == nil {
+		return 0
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	x, ok := d.ids[key]
+	if !ok {
+		return 0
+	}
+	return d.f.size[d.f.find(x)]
+}
+
+// Return the members of the set of key, starting from key,
+// or nil if key is not in the DSU.
+func (d *DSU) Members(key interface{}) []interface{} {
+	if d == nil {
+		return nil
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	x, ok := d.ids[key]
+	if !ok {
+		return nil
+	}
+	members := make([]interface{}, 0, d.f.size[d.f.find(x)])
+	d.f.members(x, func(y int) {
+		members = append(members, d.keys[y])
+	})
+	return members
+}
+
+// Return all the sets. Each set starts from its earliest added key,
+// and the sets are ordered by when those keys are added.
+func (d *DSU) Sets() [][]interface{} {
+	if d == nil {
+		return nil
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	sets := d.f.sets()
+	res := make([][]interface{}, len(sets))
+	for i, set := range sets {
+		res[i] = make([]interface{}, len(set))
+		for j, x := range set {
+			res[i][j] = d.keys[x]
+		}
+	}
+	return res
+}
+
+// Return a snapshot, to which the unions after it can be rolled back.
+// It panics if the DSU is not created with rollback.
+func (d *DSU) Snapshot() int {
+	if d == nil {
+		panic(gocontainer.ErrNoRollback)
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	if !d.f.isRollback {
+		panic(gocontainer.ErrNoRollback)
+	}
+	return len(d.f.history)
+}
+
+// Undo the unions after snapshot.
+// It panics if the DSU is not created with rollback,
+// or with an error wrapping gocontainer.ErrOutOfRange
+// if the unions before snapshot have been undone.
+func (d *DSU) Rollback(snapshot int) {
+	if err := d.TryRollback(snapshot); err != nil {
+		panic(err)
+	}
+}
+
+// Same as Rollback, but return an error instead of panic.
+func (d *DSU) TryRollback(snapshot int) error {
+	if d == nil {
+		return gocontainer.ErrNoRollback
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	return d.f.rollback(snapshot)
+}
+
+// Undo the last union, and return false if there is none.
+// It panics if the DSU is not created with rollback.
+func (d *DSU) Undo() bool {
+	if d == nil {
+		panic(gocontainer.ErrNoRollback)
+	}
+	if d.lock != nil {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+	}
+	if !d.f.isRollback {
+		panic(gocontainer.ErrNoRollback)
+	}
+	return d.f.undo()
+}
+
+// Return the element of key, and add it if it is new.
+// Call it with the lock held.
+func (d *DSU) id(key interface{}) (x int, isAdded bool) {
+	x, ok := d.ids[key]
+	if ok {
+		return x, false
+	}
+	if key == nil {
+		panic(gocontainer.ErrNilItem)
+	}
+	x = d.f.add()
+	d.ids[key] = x
+	d.keys = append(d.keys, key)
+	return x, true
+}
@@ -0,0 +1,236 @@
+package dsu
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Naive disjoint sets, as labels of the elements.
+type naive []int
+
+func (nv naive) union(a, b int) bool {
+	la, lb := nv[a], nv[b]
+	if la == lb {
+		return false
+	}
+	for i := range nv {
+		if nv[i] == lb {
+			nv[i] = la
+		}
+	}
+	return true
+}
+
+func (nv naive) size(x int) int {
+	n := 0
+	for _, l := range nv {
+		if l == nv[x] {
+			n++
+		}
+	}
+	return n
+}
+
+func checkDense(t *testing.T, d *DenseDSU, nv naive) {
+	t.Helper()
+	sets := d.Sets()
+	if len(sets) != d.NumSets() {
+		t.Fatalf("%d sets, NumSets: %d", len(sets), d.NumSets())
+	}
+	n := 0
+	for i, set := range sets {
+		if i > 0 && sets[i-1][0] >= set[0] {
+			t.Fatalf("Sets are not ordered: %v", sets)
+		}
+		for _, x := range set {
+			if nv[x] != nv[set[0]] || x < set[0] {
+				t.Fatalf("Wrong set %v", set)
+			}
+		}
+		if len(set) != nv.size(set[0]) || d.SetSize(set[0]) != len(set) {
+			t.Fatalf("Wrong size of set %v", set)
+		}
+		n += len(set)
+	}
+	if n != len(nv) {
+		t.Fatalf("%d elements in the sets, want %d", n, len(nv))
+	}
+}
+
+func TestDenseDSU(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, isRollback := range []bool{false, true} {
+		const n = 100
+		d := newDenseDSU(n, isRollback, true)
+		nv := make(naive, n)
+		for i := range nv {
+			nv[i] = i
+		}
+		for i := 0; i < 300; i++ {
+			a, b := r.Intn(n), r.Intn(n)
+			if d.Union(a, b) != nv.union(a, b) {
+				t.Fatalf("Union(%d, %d) is wrong", a, b)
+			}
+			a, b = r.Intn(n), r.Intn(n)
+			if d.Connected(a, b) != (nv[a] == nv[b]) {
+				t.Fatalf("Connected(%d, %d) is wrong", a, b)
+			}
+			if f := d.Find(a); nv[f] != nv[a] {
+				t.Fatalf("Find(%d) = %d", a, f)
+			}
+			if m := d.Members(a); m[0] != a || len(m) != nv.size(a) {
+				t.Fatalf("Members(%d) = %v", a, m)
+			}
+		}
+		checkDense(t, d, nv)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	const n = 60
+	d := NewRollbackDenseDSU(n, false)
+	nv := make(naive, n)
+	for i := range nv {
+		nv[i] = i
+	}
+	var snapshots []int
+	var saved []naive
+	for i := 0; i < 200; i++ {
+		switch r.Intn(5) {
+		case 0:
+			snapshots = append(snapshots, d.Snapshot())
+			saved = append(saved, append(naive(nil), nv...))
+		case 1:
+			if len(snapshots) > 0 {
+				j := r.Intn(len(snapshots))
+				d.Rollback(snapshots[j])
+				nv = saved[j]
+				snapshots, saved = snapshots[:j], saved[:j]
+				checkDense(t, d, nv)
+			}
+		default:
+			a, b := r.Intn(n), r.Intn(n)
+			if d.Union(a, b) != nv.union(a, b) {
+				t.Fatalf("Union(%d, %d) is wrong", a, b)
+			}
+		}
+	}
+	d.Rollback(0)
+	if d.NumSets() != n || d.Undo() {
+		t.Errorf("NumSets after Rollback(0): %d", d.NumSets())
+	}
+	d.Union(1, 2)
+	d.Union(2, 3)
+	if !d.Undo() || !d.Connected(1, 2) || d.Connected(1, 3) {
+		t.Error("Undo is wrong")
+	}
+	if err := d.TryRollback(5); !errors.Is(err, gocontainer.ErrOutOfRange) {
+		t.Errorf("TryRollback(5): %v", err)
+	}
+	err := NewDenseDSU(1, false).TryRollback(0)
+	if err != gocontainer.ErrNoRollback {
+		t.Errorf("TryRollback without rollback: %v", err)
+	}
+}
+
+func TestDSU(t *testing.T) {
+	d := NewRollbackDSU(true)
+	d.Add("a")
+	if d.Add("a") || !d.Contains("a") || d.Contains("b") {
+		t.Fatal("Add is wrong")
+	}
+	s := d.Snapshot()
+	d.Union("a", "b")
+	d.Union("c", "d")
+	d.Union("e", "d")
+	if !d.Connected("c", "e") || d.Connected("a", "c") ||
+		d.Connected("a", "x") {
+		t.Error("Connected is wrong")
+	}
+	if rep, ok := d.Find("e"); !ok || rep != "c" && rep != "d" && rep != "e" {
+		t.Errorf("Find(e) = %v, %t", rep, ok)
+	}
+	if _, ok := d.Find("x"); ok {
+		t.Error("Find(x) is found")
+	}
+	if d.SetSize("d") != 3 || d.SetSize("x") != 0 || d.Len() != 5 {
+		t.Errorf("SetSize(d) = %d, Len: %d", d.SetSize("d"), d.Len())
+	}
+	if got := fmt.Sprint(d.Sets()); got != "[[a b] [c d e]]" &&
+		got != "[[a b] [c e d]]" {
+		t.Errorf("Sets: %v", got)
+	}
+	if m := d.Members("e"); len(m) != 3 || m[0] != "e" {
+		t.Errorf("Members(e) = %v", m)
+	}
+	d.Rollback(s)
+	if d.NumSets() != 5 || d.Len() != 5 || d.Connected("a", "b") {
+		t.Errorf("After Rollback: %v", d.Sets())
+	}
+}
+
+func TestNilDSU(t *testing.T) {
+	var dd *DenseDSU
+	var d *DSU
+	errNil := gocontainer.ErrNilContainer
+	errRange := gocontainer.ErrOutOfRange
+	errNoRollback := gocontainer.ErrNoRollback
+	for _, tc := range []struct {
+		name string
+		f    func()
+		want error
+	}{
+		{"DenseDSU.Add", func() { dd.Add() }, errNil},
+		{"DenseDSU.Find", func() { dd.Find(0) }, errRange},
+		{"DenseDSU.Union", func() { dd.Union(0, 1) }, errRange},
+		{"DenseDSU.Members", func() { dd.Members(0) }, errRange},
+		{"DenseDSU.Undo", func() { dd.Undo() }, errNoRollback},
+		{"DSU.Add", func() { d.Add(0) }, errNil},
+		{"DSU.Union", func() { d.Union(0, 1) }, errNil},
+		{"DSU.Snapshot", func() { d.Snapshot() }, errNoRollback},
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, tc.want) {
+					t.Errorf("%s panics with %v", tc.name, err)
+				}
+			}()
+			tc.f()
+		}()
+	}
+	if err := d.TryRollback(0); err != errNoRollback {
+		t.Errorf("TryRollback: %v", err)
+	}
+	if d.Len() != 0 || d.Connected(0, 1) || d.Members(0) != nil {
+		t.Error("Queries on a nil DSU.")
+	}
+}
+
+func BenchmarkDenseDSU(b *testing.B) {
+	const n = 1 << 16
+	r := rand.New(rand.NewSource(1))
+	pairs := make([][2]int, n)
+	for i := range pairs {
+		pairs[i] = [2]int{r.Intn(n), r.Intn(n)}
+	}
+	for _, isRollback := range []bool{false, true} {
+		b.Run(fmt.Sprintf("rollback=%t", isRollback), func(b *testing.B) {
+			d := newDenseDSU(n, isRollback, false)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p := pairs[i%n]
+				if i%2 == 0 {
+					d.Union(p[0], p[1])
+				} else {
+					d.Connected(p[0], p[1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package dsu
+
+import (
+	"fmt"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Disjoint-set forest over the elements 0, 1, ..., n-1,
+// with union by size. Path compression is used unless isRollback is true,
+// in which case the unions are recorded in history to be undone.
+type forest struct {
+	parent []int
+	size   []int // Only valid for the roots.
+	next   []int // Circular list of the members of each set.
+	nSets  int
+
+	isRollback bool
+	history    []int // Roots attached by the unions, in order.
+}
+
+func (f *forest) init(n int, isRollback bool) {
+	f.parent = make([]int, n)
+	f.size = make([]int, n)
+	f.next = make([]int, n)
+	for i := 0; i < n; i++ {
+		f.parent[i], f.size[i], f.next[i] = i, 1, i
+	}
+	f.nSets = n
+	f.isRollback = isRollback
+	f.history = nil
+}
+
+func (f *forest) len() int {
+	return len(f.parent)
+}
+
+// Add a new singleton set, and return its element.
+func (f *forest) add() int {
+	x := len(f.parent)
+	f.parent = append(f.parent, x)
+	f.size = append(f.size, 1)
+	f.next = append(f.next, x)
+	f.nSets++
+	return x
+}
+
+func (f *forest) find(x int) int {
+	if f.isRollback {
+		for f.parent[x] != x {
+			x = f.parent[x]
+		}
+		return x
+	}
+	// Path halving.
+	for f.parent[x] != x {
+		f.parent[x] = f.parent[f.parent[x]]
+		x = f.parent[x]
+	}
+	return x
+}
+
+// Merge the sets of a and b. Return false if they are already in one set.
+func (f *forest) union(a, b int) bool {
+	ra, rb := f.find(a), f.find(b)
+	if ra == rb {
+		return false
+	}
+	if f.size[ra] < f.size[rb] {
+		ra, rb = rb, ra
+	}
+	f.parent[rb] = ra
+	f.size[ra] += f.size[rb]
+	f.next[ra], f.next[rb] = f.next[rb], f.next[ra]
+	f.nSets--
+	if f.isRollback {
+		f.history = append(f.history, rb)
+	}
+	return true
+}
+
+// Undo the last union. Return false if there is none.
+func (f *forest) undo() bool {
+	if len(f.history) == 0 {
+		return false
+	}
+	rb := f.history[len(f.history)-1]
+	f.history = f.history[:len(f.history)-1]
+	ra := f.parent[rb]
+	f.parent[rb] = rb
+	f.size[ra] -= f.size[rb]
+	// Swapping again splits the merged circular list.
+	f.next[ra], f.next[rb] = f.next[rb], f.next[ra]
+	f.nSets++
+	return true
+}
+
+// Call g with the members of the set of x, starting from x.
+func (f *forest) members(x int, g func(y int)) {
+	y := x
+	for {
+		g(y)
+		y = f.next[y]
+		if y == x {
+			return
+		}
+	}
+}
+
+// Return the sets, each starting from its least element,
+// ordered by their least elements.
+func (f *forest) sets() [][]int {
+	res := make([][]int, 0, f.nSets)
+	isSeen := make([]bool, len(f.parent)) // Indexed by the roots.
+	for x := range f.parent {
+		r := f.find(x)
+		if isSeen[r] {
+			continue
+		}
+		isSeen[r] = true
+		set := make([]int, 0, f.size[r])
+		f.members(x, func(y int) {
+			set = append(set, y)
+		})
+		res = append(res, set)
+	}
+	return res
+}
+
+// Undo the unions after snapshot, which is a length of history.
+func (f *forest) rollback(snapshot int) error {
+	if !f.isRollback {
+		return gocontainer.ErrNoRollback
+	}
+	if snapshot < 0 || snapshot > len(f.history) {
+		return fmt.Errorf("%w: snapshot %d with %d unions",
+			gocontainer.ErrOutOfRange, snapshot, len(f.history))
+	}
+	for len(f.history) > snapshot {
+		f.undo()
+	}
+	return nil
+}
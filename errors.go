@@ -0,0 +1,40 @@
+package gocontainer
+
+import "errors"
+
+// Sentinel errors returned by the error-returning APIs of the containers.
+// Use errors.Is to test them, because they are usually wrapped with details.
+var (
+	ErrNegativeCapacity = errors.New("gocontainer: capacity is negative")
+	ErrZeroCapacity     = errors.New("gocontainer: capacity is zero")
+	ErrInvalidK         = errors.New("gocontainer: k is non-positive")
+	ErrNilItem          = errors.New("gocontainer: item is nil")
+	ErrNotComparable    = errors.New("gocontainer: item is not Comparable")
+	ErrNotIndexed       = errors.New("gocontainer: item is not Indexed")
+	ErrIncomparable     = errors.New("gocontainer: items are incomparable")
+	ErrForeignItem      = errors.New("gocontainer: item is not in the container")
+	ErrAlreadyOwned     = errors.New("gocontainer: item already belongs to a container")
+	ErrOutOfRange       = errors.New("gocontainer: index out of range")
+	ErrClosed           = errors.New("gocontainer: container is closed")
+	ErrExpiryDisabled   = errors.New("gocontainer: expiry is not enabled")
+	ErrNotSync          = errors.New("gocontainer: container is not created with isSync = true")
+	ErrFull             = errors.New("gocontainer: container is full")
+	ErrInvalidWorkers   = errors.New("gocontainer: number of workers is invalid")
+	ErrCanceled         = errors.New("gocontainer: task is canceled")
+	ErrInvalidWeight    = errors.New("gocontainer: weight is invalid")
+	ErrNilContainer     = errors.New("gocontainer: container is nil")
+	ErrNoPath           = errors.New("gocontainer: no path between the vertices")
+	ErrNegativeWeight   = errors.New("gocontainer: edge weight is negative")
+	ErrCycle            = errors.New("gocontainer: graph has a cycle")
+	ErrNoRollback       = errors.New("gocontainer: disjoint set is not created with rollback")
+	ErrInvalidOptions   = errors.New("gocontainer: options are invalid")
+	ErrInvalidParam     = errors.New("gocontainer: sketch parameter is invalid")
+	ErrIncompatible     = errors.New("gocontainer: sketches have different parameters or seeds")
+	ErrInvalidData      = errors.New("gocontainer: sketch data is invalid")
+	ErrInvalidInterval  = errors.New("gocontainer: interval is empty or has a nil endpoint")
+	ErrNilMonoid        = errors.New("gocontainer: monoid is nil")
+	ErrNotGroup         = errors.New("gocontainer: monoid is not a group")
+	ErrNoAction         = errors.New("gocontainer: segment tree is not created with an action")
+	ErrNilLess          = errors.New("gocontainer: less function is nil")
+	ErrInvalidBuckets   = errors.New("gocontainer: bucket bounds are not positive and strictly increasing")
+)
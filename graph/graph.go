@@ -0,0 +1,57 @@
+// Package graph provides shortest path, minimum spanning tree
+// and topological sort algorithms over any graph,
+// driven by the priority queues of this library.
+package graph
+
+import "context"
+
+// Graph is a weighted directed graph, given by callbacks,
+// so that no specific graph type is imposed.
+// Vertices must be usable as map keys.
+// An undirected graph lists each edge in both directions.
+type Graph interface {
+	// Call f with each vertex that v has an edge to, until f returns true.
+	Neighbors(v interface{}, f func(w interface{}) (doesStop bool))
+
+	// Return the weight of the edge from u to v.
+	// It is only called for the edges listed by Neighbors.
+	Weight(u, v interface{}) float64
+}
+
+// Graph made of two functions.
+type Funcs struct {
+	NeighborsFunc func(v interface{}, f func(w interface{}) (doesStop bool))
+	WeightFunc    func(u, v interface{}) float64 // nil for weights of 1.
+}
+
+func (fs Funcs) Neighbors(v interface{},
+	f func(w interface{}) (doesStop bool)) {
+	fs.NeighborsFunc(v, f)
+}
+
+func (fs Funcs) Weight(u, v interface{}) float64 {
+	if fs.WeightFunc == nil {
+		return 1
+	}
+	return fs.WeightFunc(u, v)
+}
+
+type Edge struct {
+	From, To interface{}
+	Weight   float64
+}
+
+type Path struct {
+	Vertices []interface{} // From the source to the destination.
+	Cost     float64       // Sum of the edge weights.
+}
+
+// The algorithms check the context after every checkInterval vertices.
+const checkInterval = 64
+
+func checkCtx(ctx context.Context, n int) error {
+	if n%checkInterval == 0 && ctx != nil {
+		return ctx.Err()
+	}
+	return nil
+}
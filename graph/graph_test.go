@@ -0,0 +1,358 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/dsu"
+)
+
+// Adjacency map of a weighted digraph over ints.
+type testGraph map[int]map[int]float64
+
+func (tg testGraph) Neighbors(v interface{},
+	f func(w interface{}) (doesStop bool)) {
+	ws := make([]int, 0, len(tg[v.(int)]))
+	for w := range tg[v.(int)] {
+		ws = append(ws, w)
+	}
+	sort.Ints(ws) // For deterministic results.
+	for _, w := range ws {
+		if f(w) {
+			return
+		}
+	}
+}
+
+func (tg testGraph) Weight(u, v interface{}) float64 {
+	return tg[u.(int)][v.(int)]
+}
+
+func (tg testGraph) addEdge(u, v int, w float64) {
+	if tg[u] == nil {
+		tg[u] = make(map[int]float64)
+	}
+	tg[u][v] = w
+}
+
+func (tg testGraph) reverse() testGraph {
+	rg := make(testGraph)
+	for u, ws := range tg {
+		for v, w := range ws {
+			rg.addEdge(v, u, w)
+		}
+	}
+	return rg
+}
+
+func randomGraph(r *rand.Rand, n, m int, isUndirected bool) testGraph {
+	tg := make(testGraph)
+	for i := 0; i < m; i++ {
+		u, v, w := r.Intn(n), r.Intn(n), float64(r.Intn(20))
+		if u == v {
+			continue
+		}
+		tg.addEdge(u, v, w)
+		if isUndirected {
+			tg.addEdge(v, u, w)
+		}
+	}
+	return tg
+}
+
+// Distances by the Bellman-Ford algorithm.
+func bellmanFord(tg testGraph, n, src int) []float64 {
+	dist := make([]float64, n)
+	for i := range dist {
+		dist[i] = math.Inf(1)
+	}
+	dist[src] = 0
+	for i := 0; i < n; i++ {
+		for u, ws := range tg {
+			for v, w := range ws {
+				if dist[u]+w < dist[v] {
+					dist[v] = dist[u] + w
+				}
+			}
+		}
+	}
+	return dist
+}
+
+func checkPath(t *testing.T, tg testGraph, p Path, src, dst int,
+	cost float64) {
+	t.Helper()
+	if p.Cost != cost || p.Vertices[0] != src ||
+		p.Vertices[len(p.Vertices)-1] != dst {
+		t.Fatalf("Path %v from %d to %d, want cost %v", p, src, dst, cost)
+	}
+	var sum float64
+	for i := 1; i < len(p.Vertices); i++ {
+		w, ok := tg[p.Vertices[i-1].(int)][p.Vertices[i].(int)]
+		if !ok {
+			t.Fatalf("Path %v has no edge at %d", p, i)
+		}
+		sum += w
+	}
+	if sum != cost {
+		t.Fatalf("Path %v costs %v, want %v", p, sum, cost)
+	}
+}
+
+func TestShortestPath(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const n = 60
+	for round := 0; round < 5; round++ {
+		tg := randomGraph(r, n, 200, false)
+		rg := tg.reverse()
+		src := r.Intn(n)
+		want := bellmanFord(tg, n, src)
+		dist, _, err := ShortestPaths(context.Background(), tg, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for dst := 0; dst < n; dst++ {
+			if math.IsInf(want[dst], 1) {
+				if _, ok := dist[dst]; ok {
+					t.Fatalf("%d is reached from %d", dst, src)
+				}
+				_, err := ShortestPath(nil, tg, src, dst)
+				if !errors.Is(err, gocontainer.ErrNoPath) {
+					t.Fatalf("ShortestPath error: %v", err)
+				}
+				_, err = BidirectionalDijkstra(nil, tg, rg, src, dst)
+				if !errors.Is(err, gocontainer.ErrNoPath) {
+					t.Fatalf("BidirectionalDijkstra error: %v", err)
+				}
+				continue
+			}
+			if dist[dst] != want[dst] {
+				t.Fatalf("dist[%d] = %v, want %v", dst, dist[dst], want[dst])
+			}
+			p, err := ShortestPath(nil, tg, src, dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPath(t, tg, p, src, dst, want[dst])
+			p, err = BidirectionalDijkstra(nil, tg, rg, src, dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPath(t, tg, p, src, dst, want[dst])
+		}
+	}
+}
+
+func TestAStar(t *testing.T) {
+	// A 30x30 grid with walls, and the Manhattan distance as heuristic.
+	const size = 30
+	r := rand.New(rand.NewSource(2))
+	tg := make(testGraph)
+	isWall := func(x, y int) bool {
+		return x%4 == 2 && r.Intn(5) > 0
+	}
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			if isWall(x, y) {
+				continue
+			}
+			for _, d := range [][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}} {
+				nx, ny := x+d[0], y+d[1]
+				if nx >= 0 && nx < size && ny >= 0 && ny < size {
+					tg.addEdge(x*size+y, nx*size+ny, 1)
+				}
+			}
+		}
+	}
+	dst := size*size - 1
+	h := func(v interface{}) float64 {
+		x, y := v.(int)/size, v.(int)%size
+		return math.Abs(float64(size-1-x)) + math.Abs(float64(size-1-y))
+	}
+	want, err := ShortestPath(nil, tg, 0, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := AStar(nil, tg, 0, dst, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPath(t, tg, p, 0, dst, want.Cost)
+}
+
+func TestNegativeWeight(t *testing.T) {
+	tg := make(testGraph)
+	tg.addEdge(0, 1, 1)
+	tg.addEdge(1, 2, -1)
+	_, err := ShortestPath(nil, tg, 0, 2)
+	if !errors.Is(err, gocontainer.ErrNegativeWeight) {
+		t.Errorf("ShortestPath error: %v", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	tg := randomGraph(rand.New(rand.NewSource(3)), 500, 3000, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := ShortestPaths(ctx, tg, 0); err != context.Canceled {
+		t.Errorf("ShortestPaths error: %v", err)
+	}
+	if _, _, err := PrimMST(ctx, tg, 0); err != context.Canceled {
+		t.Errorf("PrimMST error: %v", err)
+	}
+}
+
+func TestPrimMST(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	const n = 80
+	tg := randomGraph(r, n, 300, true)
+	edges, total, err := PrimMST(nil, tg, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Kruskal on the component of 0.
+	var all []Edge
+	for u, ws := range tg {
+		for v, w := range ws {
+			if u < v {
+				all = append(all, Edge{u, v, w})
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Weight < all[j].Weight })
+	d := dsu.NewDenseDSU(n, false)
+	var chosen []Edge
+	for _, e := range all {
+		if d.Union(e.From.(int), e.To.(int)) {
+			chosen = append(chosen, e)
+		}
+	}
+	var want float64
+	for _, e := range chosen {
+		if d.Connected(0, e.From.(int)) {
+			want += e.Weight
+		}
+	}
+	if total != want {
+		t.Errorf("Total weight: %v, want %v", total, want)
+	}
+	if len(edges) != d.SetSize(0)-1 {
+		t.Errorf("%d edges, want %d", len(edges), d.SetSize(0)-1)
+	}
+	tree := dsu.NewDenseDSU(n, false)
+	var sum float64
+	for _, e := range edges {
+		if tg[e.From.(int)][e.To.(int)] != e.Weight ||
+			!tree.Union(e.From.(int), e.To.(int)) {
+			t.Fatalf("Invalid edge %v", e)
+		}
+		sum += e.Weight
+	}
+	if sum != total {
+		t.Errorf("Sum of the edges: %v, want %v", sum, total)
+	}
+}
+
+// Enumerate the simple paths from src to dst, and return their costs.
+func allPathCosts(tg testGraph, src, dst int) []float64 {
+	var costs []float64
+	visited := make(map[int]bool)
+	var dfs func(v int, cost float64)
+	dfs = func(v int, cost float64) {
+		if v == dst {
+			costs = append(costs, cost)
+			return
+		}
+		visited[v] = true
+		for w, wt := range tg[v] {
+			if !visited[w] {
+				dfs(w, cost+wt)
+			}
+		}
+		visited[v] = false
+	}
+	dfs(src, 0)
+	sort.Float64s(costs)
+	return costs
+}
+
+func TestKShortestPaths(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	for round := 0; round < 10; round++ {
+		tg := randomGraph(r, 9, 25, false)
+		want := allPathCosts(tg, 0, 8)
+		paths, err := KShortestPaths(nil, tg, 0, 8, 10)
+		if len(want) == 0 {
+			if !errors.Is(err, gocontainer.ErrNoPath) {
+				t.Fatalf("KShortestPaths error: %v", err)
+			}
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if len(want) > 10 {
+			want = want[:10]
+		}
+		if len(paths) != len(want) {
+			t.Fatalf("%d paths, want %d", len(paths), len(want))
+		}
+		seen := make(map[string]bool)
+		for i, p := range paths {
+			checkPath(t, tg, p, 0, 8, want[i])
+			visited := make(map[interface{}]bool)
+			for _, v := range p.Vertices {
+				if visited[v] {
+					t.Fatalf("Path %v has a loop", p)
+				}
+				visited[v] = true
+			}
+			key := fmt.Sprint(p.Vertices)
+			if seen[key] {
+				t.Fatalf("Path %v is repeated", p)
+			}
+			seen[key] = true
+		}
+	}
+}
+
+func TestTopologicalSort(t *testing.T) {
+	tg := make(testGraph)
+	tg.addEdge(5, 0, 1)
+	tg.addEdge(4, 0, 1)
+	tg.addEdge(5, 2, 1)
+	tg.addEdge(2, 3, 1)
+	tg.addEdge(3, 1, 1)
+	tg.addEdge(4, 1, 1)
+	vertices := []interface{}{0, 1, 2, 3, 4, 5}
+	less := func(a, b interface{}) bool { return a.(int) < b.(int) }
+	order, err := TopologicalSort(nil, tg, vertices, less)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{4, 5, 0, 2, 3, 1}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Order: %v, want %v", order, want)
+		}
+	}
+	// Duplicate vertices are ignored.
+	order, err = TopologicalSort(nil, tg,
+		append([]interface{}{2, 5}, vertices...), less)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Fatalf("Order with duplicates: %v, want %v", order, want)
+	}
+	tg.addEdge(1, 2, 1)
+	order, err = TopologicalSort(nil, tg, vertices, less)
+	if !errors.Is(err, gocontainer.ErrCycle) || len(order) != 3 {
+		t.Errorf("Order: %v, error: %v", order, err)
+	}
+}
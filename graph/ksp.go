@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+// A candidate path for Yen's algorithm.
+type candidate struct {
+	p Path
+}
+
+func (c *candidate) Less(another interface{}) bool {
+	a := another.(*candidate)
+	return c.p.Cost < a.p.Cost ||
+		c.p.Cost == a.p.Cost && len(c.p.Vertices) < len(a.p.Vertices)
+}
+
+type edgeKey struct {
+	from, to interface{}
+}
+
+// g without some vertices and edges.
+type filteredGraph struct {
+	g        Graph
+	vertices map[interface{}]bool
+	edges    map[edgeKey]bool
+}
+
+func (fg *filteredGraph) Neighbors(v interface{},
+	f func(w interface{}) (doesStop bool)) {
+	fg.g.Neighbors(v, func(w interface{}) bool {
+		if fg.vertices[w] || fg.edges[edgeKey{v, w}] {
+			return false
+		}
+		return f(w)
+	})
+}
+
+func (fg *filteredGraph) Weight(u, v interface{}) float64 {
+	return fg.g.Weight(u, v)
+}
+
+// Return up to k shortest loopless paths from src to dst,
+// in ascending order of cost, by Yen's algorithm.
+// It returns fewer paths if there are no more.
+// The errors are the same as ShortestPath, except that gocontainer.ErrNoPath
+// is returned only if dst is unreachable.
+// It panics if k is non-positive.
+func KShortestPaths(ctx context.Context, g Graph, src, dst interface{},
+	k int) ([]Path, error) {
+	if k <= 0 {
+		panic(fmt.Errorf("%w: %d", gocontainer.ErrInvalidK, k))
+	}
+	first, err := ShortestPath(ctx, g, src, dst)
+	if err != nil {
+		return nil, err
+	}
+	paths := []Path{first}
+	ids := make(map[interface{}]int) // To make the keys of the paths.
+	seen := map[string]bool{pathKey(ids, first.Vertices): true}
+	candidates := pqueue.NewPriorityQueue(0, false, false)
+	fg := &filteredGraph{g: g}
+	for len(paths) < k {
+		last := paths[len(paths)-1].Vertices
+		var rootCost float64
+		for i := 0; i < len(last)-1; i++ {
+			// Deviate from last at its i-th vertex, the spur vertex.
+			root := last[:i+1]
+			fg.vertices = make(map[interface{}]bool, i)
+			for _, v := range root[:i] {
+				fg.vertices[v] = true
+			}
+			fg.edges = make(map[edgeKey]bool)
+			for _, p := range paths {
+				if len(p.Vertices) > i+1 && hasPrefix(p.Vertices, root) {
+					fg.edges[edgeKey{p.Vertices[i], p.Vertices[i+1]}] = true
+				}
+			}
+			spur, err := ShortestPath(ctx, fg, last[i], dst)
+			if err == nil {
+				vs := make([]interface{}, 0, i+len(spur.Vertices))
+				vs = append(append(vs, root[:i]...), spur.Vertices...)
+				if key := pathKey(ids, vs); !seen[key] {
+					seen[key] = true
+					candidates.Enqueue(&candidate{
+						Path{Vertices: vs, Cost: rootCost + spur.Cost}})
+				}
+			} else if !errors.Is(err, gocontainer.ErrNoPath) {
+				return nil, err
+			}
+			rootCost += g.Weight(last[i], last[i+1])
+		}
+		c, ok := candidates.Dequeue()
+		if !ok {
+			break
+		}
+		paths = append(paths, c.(*candidate).p)
+	}
+	return paths, nil
+}
+
+func hasPrefix(vs, prefix []interface{}) bool {
+	for i, v := range prefix {
+		if vs[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Return a key identifying the path, with the vertices numbered by ids.
+func pathKey(ids map[interface{}]int, vs []interface{}) string {
+	b := make([]byte, 0, len(vs)*2)
+	var buf [binary.MaxVarintLen64]byte
+	for _, v := range vs {
+		id, ok := ids[v]
+		if !ok {
+			id = len(ids)
+			ids[v] = id
+		}
+		n := binary.PutUvarint(buf[:], uint64(id))
+		b = append(b, buf[:n]...)
+	}
+	return string(b)
+}
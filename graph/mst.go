@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+// Return the edges of a minimum spanning tree of the connected component
+// of root in the undirected graph g, and their total weight,
+// by Prim's algorithm. Negative weights are allowed.
+// It returns the error of ctx if ctx is done. ctx can be nil.
+func PrimMST(ctx context.Context, g Graph, root interface{}) (
+	edges []Edge, total float64, err error) {
+	// Each vertex out of the tree is keyed by its lightest edge to the tree.
+	pq := pqueue.NewPriorityQueueEx(0, false, false)
+	open := map[interface{}]*gocontainer.IndexedComparableItem{
+		root: gocontainer.NewIndexedComparableItem(&label{v: root}),
+	}
+	pq.Enqueue(open[root])
+	from := make(map[interface{}]interface{}) // The tree end of the edge.
+	inTree := make(map[interface{}]bool)
+	for n := 0; ; n++ {
+		if err = checkCtx(ctx, n); err != nil {
+			return nil, 0, err
+		}
+		ici, ok := pq.Dequeue()
+		if !ok {
+			return
+		}
+		l := ici.Get().(*label)
+		delete(open, l.v)
+		inTree[l.v] = true
+		if u, ok := from[l.v]; ok {
+			edges = append(edges, Edge{From: u, To: l.v, Weight: l.key})
+			total += l.key
+		}
+		g.Neighbors(l.v, func(w interface{}) bool {
+			if inTree[w] {
+				return false
+			}
+			wt := g.Weight(l.v, w)
+			if ici := open[w]; ici == nil {
+				ici = gocontainer.NewIndexedComparableItem(&label{w, wt})
+				open[w] = ici
+				pq.Enqueue(ici)
+			} else if wt < ici.Get().(*label).key {
+				pq.Update(ici, &label{w, wt})
+			} else {
+				return false
+			}
+			from[w] = l.v
+			return false
+		})
+	}
+}
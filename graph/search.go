@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+// A vertex in the open set, ordered by its key.
+type label struct {
+	v   interface{}
+	key float64 // Distance, plus the heuristic for A*.
+}
+
+func (l *label) Less(another interface{}) bool {
+	return l.key < another.(*label).key
+}
+
+// State of a Dijkstra or A* search from a source.
+type search struct {
+	g    Graph
+	h    func(v interface{}) float64 // nil for Dijkstra.
+	dist map[interface{}]float64     // Best known distances.
+	prev map[interface{}]interface{} // Previous vertices on the best paths.
+	open map[interface{}]*gocontainer.IndexedComparableItem
+	done map[interface{}]bool // Settled vertices.
+	pq   *pqueue.PriorityQueueEx
+	n    int // Number of settled vertices.
+
+	// Called when dist[w] is improved, if not nil.
+	onImprove func(w interface{}, d float64)
+}
+
+func newSearch(g Graph, src interface{},
+	h func(v interface{}) float64) *search {
+	s := &search{
+		g:    g,
+		h:    h,
+		dist: map[interface{}]float64{src: 0},
+		prev: make(map[interface{}]interface{}),
+		open: make(map[interface{}]*gocontainer.IndexedComparableItem),
+		done: make(map[interface{}]bool),
+		pq:   pqueue.NewPriorityQueueEx(0, false, false),
+	}
+	s.push(src, 0)
+	return s
+}
+
+func (s *search) push(v interface{}, d float64) {
+	key := d
+	if s.h != nil {
+		key += s.h(v)
+	}
+	if ici := s.open[v]; ici != nil {
+		s.pq.Update(ici, &label{v, key})
+		return
+	}
+	ici := gocontainer.NewIndexedComparableItem(&label{v, key})
+	s.open[v] = ici
+	s.pq.Enqueue(ici)
+}
+
+// Return the least key in the open set. ok is false if it is empty.
+func (s *search) topKey() (key float64, ok bool) {
+	ici := s.pq.Top()
+	if ici == nil {
+		return
+	}
+	return ici.Get().(*label).key, true
+}
+
+// Settle the open vertex with the least key, relax its edges,
+// and return it. ok is false if the open set is empty.
+func (s *search) step(ctx context.Context) (
+	v interface{}, ok bool, err error) {
+	if err = checkCtx(ctx, s.n); err != nil {
+		return
+	}
+	ici, ok := s.pq.Dequeue()
+	if !ok {
+		return
+	}
+	v = ici.Get().(*label).v
+	delete(s.open, v)
+	s.done[v] = true
+	s.n++
+	d := s.dist[v]
+	s.g.Neighbors(v, func(w interface{}) bool {
+		if s.done[w] {
+			return false
+		}
+		wt := s.g.Weight(v, w)
+		if wt < 0 {
+			err = fmt.Errorf("%w: %v from %v to %v",
+				gocontainer.ErrNegativeWeight, wt, v, w)
+			return true
+		}
+		if old, ok := s.dist[w]; ok && old <= d+wt {
+			return false
+		}
+		s.dist[w] = d + wt
+		s.prev[w] = v
+		s.push(w, d+wt)
+		if s.onImprove != nil {
+			s.onImprove(w, d+wt)
+		}
+		return false
+	})
+	return
+}
+
+// Return the path from the source to dst, which must be reached.
+func (s *search) path(dst interface{}) Path {
+	var vs []interface{}
+	for v := dst; ; {
+		vs = append(vs, v)
+		p, ok := s.prev[v]
+		if !ok {
+			break
+		}
+		v = p
+	}
+	for i, j := 0, len(vs)-1; i < j; i, j = i+1, j-1 {
+		vs[i], vs[j] = vs[j], vs[i]
+	}
+	return Path{Vertices: vs, Cost: s.dist[dst]}
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Return the shortest path from src to dst by Dijkstra's algorithm.
+// The edge weights must be non-negative.
+// It returns an error wrapping gocontainer.ErrNoPath if dst is unreachable,
+// gocontainer.ErrNegativeWeight if a negative weight is found,
+// or the error of ctx if ctx is done during the search.
+// ctx can be nil.
+func ShortestPath(ctx context.Context, g Graph, src, dst interface{}) (
+	Path, error) {
+	return AStar(ctx, g, src, dst, nil)
+}
+
+// Return the shortest distances from src to all the vertices reachable
+// from it, and the previous vertices on the shortest paths,
+// by Dijkstra's algorithm.
+// The errors are the same as ShortestPath.
+func ShortestPaths(ctx context.Context, g Graph, src interface{}) (
+	dist map[interface{}]float64, prev map[interface{}]interface{},
+	err error) {
+	s := newSearch(g, src, nil)
+	for {
+		var ok bool
+		if _, ok, err = s.step(ctx); err != nil {
+			return nil, nil, err
+		} else if !ok {
+			return s.dist, s.prev, nil
+		}
+	}
+}
+
+// Return the shortest path from src to dst by A* search
+// with heuristic h, which estimates the distance from a vertex to dst.
+// h must be admissible (never overestimating) and consistent
+// (h(u) <= Weight(u, v) + h(v) for each edge),
+// or the path may not be the shortest.
+// A nil h is the same as ShortestPath.
+// The errors are the same as ShortestPath.
+func AStar(ctx context.Context, g Graph, src, dst interface{},
+	h func(v interface{}) float64) (Path, error) {
+	s := newSearch(g, src, h)
+	for {
+		v, ok, err := s.step(ctx)
+		if err != nil {
+			return Path{}, err
+		} else if !ok {
+			return Path{}, fmt.Errorf("%w: from %v to %v",
+				gocontainer.ErrNoPath, src, dst)
+		} else if v == dst {
+			return s.path(dst), nil
+		}
+	}
+}
+
+// Return the shortest path from src to dst by searching from both ends.
+// It usually settles much fewer vertices than ShortestPath.
+// reverse is the reverse graph of g:
+// it has an edge from v to u with weight w iff g has one from u to v.
+// For an undirected graph, reverse can be g itself.
+// The errors are the same as ShortestPath.
+func BidirectionalDijkstra(ctx context.Context, g, reverse Graph,
+	src, dst interface{}) (Path, error) {
+	if src == dst {
+		return Path{Vertices: []interface{}{src}}, nil
+	}
+	fwd, bwd := newSearch(g, src, nil), newSearch(reverse, dst, nil)
+	var best float64 // Length of the shortest path found so far.
+	var meet interface{}
+	isFound := false
+	improve := func(other *search) func(w interface{}, d float64) {
+		return func(w interface{}, d float64) {
+			if od, ok := other.dist[w]; ok && (!isFound || d+od < best) {
+				best, meet, isFound = d+od, w, true
+			}
+		}
+	}
+	fwd.onImprove, bwd.onImprove = improve(bwd), improve(fwd)
+	for {
+		fk, fok := fwd.topKey()
+		bk, bok := bwd.topKey()
+		if !fok || !bok || isFound && fk+bk >= best {
+			break
+		}
+		s := fwd
+		if bk < fk {
+			s = bwd
+		}
+		if _, _, err := s.step(ctx); err != nil {
+			return Path{}, err
+		}
+	}
+	if !isFound {
+		return Path{}, fmt.Errorf("%w: from %v to %v",
+			gocontainer.ErrNoPath, src, dst)
+	}
+	p := fwd.path(meet)
+	for v := meet; v != dst; {
+		v = bwd.prev[v]
+		p.Vertices = append(p.Vertices, v)
+	}
+	p.Cost = best
+	return p, nil
+}
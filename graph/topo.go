@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+// A vertex ready to be output, ordered by less.
+type ready struct {
+	v    interface{}
+	less func(a, b interface{}) bool
+}
+
+func (r *ready) Less(another interface{}) bool {
+	return r.less(r.v, another.(*ready).v)
+}
+
+// Return the vertices of the directed graph g in a topological order.
+// Among the vertices whose predecessors are all output,
+// the least one by less is output first, so the order is unique.
+// If less is nil, the vertices must be gocontainer.Comparable.
+//
+// vertices must contain all the vertices of g. The edges to other vertices
+// are ignored, and so are the duplicates in vertices.
+// It returns an error wrapping gocontainer.ErrCycle, with the vertices output
+// before the cycle, if g has a cycle,
+// or the error of ctx if ctx is done. ctx can be nil.
+func TopologicalSort(ctx context.Context, g Graph, vertices []interface{},
+	less func(a, b interface{}) bool) ([]interface{}, error) {
+	if less == nil {
+		less = func(a, b interface{}) bool {
+			return a.(gocontainer.Comparable).Less(b)
+		}
+	}
+	inDegree := make(map[interface{}]int, len(vertices))
+	unique := make([]interface{}, 0, len(vertices))
+	for _, v := range vertices {
+		if _, ok := inDegree[v]; !ok {
+			inDegree[v] = 0
+			unique = append(unique, v)
+		}
+	}
+	vertices = unique
+	for _, v := range vertices {
+		g.Neighbors(v, func(w interface{}) bool {
+			if d, ok := inDegree[w]; ok {
+				inDegree[w] = d + 1
+			}
+			return false
+		})
+	}
+	pq := pqueue.NewPriorityQueue(len(vertices), false, false)
+	for _, v := range vertices {
+		if inDegree[v] == 0 {
+			pq.Enqueue(&ready{v, less})
+		}
+	}
+	order := make([]interface{}, 0, len(vertices))
+	for {
+		if err := checkCtx(ctx, len(order)); err != nil {
+			return nil, err
+		}
+		x, ok := pq.Dequeue()
+		if !ok {
+			break
+		}
+		v := x.(*ready).v
+		order = append(order, v)
+		g.Neighbors(v, func(w interface{}) bool {
+			if d, ok := inDegree[w]; ok {
+				inDegree[w] = d - 1
+				if d == 1 {
+					pq.Enqueue(&ready{w, less})
+				}
+			}
+			return false
+		})
+	}
+	if len(order) < len(inDegree) {
+		return order, fmt.Errorf("%w: %d of %d vertices are on or after cycles",
+			gocontainer.ErrCycle, len(inDegree)-len(order), len(inDegree))
+	}
+	return order, nil
+}
@@ -1,11 +1,20 @@
 package gocontainer
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 type IndexedComparableItem struct {
-	x    Comparable
-	idx  int
-	lock sync.RWMutex
+	// Accessed atomically, without the lock,
+	// because the heap updates it on every swap.
+	// Keep it the first field to be 64-bit aligned on 32-bit platforms.
+	idx int64
+
+	x     Comparable
+	owner interface{} // The container holding the item, nil if free.
+	gen   uint64      // Incremented each time the item is acquired.
+	lock  sync.RWMutex
 }
 
 func NewIndexedComparableItem(x Comparable) *IndexedComparableItem {
@@ -33,17 +42,65 @@ func (ici *IndexedComparableItem) Index() int {
 	if ici == nil {
 		return 0
 	}
+	return int(atomic.LoadInt64(&ici.idx))
+}
+
+// This method should be called by other container.
+// Do NOT call it directly.
+func (ici *IndexedComparableItem) UpdateIndex(idx int) {
+	atomic.StoreInt64(&ici.idx, int64(idx))
+}
+
+// Return the container holding the item, or nil if the item is free.
+func (ici *IndexedComparableItem) Owner() interface{} {
+	if ici == nil {
+		return nil
+	}
 	ici.lock.RLock()
 	defer ici.lock.RUnlock()
-	return ici.idx
+	return ici.owner
+}
+
+// Return the number of times the item has been put into a container.
+// Compare it with a previous value to find out whether the item
+// has been dequeued and reused since then, or pass it to
+// PriorityQueueEx.TryUpdateGeneration and TryRemoveGeneration
+// to reject a stale handle.
+func (ici *IndexedComparableItem) Generation() uint64 {
+	if ici == nil {
+		return 0
+	}
+	ici.lock.RLock()
+	defer ici.lock.RUnlock()
+	return ici.gen
 }
 
 // This method should be called by other container.
 // Do NOT call it directly.
-func (ici *IndexedComparableItem) UpdateIndex(idx int) {
+// Take the ownership of the item, and return false if it has an owner.
+func (ici *IndexedComparableItem) Acquire(owner interface{}) bool {
 	ici.lock.Lock()
 	defer ici.lock.Unlock()
-	ici.idx = idx
+	if ici.owner != nil {
+		return false
+	}
+	ici.owner = owner
+	ici.gen++
+	return true
+}
+
+// This method should be called by other container.
+// Do NOT call it directly.
+// Give up the ownership of the item, and return false if owner doesn't own it.
+func (ici *IndexedComparableItem) Release(owner interface{}) bool {
+	ici.lock.Lock()
+	defer ici.lock.Unlock()
+	if ici.owner == nil || ici.owner != owner {
+		return false
+	}
+	ici.owner = nil
+	atomic.StoreInt64(&ici.idx, -1)
+	return true
 }
 
 func (ici *IndexedComparableItem) Less(another interface{}) bool {
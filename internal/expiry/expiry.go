@@ -0,0 +1,119 @@
+package expiry
+
+import (
+	stdheap "container/heap"
+	"sync"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/internal/heap"
+)
+
+// An item with its deadline, in the expiry index.
+type Entry struct {
+	Deadline time.Time
+	Item     interface{}
+	idx      int
+}
+
+func (e *Entry) Less(another interface{}) bool {
+	return e.Deadline.Before(another.(*Entry).Deadline)
+}
+
+func (e *Entry) Index() int {
+	return e.idx
+}
+
+func (e *Entry) UpdateIndex(idx int) {
+	e.idx = idx
+}
+
+// Index of the deadlines of the items in a container,
+// to find the expired items in O(log(n)) time each.
+// It is not synchronized. The container should protect it by its lock.
+type Index struct {
+	h *heap.MinHeap
+}
+
+func NewIndex() *Index {
+	return &Index{h: heap.NewMinHeap(0, true)}
+}
+
+func (ix *Index) Len() int {
+	return ix.h.Len()
+}
+
+func (ix *Index) Add(item interface{}, deadline time.Time) *Entry {
+	e := &Entry{Deadline: deadline, Item: item}
+	stdheap.Push(ix.h, e)
+	return e
+}
+
+// Remove e from the index. Do nothing if e is nil or not in the index.
+func (ix *Index) Remove(e *Entry) {
+	if e == nil || ix.h.Get(e.idx) != gocontainer.Comparable(e) {
+		return
+	}
+	stdheap.Remove(ix.h, e.idx)
+}
+
+// Report whether any entry has a deadline not after now.
+func (ix *Index) HasExpired(now time.Time) bool {
+	return ix.h.Len() > 0 && !ix.h.Top().(*Entry).Deadline.After(now)
+}
+
+// Remove the entries whose deadlines are not after now,
+// and call f with their items in the order of deadline.
+func (ix *Index) PopExpired(now time.Time, f func(item interface{})) {
+	for ix.h.Len() > 0 {
+		e := ix.h.Top().(*Entry)
+		if e.Deadline.After(now) {
+			return
+		}
+		stdheap.Pop(ix.h)
+		f(e.Item)
+	}
+}
+
+func (ix *Index) Clear() {
+	ix.h.Clear()
+}
+
+// Call sweep on every receive from tick, or every interval if tick is nil,
+// in a new goroutine, until stop is called.
+// stop is safe for concurrent use, and waits for the goroutine to exit.
+// If tick is nil and interval is non-positive, no goroutine is started.
+func StartSweeper(tick <-chan time.Time, interval time.Duration,
+	sweep func()) (stop func()) {
+	if tick == nil && interval <= 0 {
+		return func() {}
+	}
+	var ticker *time.Ticker
+	if tick == nil {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+		for {
+			select {
+			case <-quit:
+				return
+			case <-tick:
+				sweep()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(quit)
+		})
+		<-done
+	}
+}
@@ -0,0 +1,66 @@
+package expiry
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	base := time.Unix(1000, 0)
+	ix := NewIndex()
+	entries := make([]*Entry, 5)
+	for i, s := range []int{3, 1, 4, 1, 5} {
+		entries[i] = ix.Add(i, base.Add(time.Duration(s)*time.Second))
+	}
+	ix.Remove(entries[2])
+	ix.Remove(entries[2]) // Removing twice is a no-op.
+	if ix.HasExpired(base) || !ix.HasExpired(base.Add(time.Second)) {
+		t.Fatal("HasExpired is wrong around the earliest deadline.")
+	}
+	var items []interface{}
+	ix.PopExpired(base.Add(4*time.Second), func(item interface{}) {
+		items = append(items, item)
+	})
+	if len(items) != 3 || items[2] != 0 {
+		t.Fatalf("Expired items: %v, want [1 3 0] or [3 1 0]", items)
+	}
+	if n := ix.Len(); n != 1 {
+		t.Fatalf("Len(%d) != 1", n)
+	}
+	ix.Clear()
+	if n := ix.Len(); n != 0 {
+		t.Fatalf("Len(%d) != 0 after Clear", n)
+	}
+	if ix.HasExpired(base.Add(time.Hour)) {
+		t.Fatal("HasExpired on an empty index.")
+	}
+}
+
+func TestStartSweeper(t *testing.T) {
+	tick := make(chan time.Time)
+	swept := make(chan struct{}, 2)
+	stop := StartSweeper(tick, 0, func() {
+		swept <- struct{}{}
+	})
+	tick <- time.Now()
+	tick <- time.Now()
+	stop()
+	stop()
+	if n := len(swept); n != 2 {
+		t.Fatalf("Swept %d times, want 2", n)
+	}
+	StartSweeper(nil, 0, nil)() // No sweeper.
+
+	// Concurrent stops.
+	stop = StartSweeper(nil, time.Hour, func() {})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stop()
+		}()
+	}
+	wg.Wait()
+}
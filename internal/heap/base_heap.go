@@ -1,7 +1,7 @@
 package heap
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/donyori/gocontainer"
 )
@@ -10,6 +10,9 @@ import (
 type baseHeap struct {
 	a         []gocontainer.Comparable
 	isIndexed bool
+
+	// Optional. The items are ordered by key(x) instead of x if set.
+	key func(x gocontainer.Comparable) gocontainer.Comparable
 }
 
 func (h *baseHeap) Len() int {
@@ -32,9 +35,15 @@ func (h *baseHeap) Swap(i, j int) {
 // This method should be called by "container/heap" package.
 // Do NOT call it directly.
 func (h *baseHeap) Push(x interface{}) {
-	cx := x.(gocontainer.Comparable)
+	cx, ok := x.(gocontainer.Comparable)
+	if !ok {
+		panic(fmt.Errorf("%w: %T", gocontainer.ErrNotComparable, x))
+	}
 	if h.isIndexed {
-		ix := x.(gocontainer.Indexed)
+		ix, ok := x.(gocontainer.Indexed)
+		if !ok {
+			panic(fmt.Errorf("%w: %T", gocontainer.ErrNotIndexed, x))
+		}
 		ix.UpdateIndex(len(h.a))
 	}
 	h.a = append(h.a, cx)
@@ -72,15 +81,34 @@ func (h *baseHeap) Get(i int) gocontainer.Comparable {
 // For convenience to implement Set() in Heap interface,
 //   and set an item directly without fix the heap in test.
 // Set() should fix the heap by container/heap.Fix() after calling this method.
-func (h *baseHeap) set(i int, x gocontainer.Comparable) {
+func (h *baseHeap) set(i int, x gocontainer.Comparable) error {
 	if i < 0 || i >= len(h.a) { // It's important to avoid setting the index of x to an invalid i.
-		panic(errors.New("index out of range"))
+		return fmt.Errorf("%w: %d", gocontainer.ErrOutOfRange, i)
 	}
 	if h.isIndexed {
-		ix := x.(gocontainer.Indexed)
+		ix, ok := x.(gocontainer.Indexed)
+		if !ok {
+			return fmt.Errorf("%w: %T", gocontainer.ErrNotIndexed, x)
+		}
 		ix.UpdateIndex(i)
 	}
 	h.a[i] = x
+	return nil
+}
+
+// Order the items by key(x) instead of x, or by x if key is nil.
+// Call container/heap.Init after changing it on a non-empty heap.
+func (h *baseHeap) SetKey(
+	key func(x gocontainer.Comparable) gocontainer.Comparable) {
+	h.key = key
+}
+
+// Return the sort key of the item at i.
+func (h *baseHeap) keyAt(i int) gocontainer.Comparable {
+	if h.key != nil {
+		return h.key(h.a[i])
+	}
+	return h.a[i]
 }
 
 func (h *baseHeap) Top() gocontainer.Comparable {
@@ -0,0 +1,110 @@
+package heap
+
+import (
+	stdheap "container/heap"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Keep the items for which keep returns true, in place,
+// and return the removed items. The heap should be re-heapified after it.
+// If keep panics, no item is removed, and the panic is propagated.
+func (h *baseHeap) filter(keep func(x gocontainer.Comparable) bool) (
+	removed []gocontainer.Comparable) {
+	n, i := 0, 0
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		// Put back the items not visited yet, and the removed items.
+		// n + (len(h.a) - i) + len(removed) == len(h.a).
+		m := n + copy(h.a[n:], h.a[i:])
+		copy(h.a[m:], removed)
+		if h.isIndexed {
+			for j := n; j < len(h.a); j++ {
+				h.a[j].(gocontainer.Indexed).UpdateIndex(j)
+			}
+		}
+		panic(r)
+	}()
+	for ; i < len(h.a); i++ {
+		x := h.a[i]
+		if !keep(x) {
+			removed = append(removed, x)
+			if h.isIndexed {
+				x.(gocontainer.Indexed).UpdateIndex(-1) // for safety
+			}
+			continue
+		}
+		if h.isIndexed {
+			x.(gocontainer.Indexed).UpdateIndex(n)
+		}
+		h.a[n] = x
+		n++
+	}
+	for j := n; j < len(h.a); j++ {
+		h.a[j] = nil // To avoid potential memory leak.
+	}
+	h.a = h.a[:n]
+	return
+}
+
+// Replace each item x with f(x) if it is not nil.
+// The heap should be re-heapified after it.
+// If f panics, the items replaced before are kept replaced.
+func (h *baseHeap) replaceAll(
+	f func(x gocontainer.Comparable) gocontainer.Comparable) {
+	for i, x := range h.a {
+		if newX := f(x); newX != nil {
+			if h.isIndexed {
+				newX.(gocontainer.Indexed).UpdateIndex(i)
+			}
+			h.a[i] = newX
+		}
+	}
+}
+
+// Remove the items for which keep returns false, re-heapify in O(n) time,
+// and return the removed items.
+// If keep panics, no item is removed, and the heap is still re-heapified
+// before the panic is propagated.
+func (h *MinHeap) Filter(keep func(x gocontainer.Comparable) bool) (
+	removed []gocontainer.Comparable) {
+	defer stdheap.Init(h)
+	return h.filter(keep)
+}
+
+// Replace each item x with f(x) if it is not nil,
+// and re-heapify in O(n) time.
+// f can also modify x in place and return nil.
+// If f panics, the heap is still re-heapified with the items
+// replaced so far, before the panic is propagated.
+// If Less panics during the re-heapify, the heap is left unordered.
+func (h *MinHeap) ReplaceAll(
+	f func(x gocontainer.Comparable) gocontainer.Comparable) {
+	defer stdheap.Init(h)
+	h.replaceAll(f)
+}
+
+// Remove the items for which keep returns false, re-heapify in O(n) time,
+// and return the removed items.
+// If keep panics, no item is removed, and the heap is still re-heapified
+// before the panic is propagated.
+func (h *MaxHeap) Filter(keep func(x gocontainer.Comparable) bool) (
+	removed []gocontainer.Comparable) {
+	defer stdheap.Init(h)
+	return h.filter(keep)
+}
+
+// Replace each item x with f(x) if it is not nil,
+// and re-heapify in O(n) time.
+// f can also modify x in place and return nil.
+// If f panics, the heap is still re-heapified with the items
+// replaced so far, before the panic is propagated.
+// If Less panics during the re-heapify, the heap is left unordered.
+func (h *MaxHeap) ReplaceAll(
+	f func(x gocontainer.Comparable) gocontainer.Comparable) {
+	defer stdheap.Init(h)
+	h.replaceAll(f)
+}
@@ -0,0 +1,110 @@
+package heap
+
+import (
+	stdheap "container/heap"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gorecover"
+)
+
+func TestFilterAndReplaceAll(t *testing.T) {
+	inputs := []testElement{3, 0, 9, -4, 3, -5, 8, 1, 6}
+	h := NewMaxHeap(0, true)
+	for _, x := range inputs {
+		stdheap.Push(h, gocontainer.NewIndexedComparableItem(x))
+	}
+	removed := h.Filter(func(x gocontainer.Comparable) bool {
+		return x.(*gocontainer.IndexedComparableItem).Get().(testElement)%2 == 0
+	})
+	if len(removed) != 5 || h.Len() != 4 {
+		t.Fatalf("Removed %d items, %d left", len(removed), h.Len())
+	}
+	for _, x := range removed {
+		if idx := x.(*gocontainer.IndexedComparableItem).Index(); idx != -1 {
+			t.Errorf("Index of removed item is %d", idx)
+		}
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// Negate all the items, so the order is reversed.
+	h.ReplaceAll(func(x gocontainer.Comparable) gocontainer.Comparable {
+		ici := x.(*gocontainer.IndexedComparableItem)
+		ici.Set(-ici.Get().(testElement))
+		return nil
+	})
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if top := h.Top().(*gocontainer.IndexedComparableItem).Get(); top !=
+		testElement(4) {
+		t.Fatalf("Top(%v) != 4", top)
+	}
+
+	mh := NewMinHeap(0, false)
+	for _, x := range inputs {
+		stdheap.Push(mh, x)
+	}
+	mh.ReplaceAll(func(x gocontainer.Comparable) gocontainer.Comparable {
+		return x.(testElement) * -1
+	})
+	if err := mh.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if top := mh.Top(); top != testElement(-9) {
+		t.Fatalf("Top(%v) != -9", top)
+	}
+}
+
+func TestFilterAndReplaceAllPanic(t *testing.T) {
+	inputs := []testElement{3, 0, 9, -4, 3, -5, 8, 1, 6}
+	h := NewMinHeap(0, true)
+	for _, x := range inputs {
+		stdheap.Push(h, gocontainer.NewIndexedComparableItem(x))
+	}
+	visited := 0
+	err := gorecover.Recover(func() {
+		h.Filter(func(x gocontainer.Comparable) bool {
+			if visited++; visited > 5 {
+				panic("keep panics")
+			}
+			return visited%2 == 0
+		})
+	})
+	if err == nil {
+		t.Fatal("Filter didn't propagate the panic.")
+	}
+	if h.Len() != len(inputs) {
+		t.Fatalf("Len(%d) != %d after a panic in Filter", h.Len(), len(inputs))
+	}
+	var sum testElement
+	h.Scan(func(x gocontainer.Comparable) bool {
+		sum += x.(*gocontainer.IndexedComparableItem).Get().(testElement)
+		return false
+	})
+	if sum != 21 {
+		t.Errorf("Sum of the items is %d, want 21", sum)
+	}
+	if err = h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	visited = 0
+	err = gorecover.Recover(func() {
+		h.ReplaceAll(func(x gocontainer.Comparable) gocontainer.Comparable {
+			if visited++; visited > 5 {
+				panic("f panics")
+			}
+			ici := x.(*gocontainer.IndexedComparableItem)
+			ici.Set(-ici.Get().(testElement))
+			return nil
+		})
+	})
+	if err == nil {
+		t.Fatal("ReplaceAll didn't propagate the panic.")
+	}
+	if err = h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
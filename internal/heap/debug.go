@@ -0,0 +1,90 @@
+package heap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Check the heap property with less, which should be the Less method of
+// MinHeap or MaxHeap, and check the index of each item if the heap is indexed.
+// Return nil if the heap is valid.
+func (h *baseHeap) validate(less func(i, j int) bool) error {
+	if h == nil {
+		return nil
+	}
+	for i := range h.a {
+		if h.a[i] == nil {
+			return fmt.Errorf("gocontainer: item at %d is nil", i)
+		}
+		if i > 0 {
+			parent := (i - 1) / 2
+			if less(i, parent) {
+				return fmt.Errorf("gocontainer: heap property is violated, "+
+					"item at %d should be before its parent at %d", i, parent)
+			}
+		}
+		if h.isIndexed {
+			ix, ok := h.a[i].(gocontainer.Indexed)
+			if !ok {
+				return fmt.Errorf("gocontainer: item at %d is not Indexed", i)
+			}
+			if idx := ix.Index(); idx != i {
+				return fmt.Errorf("gocontainer: item at %d has index %d",
+					i, idx)
+			}
+		}
+	}
+	return nil
+}
+
+func (h *baseHeap) label(i int) string {
+	x := h.a[i]
+	var s string
+	if ici, ok := x.(*gocontainer.IndexedComparableItem); ok {
+		s = fmt.Sprintf("%v", ici.Get())
+	} else {
+		s = fmt.Sprintf("%v", x)
+	}
+	if ix, ok := x.(gocontainer.Indexed); ok && h.isIndexed {
+		s += fmt.Sprintf(" (index: %d)", ix.Index())
+	}
+	return s
+}
+
+func (h *baseHeap) DebugString() string {
+	if h == nil || len(h.a) == 0 {
+		return "<empty heap>"
+	}
+	var b strings.Builder
+	var walk func(i, depth int)
+	walk = func(i, depth int) {
+		if i >= len(h.a) {
+			return
+		}
+		fmt.Fprintf(&b, "%s[%d] %s\n", strings.Repeat("  ", depth), i,
+			h.label(i))
+		walk(2*i+1, depth+1)
+		walk(2*i+2, depth+1)
+	}
+	walk(0, 0)
+	return b.String()
+}
+
+// Return the heap tree in Graphviz DOT language.
+func (h *baseHeap) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph heap {\n")
+	if h != nil {
+		for i := range h.a {
+			fmt.Fprintf(&b, "\tn%d [label=%q];\n", i,
+				fmt.Sprintf("[%d] %s", i, h.label(i)))
+		}
+		for i := 1; i < len(h.a); i++ {
+			fmt.Fprintf(&b, "\tn%d -> n%d;\n", (i-1)/2, i)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package heap
+
+import (
+	stdheap "container/heap"
+	"strings"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestValidate(t *testing.T) {
+	inputs := []testElement{3, 0, 9, -4, 3, -5, 8}
+	minH := NewMinHeap(len(inputs), false)
+	maxH := NewMaxHeap(len(inputs), false)
+	for _, x := range inputs {
+		stdheap.Push(minH, x)
+		stdheap.Push(maxH, x)
+	}
+	if err := minH.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := maxH.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	minH.set(minH.Len()-1, testElement(-100))
+	if err := minH.Validate(); err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+	maxH.set(maxH.Len()-1, testElement(100))
+	if err := maxH.Validate(); err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+
+	h := NewMinHeap(len(inputs), true)
+	for _, x := range inputs {
+		stdheap.Push(h, gocontainer.NewIndexedComparableItem(x))
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	h.Get(2).(*gocontainer.IndexedComparableItem).UpdateIndex(5)
+	if err := h.Validate(); err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+}
+
+func TestDebugString(t *testing.T) {
+	h := NewMinHeap(0, true)
+	if s := h.DebugString(); s != "<empty heap>" {
+		t.Errorf("DebugString of empty heap: %q", s)
+	}
+	for _, x := range []testElement{3, 1, 2} {
+		stdheap.Push(h, gocontainer.NewIndexedComparableItem(x))
+	}
+	want := "[0] 1 (index: 0)\n  [1] 3 (index: 1)\n  [2] 2 (index: 2)\n"
+	if s := h.DebugString(); s != want {
+		t.Errorf("DebugString: %q != %q", s, want)
+	}
+	dot := h.DOT()
+	t.Log(dot)
+	if !strings.HasPrefix(dot, "digraph heap {\n") ||
+		!strings.Contains(dot, "\tn0 -> n2;\n") {
+		t.Errorf("Wrong DOT: %q", dot)
+	}
+}
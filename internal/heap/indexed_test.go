@@ -2,6 +2,7 @@ package heap
 
 import (
 	stdheap "container/heap"
+	"math/rand"
 	"testing"
 
 	"github.com/donyori/gocontainer"
@@ -62,3 +63,66 @@ func checkIndex(t *testing.T, h *MinHeap) {
 		}
 	}
 }
+
+const benchmarkHeapSize = 10000
+
+func makeBenchmarkInputs() []testElement {
+	r := rand.New(rand.NewSource(1))
+	inputs := make([]testElement, benchmarkHeapSize)
+	for i := range inputs {
+		inputs[i] = testElement(r.Int())
+	}
+	return inputs
+}
+
+// Push all items, update some of them, and then pop all items.
+func benchmarkIndexedHeap(b *testing.B, items []gocontainer.Comparable) {
+	r := rand.New(rand.NewSource(2))
+	h := NewMinHeap(len(items), true)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, x := range items {
+			stdheap.Push(h, x)
+		}
+		for j := 0; j < len(items)/10; j++ {
+			x := items[r.Intn(len(items))]
+			stdheap.Fix(h, x.(gocontainer.Indexed).Index())
+		}
+		for h.Len() > 0 {
+			stdheap.Pop(h)
+		}
+	}
+}
+
+func BenchmarkIndexedComparableItem(b *testing.B) {
+	inputs := makeBenchmarkInputs()
+	items := make([]gocontainer.Comparable, len(inputs))
+	for i := range inputs {
+		items[i] = gocontainer.NewIndexedComparableItem(inputs[i])
+	}
+	benchmarkIndexedHeap(b, items)
+}
+
+func BenchmarkMutexIndexedItem(b *testing.B) {
+	inputs := makeBenchmarkInputs()
+	items := make([]gocontainer.Comparable, len(inputs))
+	for i := range inputs {
+		items[i] = &testMutexIndexedItem{x: inputs[i]}
+	}
+	benchmarkIndexedHeap(b, items)
+}
+
+func BenchmarkIndexedComparableItemUpdateIndex(b *testing.B) {
+	item := gocontainer.NewIndexedComparableItem(testElement(0))
+	for i := 0; i < b.N; i++ {
+		item.UpdateIndex(item.Index() + 1)
+	}
+}
+
+func BenchmarkMutexIndexedItemUpdateIndex(b *testing.B) {
+	item := new(testMutexIndexedItem)
+	for i := 0; i < b.N; i++ {
+		item.UpdateIndex(item.Index() + 1)
+	}
+}
@@ -11,9 +11,18 @@ type Heap interface {
 	Cap() int
 	Get(i int) gocontainer.Comparable
 	Set(i int, x gocontainer.Comparable)
+	TrySet(i int, x gocontainer.Comparable) error
 	Top() gocontainer.Comparable
 	UpdateTop(x gocontainer.Comparable)
+	SetKey(key func(x gocontainer.Comparable) gocontainer.Comparable)
+	PeekN(n int) []gocontainer.Comparable
 	Scan(f func(x gocontainer.Comparable) (doesStop bool))
+	Filter(keep func(x gocontainer.Comparable) bool) (
+		removed []gocontainer.Comparable)
+	ReplaceAll(f func(x gocontainer.Comparable) gocontainer.Comparable)
 	Clear()
 	Reset(capacity int)
+	Validate() error
+	DebugString() string
+	DOT() string
 }
@@ -27,14 +27,27 @@ func NewMaxHeap(capacity int, isIndexed bool) *MaxHeap {
 }
 
 func (h *MaxHeap) Less(i, j int) bool {
-	return h.Get(j).Less(h.Get(i))
+	return h.keyAt(j).Less(h.keyAt(i))
 }
 
 func (h *MaxHeap) Set(i int, x gocontainer.Comparable) {
-	h.set(i, x)
-	stdheap.Fix(h, 0)
+	if err := h.TrySet(i, x); err != nil {
+		panic(err)
+	}
+}
+
+func (h *MaxHeap) TrySet(i int, x gocontainer.Comparable) error {
+	if err := h.set(i, x); err != nil {
+		return err
+	}
+	stdheap.Fix(h, i)
+	return nil
 }
 
 func (h *MaxHeap) UpdateTop(x gocontainer.Comparable) {
 	h.Set(0, x)
 }
+
+func (h *MaxHeap) Validate() error {
+	return h.validate(h.Less)
+}
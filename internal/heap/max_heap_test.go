@@ -68,3 +68,18 @@ func TestMaxHeap(t *testing.T) {
 		t.Fatal("Clear failed.")
 	}
 }
+
+// Set must fix the heap at the index set, not at the top.
+func TestMaxHeapSet(t *testing.T) {
+	h := NewMaxHeap(0, false)
+	for i := 1; i <= 7; i++ {
+		stdheap.Push(h, testElement(i))
+	}
+	h.Set(h.Len()-1, testElement(10))
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if top := h.Top(); top != testElement(10) {
+		t.Errorf("Top(%v) != 10", top)
+	}
+}
@@ -27,14 +27,27 @@ func NewMinHeap(capacity int, isIndexed bool) *MinHeap {
 }
 
 func (h *MinHeap) Less(i, j int) bool {
-	return h.Get(i).Less(h.Get(j))
+	return h.keyAt(i).Less(h.keyAt(j))
 }
 
 func (h *MinHeap) Set(i int, x gocontainer.Comparable) {
-	h.set(i, x)
-	stdheap.Fix(h, 0)
+	if err := h.TrySet(i, x); err != nil {
+		panic(err)
+	}
+}
+
+func (h *MinHeap) TrySet(i int, x gocontainer.Comparable) error {
+	if err := h.set(i, x); err != nil {
+		return err
+	}
+	stdheap.Fix(h, i)
+	return nil
 }
 
 func (h *MinHeap) UpdateTop(x gocontainer.Comparable) {
 	h.Set(0, x)
 }
+
+func (h *MinHeap) Validate() error {
+	return h.validate(h.Less)
+}
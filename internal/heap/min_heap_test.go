@@ -68,3 +68,18 @@ func TestMinHeap(t *testing.T) {
 		t.Fatal("Clear failed.")
 	}
 }
+
+// Set must fix the heap at the index set, not at the top.
+func TestMinHeapSet(t *testing.T) {
+	h := NewMinHeap(0, false)
+	for i := 1; i <= 7; i++ {
+		stdheap.Push(h, testElement(i))
+	}
+	h.Set(h.Len()-1, testElement(-1))
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if top := h.Top(); top != testElement(-1) {
+		t.Errorf("Top(%v) != -1", top)
+	}
+}
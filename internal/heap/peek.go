@@ -0,0 +1,75 @@
+package heap
+
+import (
+	stdheap "container/heap"
+
+	"github.com/donyori/gocontainer"
+)
+
+// A heap of positions in a heap, ordered by the items at the positions.
+// Used as the frontier by peekN.
+type frontier struct {
+	idx  []int
+	less func(i, j int) bool
+}
+
+func (f *frontier) Len() int {
+	return len(f.idx)
+}
+
+func (f *frontier) Less(i, j int) bool {
+	return f.less(f.idx[i], f.idx[j])
+}
+
+func (f *frontier) Swap(i, j int) {
+	f.idx[i], f.idx[j] = f.idx[j], f.idx[i]
+}
+
+func (f *frontier) Push(x interface{}) {
+	f.idx = append(f.idx, x.(int))
+}
+
+func (f *frontier) Pop() interface{} {
+	last := len(f.idx) - 1
+	i := f.idx[last]
+	f.idx = f.idx[:last]
+	return i
+}
+
+// Return the first n items in the order of less, without modifying the heap.
+// The next item must be the top of the frontier, which holds the children
+// of the items already taken,
+// so it takes O(n*log(n)) time, regardless of the size of the heap.
+func (h *baseHeap) peekN(n int, less func(i, j int) bool) (
+	xs []gocontainer.Comparable) {
+	if n > len(h.a) {
+		n = len(h.a)
+	}
+	if n <= 0 {
+		return nil
+	}
+	xs = make([]gocontainer.Comparable, 0, n)
+	f := &frontier{idx: make([]int, 1, n+1), less: less} // Start from the top.
+	for len(xs) < n {
+		i := stdheap.Pop(f).(int)
+		xs = append(xs, h.a[i])
+		for c := i*2 + 1; c <= i*2+2 && c < len(h.a); c++ {
+			stdheap.Push(f, c)
+		}
+	}
+	return xs
+}
+
+// Return the n smallest items in ascending order,
+// or all the items if n > h.Len(). It doesn't modify the heap.
+// O(n*log(n)) time.
+func (h *MinHeap) PeekN(n int) []gocontainer.Comparable {
+	return h.peekN(n, h.Less)
+}
+
+// Return the n greatest items in descending order,
+// or all the items if n > h.Len(). It doesn't modify the heap.
+// O(n*log(n)) time.
+func (h *MaxHeap) PeekN(n int) []gocontainer.Comparable {
+	return h.peekN(n, h.Less)
+}
@@ -0,0 +1,63 @@
+package heap
+
+import (
+	stdheap "container/heap"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestPeekN(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, isTopMax := range []bool{false, true} {
+		var h Heap
+		if isTopMax {
+			h = NewMaxHeap(0, false)
+		} else {
+			h = NewMinHeap(0, false)
+		}
+		inputs := make([]int, 100)
+		for i := range inputs {
+			inputs[i] = r.Intn(50)
+			stdheap.Push(h, testElement(inputs[i]))
+		}
+		if isTopMax {
+			sort.Sort(sort.Reverse(sort.IntSlice(inputs)))
+		} else {
+			sort.Ints(inputs)
+		}
+		for _, n := range []int{-1, 0, 1, 2, 7, 64, 100, 101} {
+			xs := h.PeekN(n)
+			wantLen := n
+			if n < 0 {
+				wantLen = 0
+			} else if n > len(inputs) {
+				wantLen = len(inputs)
+			}
+			if len(xs) != wantLen {
+				t.Fatalf("isTopMax: %t, n: %d, len(PeekN) = %d",
+					isTopMax, n, len(xs))
+			}
+			for i, x := range xs {
+				if x != testElement(inputs[i]) {
+					t.Fatalf("isTopMax: %t, n: %d, PeekN: %v", isTopMax, n, xs)
+				}
+			}
+		}
+		if err := h.Validate(); err != nil || h.Len() != len(inputs) {
+			t.Fatalf("PeekN modified the heap, err: %v", err)
+		}
+	}
+}
+
+func BenchmarkPeekN(b *testing.B) {
+	h := NewMinHeap(0, false)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1<<16; i++ {
+		stdheap.Push(h, testElement(r.Int()))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.PeekN(16)
+	}
+}
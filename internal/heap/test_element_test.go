@@ -1,8 +1,34 @@
 package heap
 
+import "sync"
+
 type testElement int
 
 func (te testElement) Less(another interface{}) bool {
 	a := another.(testElement)
 	return te < a
 }
+
+// The implementation of gocontainer.IndexedComparableItem before its index
+// became atomic, kept as the baseline of the benchmarks.
+type testMutexIndexedItem struct {
+	x    testElement
+	idx  int
+	lock sync.RWMutex
+}
+
+func (item *testMutexIndexedItem) Index() int {
+	item.lock.RLock()
+	defer item.lock.RUnlock()
+	return item.idx
+}
+
+func (item *testMutexIndexedItem) UpdateIndex(idx int) {
+	item.lock.Lock()
+	defer item.lock.Unlock()
+	item.idx = idx
+}
+
+func (item *testMutexIndexedItem) Less(another interface{}) bool {
+	return item.x < another.(*testMutexIndexedItem).x
+}
@@ -0,0 +1,118 @@
+package heap
+
+import (
+	stdheap "container/heap"
+	"errors"
+	"fmt"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Push x into h by container/heap.Push, and return an error instead of panic.
+// If the push fails after x was appended to h (e.g. Less panics because x is
+// incomparable with other items), it tries to remove x to recover h.
+func TryPush(h Heap, x gocontainer.Comparable) (err error) {
+	if x == nil {
+		return gocontainer.ErrNilItem
+	}
+	nBefore := h.Len()
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		err = panicToError(r)
+		nAfter := h.Len()
+		if nAfter == nBefore {
+			// Push didn't modify the heap.
+			return
+		}
+		// Try to recover the heap:
+		idx := -1
+		if ix, ok := x.(gocontainer.Indexed); ok {
+			if i := ix.Index(); h.Get(i) == x {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			// Traverse the whole heap to find the wrong item:
+			for i := 0; i < nAfter; i++ {
+				if h.Get(i) == x {
+					idx = i
+					break
+				}
+			}
+		}
+		if idx < 0 {
+			err = fmt.Errorf("%w; cannot recover the heap, "+
+				"because cannot find the wrong item to remove", err)
+			return
+		}
+		if r2 := tryRemove(h, idx); r2 != nil {
+			err = fmt.Errorf("%w; error occurs when recover the heap: %v",
+				err, r2)
+		}
+		// Succeed to recover the heap.
+	}()
+	stdheap.Push(h, x)
+	return nil
+}
+
+// Replace the top of h with x by UpdateTop, and return an error
+// instead of panic.
+// If it fails (e.g. Less panics because x is incomparable with
+// other items), it tries to remove x and push the old top back to recover h.
+func TryUpdateTop(h Heap, x gocontainer.Comparable) (err error) {
+	if x == nil {
+		return gocontainer.ErrNilItem
+	}
+	top := h.Top()
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		err = panicToError(r)
+		idx := -1
+		for i := 0; i < h.Len(); i++ {
+			if h.Get(i) == x {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			// UpdateTop didn't modify the heap.
+			return
+		}
+		if r2 := tryRemove(h, idx); r2 != nil {
+			err = fmt.Errorf("%w; error occurs when recover the heap: %v",
+				err, r2)
+			return
+		}
+		stdheap.Push(h, top)
+	}()
+	h.UpdateTop(x)
+	return nil
+}
+
+// Call container/heap.Remove, and return the recovered panic value if any.
+func tryRemove(h Heap, i int) (r interface{}) {
+	defer func() {
+		r = recover()
+	}()
+	stdheap.Remove(h, i)
+	return nil
+}
+
+// Convert a value recovered while operating the heap to an error.
+// Panics not raised by the heap itself are regarded as
+// an inconsistency of the items' Less methods.
+func panicToError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		if errors.Is(err, gocontainer.ErrNotComparable) ||
+			errors.Is(err, gocontainer.ErrNotIndexed) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %v", gocontainer.ErrIncomparable, r)
+}
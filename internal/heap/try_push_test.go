@@ -0,0 +1,74 @@
+package heap
+
+import (
+	stdheap "container/heap"
+	"errors"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+type testIncomparable float64
+
+func (ti testIncomparable) Less(another interface{}) bool {
+	return ti < another.(testIncomparable)
+}
+
+func TestTryPush(t *testing.T) {
+	h := NewMinHeap(0, false)
+	for _, x := range []testElement{3, 1, 2} {
+		if err := TryPush(h, x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := TryPush(h, testIncomparable(1.5))
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	t.Log(err)
+	if n := h.Len(); n != 3 {
+		t.Fatalf("Len(%d) != 3 after failed push", n)
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := TryPush(h, nil); !errors.Is(err, gocontainer.ErrNilItem) {
+		t.Fatalf("err(%v) is not ErrNilItem", err)
+	}
+
+	ih := NewMinHeap(0, true)
+	if err := TryPush(ih, testElement(1)); !errors.Is(err,
+		gocontainer.ErrNotIndexed) {
+		t.Fatalf("err(%v) is not ErrNotIndexed", err)
+	}
+	if err := ih.TrySet(0, gocontainer.NewIndexedComparableItem(
+		testElement(1))); !errors.Is(err, gocontainer.ErrOutOfRange) {
+		t.Fatalf("err(%v) is not ErrOutOfRange", err)
+	}
+}
+
+func TestTryUpdateTop(t *testing.T) {
+	h := NewMinHeap(0, false)
+	for _, x := range []testElement{3, 1, 2} {
+		stdheap.Push(h, x)
+	}
+	err := TryUpdateTop(h, testIncomparable(1.5))
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	if n := h.Len(); n != 3 {
+		t.Fatalf("Len(%d) != 3 after failed update", n)
+	}
+	if err := h.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if top := h.Top(); top != testElement(1) {
+		t.Errorf("Top(%v) != 1", top)
+	}
+	if err := TryUpdateTop(h, testElement(4)); err != nil {
+		t.Fatal(err)
+	}
+	if top := h.Top(); top != testElement(2) {
+		t.Errorf("Top(%v) != 2", top)
+	}
+}
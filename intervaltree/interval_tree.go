@@ -0,0 +1,185 @@
+// Package intervaltree provides an interval tree, for finding
+// the intervals overlapping a given interval or containing a given point.
+package intervaltree
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// An interval in the tree, with its value.
+// Don't modify its fields. Delete it and insert a new one instead.
+type Entry struct {
+	Lo, Hi gocontainer.Comparable // Endpoints of the closed interval.
+	Value  interface{}
+
+	seq uint64 // Insertion order, to tell apart equal intervals.
+}
+
+// IntervalTree is a set of closed intervals [Lo, Hi] with values.
+// Equal intervals can be inserted many times, as different entries.
+//
+// It is an augmented red-black tree. Insert and Delete take O(log(n)) time,
+// and the queries take O(k * log(n)) time for k results.
+type IntervalTree struct {
+	root *node
+	n    int
+	seq  uint64
+	lock *sync.RWMutex
+}
+
+func NewIntervalTree(isSync bool) *IntervalTree {
+	t := new(IntervalTree)
+	if isSync {
+		t.lock = new(sync.RWMutex)
+	}
+	return t
+}
+
+func (t *IntervalTree) Len() int {
+	if t == nil {
+		return 0
+	}
+	if t.lock != nil {
+		t.lock.RLock()
+		defer t.lock.RUnlock()
+	}
+	return t.n
+}
+
+// Insert the interval [lo, hi] with value, and return its entry,
+// which is used to delete it.
+// It panics if hi < lo or an endpoint is nil.
+func (t *IntervalTree) Insert(lo, hi gocontainer.Comparable,
+	value interface{}) *Entry {
+	e, err := t.TryInsert(lo, hi, value)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Same as Insert, but return an error instead of panic,
+// or gocontainer.ErrNilContainer if t is nil.
+func (t *IntervalTree) TryInsert(lo, hi gocontainer.Comparable,
+	value interface{}) (*Entry, error) {
+	if t == nil {
+		return nil, gocontainer.ErrNilContainer
+	}
+	if lo == nil || hi == nil || hi.Less(lo) {
+		return nil, fmt.Errorf("%w: [%v, %v]",
+			gocontainer.ErrInvalidInterval, lo, hi)
+	}
+	if t.lock != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+	}
+	t.seq++
+	e := &Entry{Lo: lo, Hi: hi, Value: value, seq: t.seq}
+	t.root = insert(t.root, e)
+	t.root.isRed = false
+	t.n++
+	return e, nil
+}
+
+// Delete the entry, and return true if it was in the tree.
+func (t *IntervalTree) Delete(e *Entry) bool {
+	if t == nil || e == nil {
+		return false
+	}
+	if t.lock != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+	}
+	if n := find(t.root, e); n == nil || n.e != e {
+		return false
+	}
+	if !isRed(t.root.left) && !isRed(t.root.right) {
+		t.root.isRed = true
+	}
+	t.root = deleteEntry(t.root, e)
+	if t.root != nil {
+		t.root.isRed = false
+	}
+	t.n--
+	return true
+}
+
+// Call f with the entries overlapping the closed interval [lo, hi],
+// in ascending order of (Lo, Hi), until f returns true.
+// It panics if hi < lo or an endpoint is nil.
+//
+// Don't modify the tree in f.
+func (t *IntervalTree) Overlap(lo, hi gocontainer.Comparable,
+	f func(e *Entry) (doesStop bool)) {
+	if lo == nil || hi == nil || hi.Less(lo) {
+		panic(fmt.Errorf("%w: [%v, %v]",
+			gocontainer.ErrInvalidInterval, lo, hi))
+	}
+	if t == nil {
+		return
+	}
+	if t.lock != nil {
+		t.lock.RLock()
+		defer t.lock.RUnlock()
+	}
+	overlap(t.root, lo, hi, f)
+}
+
+// Call f with the entries containing point, in ascending order of (Lo, Hi),
+// until f returns true.
+//
+// Don't modify the tree in f.
+func (t *IntervalTree) Stab(point gocontainer.Comparable,
+	f func(e *Entry) (doesStop bool)) {
+	t.Overlap(point, point, f)
+}
+
+// Return the entries overlapping [lo, hi], in ascending order of (Lo, Hi).
+func (t *IntervalTree) OverlapAll(lo, hi gocontainer.Comparable) []*Entry {
+	var es []*Entry
+	t.Overlap(lo, hi, func(e *Entry) bool {
+		es = append(es, e)
+		return false
+	})
+	return es
+}
+
+// Return true if any interval overlaps [lo, hi].
+func (t *IntervalTree) Overlaps(lo, hi gocontainer.Comparable) bool {
+	var found bool
+	t.Overlap(lo, hi, func(*Entry) bool {
+		found = true
+		return true
+	})
+	return found
+}
+
+// Call f with all the entries in ascending order of (Lo, Hi),
+// until f returns true.
+//
+// Don't modify the tree in f.
+func (t *IntervalTree) Scan(f func(e *Entry) (doesStop bool)) {
+	if t == nil {
+		return
+	}
+	if t.lock != nil {
+		t.lock.RLock()
+		defer t.lock.RUnlock()
+	}
+	walk(t.root, f)
+}
+
+func (t *IntervalTree) Clear() {
+	if t == nil {
+		return
+	}
+	if t.lock != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+	}
+	t.root = nil
+	t.n = 0
+}
@@ -0,0 +1,150 @@
+package intervaltree
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Check the red-black invariants and the augmented maxHi,
+// and return the black height of h.
+func checkNode(h *node) (int, error) {
+	if h == nil {
+		return 0, nil
+	}
+	if isRed(h.right) || isRed(h) && isRed(h.left) {
+		return 0, fmt.Errorf("red links at %v", h.e.Lo)
+	}
+	maxHi := h.e.Hi
+	for _, c := range []*node{h.left, h.right} {
+		if c != nil && maxHi.Less(c.maxHi) {
+			maxHi = c.maxHi
+		}
+	}
+	if maxHi != h.maxHi {
+		return 0, fmt.Errorf("maxHi of %v is %v, want %v", h.e.Lo, h.maxHi, maxHi)
+	}
+	if h.left != nil && !entryLess(h.left.e, h.e) ||
+		h.right != nil && !entryLess(h.e, h.right.e) {
+		return 0, fmt.Errorf("out of order at %v", h.e.Lo)
+	}
+	lbh, err := checkNode(h.left)
+	if err != nil {
+		return 0, err
+	}
+	rbh, err := checkNode(h.right)
+	if err != nil {
+		return 0, err
+	}
+	if lbh != rbh {
+		return 0, fmt.Errorf("unbalanced at %v", h.e.Lo)
+	}
+	if !isRed(h) {
+		lbh++
+	}
+	return lbh, nil
+}
+
+func TestIntervalTreeRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tree := NewIntervalTree(true)
+	var ref []*Entry
+	for i := 0; i < 3000; i++ {
+		if len(ref) > 0 && r.Intn(3) == 0 {
+			j := r.Intn(len(ref))
+			if !tree.Delete(ref[j]) {
+				t.Fatalf("Delete(%v) = false", ref[j])
+			}
+			if tree.Delete(ref[j]) {
+				t.Fatalf("Delete(%v) twice", ref[j])
+			}
+			ref[j] = ref[len(ref)-1]
+			ref = ref[:len(ref)-1]
+		} else {
+			lo := r.Intn(1000)
+			hi := lo + r.Intn(50)
+			ref = append(ref, tree.Insert(testElement(lo), testElement(hi), i))
+		}
+		if i%100 != 0 {
+			continue
+		}
+		if _, err := checkNode(tree.root); err != nil || isRed(tree.root) {
+			t.Fatalf("Invalid tree: %v", err)
+		}
+		lo := r.Intn(1000)
+		hi := lo + r.Intn(30)
+		want := make(map[*Entry]bool)
+		for _, e := range ref {
+			if int(e.Lo.(testElement)) <= hi && lo <= int(e.Hi.(testElement)) {
+				want[e] = true
+			}
+		}
+		got := tree.OverlapAll(testElement(lo), testElement(hi))
+		if len(got) != len(want) {
+			t.Fatalf("Overlap(%d, %d): %d entries, want %d",
+				lo, hi, len(got), len(want))
+		}
+		for j, e := range got {
+			if !want[e] || j > 0 && !entryLess(got[j-1], e) {
+				t.Fatalf("Overlap(%d, %d): wrong or unordered %v", lo, hi, e)
+			}
+		}
+		if tree.Overlaps(testElement(lo), testElement(hi)) != (len(want) > 0) {
+			t.Fatalf("Overlaps(%d, %d) is wrong", lo, hi)
+		}
+	}
+	if tree.Len() != len(ref) {
+		t.Errorf("Len: %d, want %d", tree.Len(), len(ref))
+	}
+}
+
+func TestIntervalTreeStab(t *testing.T) {
+	tree := NewIntervalTree(false)
+	tree.Insert(testElement(1), testElement(5), "a")
+	b := tree.Insert(testElement(3), testElement(3), "b")
+	tree.Insert(testElement(3), testElement(3), "b2")
+	tree.Insert(testElement(4), testElement(9), "c")
+	tree.Insert(testElement(6), testElement(7), "d")
+	stab := func(p int) string {
+		var s string
+		tree.Stab(testElement(p), func(e *Entry) bool {
+			s += e.Value.(string) + " "
+			return false
+		})
+		return s
+	}
+	for p, want := range map[int]string{0: "", 1: "a ", 3: "a b b2 ",
+		5: "a c ", 6: "c d ", 9: "c ", 10: ""} {
+		if got := stab(p); got != want {
+			t.Errorf("Stab(%d): %q, want %q", p, got, want)
+		}
+	}
+	tree.Delete(b)
+	if got := stab(3); got != "a b2 " {
+		t.Errorf("Stab(3) after Delete: %q", got)
+	}
+	var n int
+	tree.Scan(func(e *Entry) bool {
+		n++
+		return n == 2
+	})
+	if n != 2 || tree.Len() != 4 {
+		t.Errorf("Scan: %d, Len: %d", n, tree.Len())
+	}
+	_, err := tree.TryInsert(testElement(2), testElement(1), nil)
+	if !errors.Is(err, gocontainer.ErrInvalidInterval) {
+		t.Errorf("TryInsert: %v", err)
+	}
+	var nilTree *IntervalTree
+	_, err = nilTree.TryInsert(testElement(1), testElement(2), nil)
+	if !errors.Is(err, gocontainer.ErrNilContainer) {
+		t.Errorf("TryInsert on a nil tree: %v", err)
+	}
+	tree.Clear()
+	if tree.Len() != 0 || tree.Overlaps(testElement(0), testElement(10)) {
+		t.Error("Clear failed")
+	}
+}
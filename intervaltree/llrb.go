@@ -0,0 +1,198 @@
+package intervaltree
+
+import "github.com/donyori/gocontainer"
+
+// Left-leaning red-black tree ordered by (Lo, Hi, insertion order),
+// augmented with the greatest Hi in each subtree.
+
+type node struct {
+	e           *Entry
+	left, right *node
+	isRed       bool
+	maxHi       gocontainer.Comparable // The greatest Hi in the subtree.
+}
+
+func isRed(n *node) bool {
+	return n != nil && n.isRed
+}
+
+func (n *node) update() {
+	n.maxHi = n.e.Hi
+	if n.left != nil && n.maxHi.Less(n.left.maxHi) {
+		n.maxHi = n.left.maxHi
+	}
+	if n.right != nil && n.maxHi.Less(n.right.maxHi) {
+		n.maxHi = n.right.maxHi
+	}
+}
+
+func entryLess(a, b *Entry) bool {
+	if a.Lo.Less(b.Lo) {
+		return true
+	} else if b.Lo.Less(a.Lo) {
+		return false
+	}
+	if a.Hi.Less(b.Hi) {
+		return true
+	} else if b.Hi.Less(a.Hi) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func rotateLeft(h *node) *node {
+	x := h.right
+	h.right = x.left
+	x.left = h
+	x.isRed = h.isRed
+	h.isRed = true
+	h.update()
+	x.update()
+	return x
+}
+
+func rotateRight(h *node) *node {
+	x := h.left
+	h.left = x.right
+	x.right = h
+	x.isRed = h.isRed
+	h.isRed = true
+	h.update()
+	x.update()
+	return x
+}
+
+func flipColors(h *node) {
+	h.isRed = !h.isRed
+	h.left.isRed = !h.left.isRed
+	h.right.isRed = !h.right.isRed
+}
+
+// Restore the invariants of h on the way up.
+func fixUp(h *node) *node {
+	if isRed(h.right) && !isRed(h.left) {
+		h = rotateLeft(h)
+	}
+	if isRed(h.left) && isRed(h.left.left) {
+		h = rotateRight(h)
+	}
+	if isRed(h.left) && isRed(h.right) {
+		flipColors(h)
+	}
+	h.update()
+	return h
+}
+
+func moveRedLeft(h *node) *node {
+	flipColors(h)
+	if isRed(h.right.left) {
+		h.right = rotateRight(h.right)
+		h = rotateLeft(h)
+		flipColors(h)
+	}
+	return h
+}
+
+func moveRedRight(h *node) *node {
+	flipColors(h)
+	if isRed(h.left.left) {
+		h = rotateRight(h)
+		flipColors(h)
+	}
+	return h
+}
+
+func minNode(h *node) *node {
+	for h.left != nil {
+		h = h.left
+	}
+	return h
+}
+
+func deleteMin(h *node) *node {
+	if h.left == nil {
+		return nil
+	}
+	if !isRed(h.left) && !isRed(h.left.left) {
+		h = moveRedLeft(h)
+	}
+	h.left = deleteMin(h.left)
+	return fixUp(h)
+}
+
+func insert(h *node, e *Entry) *node {
+	if h == nil {
+		return &node{e: e, isRed: true, maxHi: e.Hi}
+	}
+	if entryLess(e, h.e) {
+		h.left = insert(h.left, e)
+	} else {
+		h.right = insert(h.right, e)
+	}
+	return fixUp(h)
+}
+
+// e must be in the subtree rooted at h.
+func deleteEntry(h *node, e *Entry) *node {
+	if entryLess(e, h.e) {
+		if !isRed(h.left) && !isRed(h.left.left) {
+			h = moveRedLeft(h)
+		}
+		h.left = deleteEntry(h.left, e)
+	} else {
+		if isRed(h.left) {
+			h = rotateRight(h)
+		}
+		if h.e == e && h.right == nil {
+			return nil
+		}
+		if !isRed(h.right) && !isRed(h.right.left) {
+			h = moveRedRight(h)
+		}
+		if h.e == e {
+			h.e = minNode(h.right).e
+			h.right = deleteMin(h.right)
+		} else {
+			h.right = deleteEntry(h.right, e)
+		}
+	}
+	return fixUp(h)
+}
+
+func find(h *node, e *Entry) *node {
+	for h != nil {
+		if entryLess(e, h.e) {
+			h = h.left
+		} else if entryLess(h.e, e) {
+			h = h.right
+		} else {
+			return h
+		}
+	}
+	return nil
+}
+
+// Call f with the entries overlapping [lo, hi] in the subtree rooted at h,
+// in ascending order. Return true if f asks to stop.
+func overlap(h *node, lo, hi gocontainer.Comparable,
+	f func(e *Entry) (doesStop bool)) bool {
+	// No interval in the subtree ends at or after lo.
+	if h == nil || h.maxHi.Less(lo) {
+		return false
+	}
+	if overlap(h.left, lo, hi, f) {
+		return true
+	}
+	// h and all the intervals on its right start after hi.
+	if hi.Less(h.e.Lo) {
+		return false
+	}
+	if !h.e.Hi.Less(lo) && f(h.e) {
+		return true
+	}
+	return overlap(h.right, lo, hi, f)
+}
+
+func walk(h *node, f func(e *Entry) (doesStop bool)) bool {
+	return h != nil && (walk(h.left, f) || f(h.e) || walk(h.right, f))
+}
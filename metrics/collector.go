@@ -0,0 +1,240 @@
+// Package metrics provides a gocontainer.Observer collecting metrics
+// of a container, which can be exported through expvar.
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+type Options struct {
+	// Upper bounds of the wait time histogram buckets,
+	// positive and in ascending order. Default: DefaultBuckets.
+	Buckets []time.Duration
+
+	// The clock. Default: time.Now.
+	Now func() time.Time
+}
+
+// Collector is a gocontainer.Observer collecting the metrics
+// of one container:
+//   - length and its high-water mark,
+//   - counters of each kind of event,
+//   - a histogram of the wait time, from enqueue to dequeue, of items,
+//   - the time spent waiting for the lock of the container.
+//
+// Set it to a container by SetObserver.
+// Use one Collector per container.
+//
+// The wait time is only measured for items usable as map keys,
+// such as pointers. For equal items, e.g. two equal integers,
+// the earliest enqueue time is used.
+//
+// It is safe for concurrent use.
+// It implements expvar.Var, and can be exported by Publish.
+type Collector struct {
+	now  func() time.Time
+	lock sync.Mutex
+
+	start   time.Time
+	length  int
+	maxLen  int
+	counts  [6]int64 // Indexed by gocontainer.EventKind.
+	wait    *Histogram
+	lockCnt int64
+	lockSum time.Duration
+	lockMax time.Duration
+
+	// Enqueue times of the items in the container.
+	since map[interface{}][]time.Time
+}
+
+// A snapshot of the metrics collected by a Collector.
+type Snapshot struct {
+	Start   time.Time     // The time the Collector is created or reset.
+	Elapsed time.Duration // The time since Start.
+
+	Len    int // The length of the container at the last event.
+	MaxLen int // The high-water mark of the length.
+
+	Enqueued int64
+	Dequeued int64
+	Updated  int64
+	Removed  int64
+	Evicted  int64
+	Rejected int64
+
+	Wait Histogram // Time from enqueue to dequeue.
+
+	LockWaitCount int64 // Number of measured lock acquisitions.
+	LockWaitSum   time.Duration
+	LockWaitMax   time.Duration
+}
+
+// Return the number of dequeued items per second since Start.
+func (s Snapshot) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Dequeued) / s.Elapsed.Seconds()
+}
+
+// Return the mean time spent waiting for the lock.
+func (s Snapshot) LockWaitMean() time.Duration {
+	if s.LockWaitCount == 0 {
+		return 0
+	}
+	return s.LockWaitSum / time.Duration(s.LockWaitCount)
+}
+
+func NewCollector(opts *Options) *Collector {
+	c, err := NewCollectorE(opts)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Same as NewCollector, but return an error instead of panic.
+func NewCollectorE(opts *Options) (*Collector, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	bounds := opts.Buckets
+	if bounds == nil {
+		bounds = DefaultBuckets
+	}
+	h, err := newHistogram(bounds)
+	if err != nil {
+		return nil, err
+	}
+	c := &Collector{
+		now:   opts.Now,
+		wait:  h,
+		since: make(map[interface{}][]time.Time),
+	}
+	if c.now == nil {
+		c.now = time.Now
+	}
+	c.start = c.now()
+	return c, nil
+}
+
+// Observe implements gocontainer.Observer.
+func (c *Collector) Observe(e gocontainer.Event) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e.Kind >= 0 && int(e.Kind) < len(c.counts) {
+		c.counts[e.Kind]++
+	}
+	c.length = e.Len
+	if e.Len > c.maxLen {
+		c.maxLen = e.Len
+	}
+	if e.LockWait > 0 {
+		c.lockCnt++
+		c.lockSum += e.LockWait
+		if e.LockWait > c.lockMax {
+			c.lockMax = e.LockWait
+		}
+	}
+	switch e.Kind {
+	case gocontainer.EventEnqueue:
+		if isKey(e.Item) {
+			c.since[e.Item] = append(c.since[e.Item], c.now())
+		}
+	case gocontainer.EventDequeue:
+		if t, ok := c.forget(e.Item); ok {
+			c.wait.add(c.now().Sub(t))
+		}
+	case gocontainer.EventRemove, gocontainer.EventEvict:
+		c.forget(e.Item)
+	case gocontainer.EventUpdate:
+		if e.Prev != nil {
+			if t, ok := c.forget(e.Prev); ok && isKey(e.Item) {
+				// Keep the enqueue time for the replacement.
+				c.since[e.Item] = append(c.since[e.Item], t)
+			}
+		}
+	}
+}
+
+// Return a snapshot of the metrics.
+func (c *Collector) Snapshot() Snapshot {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return Snapshot{
+		Start:         c.start,
+		Elapsed:       c.now().Sub(c.start),
+		Len:           c.length,
+		MaxLen:        c.maxLen,
+		Enqueued:      c.counts[gocontainer.EventEnqueue],
+		Dequeued:      c.counts[gocontainer.EventDequeue],
+		Updated:       c.counts[gocontainer.EventUpdate],
+		Removed:       c.counts[gocontainer.EventRemove],
+		Evicted:       c.counts[gocontainer.EventEvict],
+		Rejected:      c.counts[gocontainer.EventReject],
+		Wait:          c.wait.clone(),
+		LockWaitCount: c.lockCnt,
+		LockWaitSum:   c.lockSum,
+		LockWaitMax:   c.lockMax,
+	}
+}
+
+// Reset the counters, the high-water mark and the histograms,
+// and restart the clock of the throughput.
+// The current length and the enqueue times of the items
+// still in the container are kept.
+func (c *Collector) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.start = c.now()
+	c.maxLen = c.length
+	c.counts = [len(c.counts)]int64{}
+	c.wait.reset()
+	c.lockCnt, c.lockSum, c.lockMax = 0, 0, 0
+}
+
+// String implements expvar.Var. It returns the snapshot in JSON.
+func (c *Collector) String() string {
+	b, err := json.Marshal(c.Snapshot())
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
+
+// Export the collector through expvar with the specified name.
+// Like expvar.Publish, it panics if the name is already registered.
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, c)
+}
+
+// Remove the earliest enqueue time of x, and return it.
+// Call it with the lock held.
+func (c *Collector) forget(x gocontainer.Comparable) (t time.Time, ok bool) {
+	if !isKey(x) {
+		return
+	}
+	ts := c.since[x]
+	if len(ts) == 0 {
+		return
+	}
+	t, ok = ts[0], true
+	if len(ts) == 1 {
+		delete(c.since, x)
+	} else {
+		c.since[x] = ts[1:]
+	}
+	return
+}
+
+// Report whether x can be used as a map key.
+func isKey(x gocontainer.Comparable) bool {
+	return x != nil && reflect.TypeOf(x).Comparable()
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+type testElement int
+
+func (te *testElement) Less(another interface{}) bool {
+	return *te < *another.(*testElement)
+}
+
+func TestCollector(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := NewCollector(&Options{
+		Buckets: []time.Duration{time.Second, 10 * time.Second},
+		Now: func() time.Time {
+			return now
+		},
+	})
+	pq := pqueue.NewPriorityQueue(0, false, true)
+	pq.SetObserver(c)
+	xs := []testElement{5, 1, 3}
+	for i := range xs {
+		pq.Enqueue(&xs[i])
+		now = now.Add(time.Second)
+	}
+	// Now: +3s. Waits: 1 for 2s, 3 for 1s, 5 for 3s.
+	pq.Dequeue()
+	pq.Dequeue()
+	now = now.Add(20 * time.Second)
+	pq.Dequeue() // 5 for 23s.
+	pq.Enqueue(&xs[0])
+	pq.Clear()
+
+	s := c.Snapshot()
+	if s.Enqueued != 4 || s.Dequeued != 3 || s.Removed != 1 {
+		t.Errorf("Counters: %+v", s)
+	}
+	if s.Len != 0 || s.MaxLen != 3 {
+		t.Errorf("Len: %d, MaxLen: %d", s.Len, s.MaxLen)
+	}
+	if s.Wait.Count != 3 || s.Wait.Max != 23*time.Second {
+		t.Errorf("Wait: %+v", s.Wait)
+	}
+	wantCounts := []int64{1, 1, 1}
+	for i, n := range wantCounts {
+		if s.Wait.Counts[i] != n {
+			t.Errorf("Wait.Counts: %v, want %v", s.Wait.Counts, wantCounts)
+			break
+		}
+	}
+	if q := s.Wait.Quantile(0.5); q != 10*time.Second {
+		t.Errorf("Median(%v) != 10s", q)
+	}
+	if q := s.Wait.Quantile(0.3); q != time.Second {
+		t.Errorf("Quantile(0.3) = %v != 1s", q)
+	}
+	if q := s.Wait.Quantile(1); q != 23*time.Second {
+		t.Errorf("Quantile(1) = %v != 23s", q)
+	}
+	if m := s.Wait.Mean(); m != 26*time.Second/3 {
+		t.Errorf("Mean(%v) != 26s/3", m)
+	}
+	if tp := s.Throughput(); tp != 3.0/23 {
+		t.Errorf("Throughput(%v) != 3/23", tp)
+	}
+	if len(c.since) != 0 {
+		t.Errorf("%d items are still tracked", len(c.since))
+	}
+
+	var decoded Snapshot
+	if err := json.Unmarshal([]byte(c.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Enqueued != 4 {
+		t.Errorf("Decoded Enqueued(%d) != 4", decoded.Enqueued)
+	}
+
+	c.Reset()
+	if s = c.Snapshot(); s.Enqueued != 0 || s.Wait.Count != 0 {
+		t.Errorf("After Reset: %+v", s)
+	}
+}
+
+func TestCollectorLockWait(t *testing.T) {
+	c := NewCollector(nil)
+	c.Observe(gocontainer.Event{Kind: gocontainer.EventEnqueue, Len: 1,
+		LockWait: time.Millisecond})
+	c.Observe(gocontainer.Event{Kind: gocontainer.EventEnqueue, Len: 2,
+		LockWait: 3 * time.Millisecond})
+	s := c.Snapshot()
+	if s.LockWaitCount != 2 || s.LockWaitMax != 3*time.Millisecond ||
+		s.LockWaitMean() != 2*time.Millisecond {
+		t.Errorf("Lock wait: %+v", s)
+	}
+}
+
+func TestNewCollectorE(t *testing.T) {
+	_, err := NewCollectorE(&Options{
+		Buckets: []time.Duration{time.Second, time.Second},
+	})
+	if !errors.Is(err, gocontainer.ErrInvalidBuckets) {
+		t.Errorf("err(%v) is not ErrInvalidBuckets", err)
+	}
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Upper bounds of the buckets used if Options.Buckets is nil.
+var DefaultBuckets = []time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+// A histogram of durations.
+type Histogram struct {
+	// Upper bounds (inclusive) of the buckets, in ascending order.
+	Bounds []time.Duration
+
+	// Counts[i] is the number of durations in bucket i.
+	// The last one, Counts[len(Bounds)], counts the durations
+	// greater than all the bounds.
+	Counts []int64
+
+	Count int64         // Total number of durations.
+	Sum   time.Duration // Sum of the durations.
+	Max   time.Duration
+}
+
+func newHistogram(bounds []time.Duration) (*Histogram, error) {
+	for i, b := range bounds {
+		if b <= 0 || i > 0 && b <= bounds[i-1] {
+			return nil, fmt.Errorf("%w: %v",
+				gocontainer.ErrInvalidBuckets, bounds)
+		}
+	}
+	return &Histogram{
+		Bounds: append([]time.Duration(nil), bounds...),
+		Counts: make([]int64, len(bounds)+1),
+	}, nil
+}
+
+func (h *Histogram) add(d time.Duration) {
+	// The buckets are few, so a linear search is fast enough.
+	i := 0
+	for i < len(h.Bounds) && d > h.Bounds[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+	if d > h.Max {
+		h.Max = d
+	}
+}
+
+func (h *Histogram) reset() {
+	for i := range h.Counts {
+		h.Counts[i] = 0
+	}
+	h.Count, h.Sum, h.Max = 0, 0, 0
+}
+
+func (h *Histogram) clone() Histogram {
+	c := *h
+	c.Bounds = append([]time.Duration(nil), h.Bounds...)
+	c.Counts = append([]int64(nil), h.Counts...)
+	return c
+}
+
+// Return the mean of the durations, or 0 if the histogram is empty.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Return an upper bound of the q-quantile (0 <= q <= 1),
+// that is, the bound of the bucket holding it.
+// For the last bucket, it returns Max.
+// It returns 0 if the histogram is empty.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	if q < 0 {
+		q = 0
+	} else if q > 1 {
+		q = 1
+	}
+	rank := int64(math.Ceil(q * float64(h.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var n int64
+	for i, c := range h.Counts {
+		n += c
+		if n >= rank {
+			if i < len(h.Bounds) {
+				return h.Bounds[i]
+			}
+			break
+		}
+	}
+	return h.Max
+}
@@ -0,0 +1,57 @@
+package gocontainer
+
+import "time"
+
+// The kind of an Event.
+type EventKind int8
+
+const (
+	EventEnqueue EventKind = iota // An item is added.
+	EventDequeue                  // An item is taken out from the top.
+	EventUpdate                   // The priority of an item is changed.
+	EventRemove                   // An item is removed by the user.
+	EventEvict                    // An item is dropped by the container, e.g., expired.
+	EventReject                   // An item is not accepted by the container.
+)
+
+var eventKindNames = [...]string{
+	"enqueue", "dequeue", "update", "remove", "evict", "reject",
+}
+
+func (k EventKind) String() string {
+	if k >= 0 && int(k) < len(eventKindNames) {
+		return eventKindNames[k]
+	}
+	return "unknown"
+}
+
+// An event reported to an Observer.
+type Event struct {
+	Kind EventKind
+	Item Comparable
+
+	// For EventUpdate, the item replaced by Item, or nil if Item is updated
+	// in place.
+	Prev Comparable
+
+	// The length of the container after the operation.
+	Len int
+
+	// The time spent waiting for the lock of the container.
+	// It is only measured for enqueue, dequeue, update and remove
+	// on a container created with isSync = true, and is 0 otherwise.
+	LockWait time.Duration
+
+	// For EventReject, the reason, or nil if the item is simply not good
+	// enough, e.g., for a full top-k buffer.
+	Err error
+}
+
+// Observer receives the events of a container.
+//
+// Observe is called synchronously with the lock of the container held,
+// so it should return quickly, and must NOT call any method
+// of the container.
+type Observer interface {
+	Observe(e Event)
+}
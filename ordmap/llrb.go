@@ -0,0 +1,232 @@
+package ordmap
+
+// Left-leaning red-black tree, augmented with subtree sizes
+// for order statistics.
+
+type node struct {
+	key, value  interface{}
+	left, right *node
+	isRed       bool
+	size        int // Number of nodes in the subtree rooted at it.
+}
+
+func isRed(n *node) bool {
+	return n != nil && n.isRed
+}
+
+func size(n *node) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *node) updateSize() {
+	n.size = 1 + size(n.left) + size(n.right)
+}
+
+func rotateLeft(h *node) *node {
+	x := h.right
+	h.right = x.left
+	x.left = h
+	x.isRed = h.isRed
+	h.isRed = true
+	x.size = h.size
+	h.updateSize()
+	return x
+}
+
+func rotateRight(h *node) *node {
+	x := h.left
+	h.left = x.right
+	x.right = h
+	x.isRed = h.isRed
+	h.isRed = true
+	x.size = h.size
+	h.updateSize()
+	return x
+}
+
+func flipColors(h *node) {
+	h.isRed = !h.isRed
+	h.left.isRed = !h.left.isRed
+	h.right.isRed = !h.right.isRed
+}
+
+// Restore the invariants of h on the way up.
+func fixUp(h *node) *node {
+	if isRed(h.right) && !isRed(h.left) {
+		h = rotateLeft(h)
+	}
+	if isRed(h.left) && isRed(h.left.left) {
+		h = rotateRight(h)
+	}
+	if isRed(h.left) && isRed(h.right) {
+		flipColors(h)
+	}
+	h.updateSize()
+	return h
+}
+
+// Assuming h is red and both h.left and h.left.left are black,
+// make h.left or one of its children red.
+func moveRedLeft(h *node) *node {
+	flipColors(h)
+	if isRed(h.right.left) {
+		h.right = rotateRight(h.right)
+		h = rotateLeft(h)
+		flipColors(h)
+	}
+	return h
+}
+
+// Assuming h is red and both h.right and h.right.left are black,
+// make h.right or one of its children red.
+func moveRedRight(h *node) *node {
+	flipColors(h)
+	if isRed(h.left.left) {
+		h = rotateRight(h)
+		flipColors(h)
+	}
+	return h
+}
+
+func minNode(h *node) *node {
+	for h.left != nil {
+		h = h.left
+	}
+	return h
+}
+
+func maxNode(h *node) *node {
+	for h.right != nil {
+		h = h.right
+	}
+	return h
+}
+
+func deleteMin(h *node) *node {
+	if h.left == nil {
+		return nil
+	}
+	if !isRed(h.left) && !isRed(h.left.left) {
+		h = moveRedLeft(h)
+	}
+	h.left = deleteMin(h.left)
+	return fixUp(h)
+}
+
+func (m *OrderedMap) put(h *node, key, value interface{}) (
+	n *node, isAdded bool) {
+	if h == nil {
+		return &node{key: key, value: value, isRed: true, size: 1}, true
+	}
+	if m.less(key, h.key) {
+		h.left, isAdded = m.put(h.left, key, value)
+	} else if m.less(h.key, key) {
+		h.right, isAdded = m.put(h.right, key, value)
+	} else {
+		h.value = value
+	}
+	return fixUp(h), isAdded
+}
+
+// key must be in the subtree rooted at h.
+func (m *OrderedMap) delete(h *node, key interface{}) *node {
+	if m.less(key, h.key) {
+		if !isRed(h.left) && !isRed(h.left.left) {
+			h = moveRedLeft(h)
+		}
+		h.left = m.delete(h.left, key)
+	} else {
+		if isRed(h.left) {
+			h = rotateRight(h)
+		}
+		if !m.less(h.key, key) && h.right == nil {
+			return nil
+		}
+		if !isRed(h.right) && !isRed(h.right.left) {
+			h = moveRedRight(h)
+		}
+		if !m.less(h.key, key) {
+			min := minNode(h.right)
+			h.key, h.value = min.key, min.value
+			h.right = deleteMin(h.right)
+		} else {
+			h.right = m.delete(h.right, key)
+		}
+	}
+	return fixUp(h)
+}
+
+func (m *OrderedMap) find(key interface{}) *node {
+	h := m.root
+	for h != nil {
+		if m.less(key, h.key) {
+			h = h.left
+		} else if m.less(h.key, key) {
+			h = h.right
+		} else {
+			return h
+		}
+	}
+	return nil
+}
+
+// Return the node with the greatest key less than key,
+// or equal to key if isInclusive is true.
+func (m *OrderedMap) floor(key interface{}, isInclusive bool) *node {
+	var res *node
+	h := m.root
+	for h != nil {
+		if m.less(key, h.key) || !isInclusive && !m.less(h.key, key) {
+			h = h.left
+		} else {
+			res = h
+			h = h.right
+		}
+	}
+	return res
+}
+
+// Return the node with the least key greater than key,
+// or equal to key if isInclusive is true.
+func (m *OrderedMap) ceiling(key interface{}, isInclusive bool) *node {
+	var res *node
+	h := m.root
+	for h != nil {
+		if m.less(h.key, key) || !isInclusive && !m.less(key, h.key) {
+			h = h.right
+		} else {
+			res = h
+			h = h.left
+		}
+	}
+	return res
+}
+
+// Call f with the nodes whose keys are in [lo, hi) in ascending order,
+// or in descending order if isReverse is true.
+// nil lo or hi means unbounded.
+// Return true if f asks to stop.
+func (m *OrderedMap) walk(h *node, lo, hi interface{}, isReverse bool,
+	f func(key, value interface{}) (doesStop bool)) bool {
+	if h == nil {
+		return false
+	}
+	isAboveLo := lo == nil || !m.less(h.key, lo) // h.key >= lo
+	isBelowHi := hi == nil || m.less(h.key, hi)  // h.key < hi
+	first, second := h.left, h.right
+	goFirst, goSecond := isAboveLo, isBelowHi
+	if isReverse {
+		first, second = second, first
+		goFirst, goSecond = goSecond, goFirst
+	}
+	if goFirst && m.walk(first, lo, hi, isReverse, f) {
+		return true
+	}
+	if isAboveLo && isBelowHi && f(h.key, h.value) {
+		return true
+	}
+	return goSecond && m.walk(second, lo, hi, isReverse, f)
+}
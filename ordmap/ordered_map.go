@@ -0,0 +1,289 @@
+// Package ordmap provides an ordered map, implemented as a balanced
+// binary search tree, with range queries and order statistics.
+package ordmap
+
+import (
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// OrderedMap is a map whose keys are kept in ascending order.
+// It can also be used as a sorted set, with nil values.
+//
+// Two keys a and b are regarded as equal
+// if neither less(a, b) nor less(b, a).
+type OrderedMap struct {
+	root *node
+	less func(a, b interface{}) bool
+	lock *sync.RWMutex
+}
+
+// Create an OrderedMap whose keys are gocontainer.Comparable,
+// ordered by their Less method.
+func NewOrderedMap(isSync bool) *OrderedMap {
+	return NewOrderedMapFunc(comparableLess, isSync)
+}
+
+// Create an OrderedMap whose keys are ordered by less.
+// It panics with gocontainer.ErrNilLess if less is nil.
+func NewOrderedMapFunc(less func(a, b interface{}) bool,
+	isSync bool) *OrderedMap {
+	if less == nil {
+		panic(gocontainer.ErrNilLess)
+	}
+	m := &OrderedMap{less: less}
+	if isSync {
+		m.lock = new(sync.RWMutex)
+	}
+	return m
+}
+
+func comparableLess(a, b interface{}) bool {
+	return a.(gocontainer.Comparable).Less(b)
+}
+
+func (m *OrderedMap) Len() int {
+	if m == nil {
+		return 0
+	}
+	if m.lock != nil {
+		m.lock.RLock()
+		defer m.lock.RUnlock()
+	}
+	return size(m.root)
+}
+
+func (m *OrderedMap) Get(key interface{}) (value interface{}, ok bool) {
+	if m == nil {
+		return
+	}
+	if m.lock != nil {
+		m.lock.RLock()
+		defer m.lock.RUnlock()
+	}
+	if n := m.find(key); n != nil {
+		return n.value, true
+	}
+	return
+}
+
+func (m *OrderedMap) Contains(key interface{}) bool {
+	_, ok := m.Get(key)
+	return ok
+}
+
+// Set the value of key, and return true if key is new.
+func (m *OrderedMap) Put(key, value interface{}) (isAdded bool) {
+	if m.lock != nil {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+	}
+	m.root, isAdded = m.put(m.root, key, value)
+	m.root.isRed = false
+	return
+}
+
+// Delete key, and return its value. ok is false if key is not in the map.
+func (m *OrderedMap) Delete(key interface{}) (value interface{}, ok bool) {
+	if m == nil {
+		return
+	}
+	if m.lock != nil {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+	}
+	return m.deleteKey(key)
+}
+
+// Call it with the lock held.
+func (m *OrderedMap) deleteKey(key interface{}) (value interface{}, ok bool) {
+	n := m.find(key)
+	if n == nil {
+		return
+	}
+	value = n.value
+	if !isRed(m.root.left) && !isRed(m.root.right) {
+		m.root.isRed = true
+	}
+	m.root = m.delete(m.root, key)
+	if m.root != nil {
+		m.root.isRed = false
+	}
+	return value, true
+}
+
+// Return the greatest key less than or equal to key, and its value.
+func (m *OrderedMap) Floor(key interface{}) (k, v interface{}, ok bool) {
+	return m.search(key, true, true)
+}
+
+// Return the least key greater than or equal to key, and its value.
+func (m *OrderedMap) Ceiling(key interface{}) (k, v interface{}, ok bool) {
+	return m.search(key, false, true)
+}
+
+// Return the greatest key less than key (the predecessor), and its value.
+func (m *OrderedMap) Lower(key interface{}) (k, v interface{}, ok bool) {
+	return m.search(key, true, false)
+}
+
+// Return the least key greater than key (the successor), and its value.
+func (m *OrderedMap) Higher(key interface{}) (k, v interface{}, ok bool) {
+	return m.search(key, false, false)
+}
+
+func (m *OrderedMap) search(key interface{}, isFloor, isInclusive bool) (
+	k, v interface{}, ok bool) {
+	if m == nil {
+		return
+	}
+	if m.lock != nil {
+		m.lock.RLock()
+		defer m.lock.RUnlock()
+	}
+	var n *node
+	if isFloor {
+		n = m.floor(key, isInclusive)
+	} else {
+		n = m.ceiling(key, isInclusive)
+	}
+	if n == nil {
+		return
+	}
+	return n.key, n.value, true
+}
+
+// Return the least key and its value.
+func (m *OrderedMap) Min() (k, v interface{}, ok bool) {
+	return m.end(false, false)
+}
+
+// Return the greatest key and its value.
+func (m *OrderedMap) Max() (k, v interface{}, ok bool) {
+	return m.end(true, false)
+}
+
+// Delete the least key, and return it and its value.
+func (m *OrderedMap) PopMin() (k, v interface{}, ok bool) {
+	return m.end(false, true)
+}
+
+// Delete the greatest key, and return it and its value.
+func (m *OrderedMap) PopMax() (k, v interface{}, ok bool) {
+	return m.end(true, true)
+}
+
+func (m *OrderedMap) end(isMax, doesDelete bool) (k, v interface{},
+	ok bool) {
+	if m == nil {
+		return
+	}
+	if m.lock != nil {
+		if doesDelete {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+		} else {
+			m.lock.RLock()
+			defer m.lock.RUnlock()
+		}
+	}
+	if m.root == nil {
+		return
+	}
+	var n *node
+	if isMax {
+		n = maxNode(m.root)
+	} else {
+		n = minNode(m.root)
+	}
+	k, v = n.key, n.value
+	if doesDelete {
+		m.deleteKey(k)
+	}
+	return k, v, true
+}
+
+// Call f with the keys in [lo, hi) and their values, in ascending order,
+// until f returns true. nil lo or hi means unbounded.
+// f must NOT modify the map.
+func (m *OrderedMap) Range(lo, hi interface{},
+	f func(key, value interface{}) (doesStop bool)) {
+	m.rangeImpl(lo, hi, false, f)
+}
+
+// Same as Range, but in descending order.
+func (m *OrderedMap) ReverseRange(lo, hi interface{},
+	f func(key, value interface{}) (doesStop bool)) {
+	m.rangeImpl(lo, hi, true, f)
+}
+
+func (m *OrderedMap) rangeImpl(lo, hi interface{}, isReverse bool,
+	f func(key, value interface{}) (doesStop bool)) {
+	if m == nil || f == nil {
+		return
+	}
+	if m.lock != nil {
+		m.lock.RLock()
+		defer m.lock.RUnlock()
+	}
+	m.walk(m.root, lo, hi, isReverse, f)
+}
+
+// Return the number of keys less than key.
+// O(log(n)) time.
+func (m *OrderedMap) Rank(key interface{}) int {
+	if m == nil {
+		return 0
+	}
+	if m.lock != nil {
+		m.lock.RLock()
+		defer m.lock.RUnlock()
+	}
+	r := 0
+	h := m.root
+	for h != nil {
+		if m.less(h.key, key) {
+			r += size(h.left) + 1
+			h = h.right
+		} else {
+			h = h.left
+		}
+	}
+	return r
+}
+
+// Return the key of rank i, i.e., the (i+1)-th least key, and its value.
+// ok is false if i is out of [0, m.Len()).
+// O(log(n)) time.
+func (m *OrderedMap) Select(i int) (k, v interface{}, ok bool) {
+	if m == nil {
+		return
+	}
+	if m.lock != nil {
+		m.lock.RLock()
+		defer m.lock.RUnlock()
+	}
+	if i < 0 || i >= size(m.root) {
+		return
+	}
+	h := m.root
+	for {
+		if ls := size(h.left); i < ls {
+			h = h.left
+		} else if i > ls {
+			i -= ls + 1
+			h = h.right
+		} else {
+			return h.key, h.value, true
+		}
+	}
+}
+
+func (m *OrderedMap) Clear() {
+	if m.lock != nil {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+	}
+	m.root = nil
+}
@@ -0,0 +1,225 @@
+package ordmap
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Check the invariants of the left-leaning red-black tree.
+func checkTree(m *OrderedMap) error {
+	if isRed(m.root) {
+		return fmt.Errorf("root is red")
+	}
+	_, err := checkNode(m, m.root, nil, nil)
+	return err
+}
+
+// Return the black height of h.
+func checkNode(m *OrderedMap, h *node, lo, hi interface{}) (int, error) {
+	if h == nil {
+		return 0, nil
+	}
+	if lo != nil && !m.less(lo, h.key) || hi != nil && !m.less(h.key, hi) {
+		return 0, fmt.Errorf("key %v is out of order", h.key)
+	}
+	if isRed(h.right) {
+		return 0, fmt.Errorf("right link of %v is red", h.key)
+	}
+	if isRed(h) && isRed(h.left) {
+		return 0, fmt.Errorf("two red links in a row at %v", h.key)
+	}
+	if h.size != 1+size(h.left)+size(h.right) {
+		return 0, fmt.Errorf("size of %v is wrong", h.key)
+	}
+	lbh, err := checkNode(m, h.left, lo, h.key)
+	if err != nil {
+		return 0, err
+	}
+	rbh, err := checkNode(m, h.right, h.key, hi)
+	if err != nil {
+		return 0, err
+	}
+	if lbh != rbh {
+		return 0, fmt.Errorf("unbalanced at %v", h.key)
+	}
+	if !isRed(h) {
+		lbh++
+	}
+	return lbh, nil
+}
+
+func TestOrderedMapRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	m := NewOrderedMap(true)
+	ref := make(map[testElement]int)
+	for i := 0; i < 5000; i++ {
+		k := testElement(r.Intn(500))
+		if r.Intn(3) == 0 {
+			v, ok := m.Delete(k)
+			rv, rok := ref[k]
+			if ok != rok || ok && v != rv {
+				t.Fatalf("Delete(%d) = %v, %t, want %v, %t", k, v, ok, rv, rok)
+			}
+			delete(ref, k)
+		} else {
+			_, exists := ref[k]
+			if isAdded := m.Put(k, i); isAdded == exists {
+				t.Fatalf("Put(%d) = %t", k, isAdded)
+			}
+			ref[k] = i
+		}
+		if i%100 == 0 {
+			if err := checkTree(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := checkTree(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != len(ref) {
+		t.Fatalf("Len(%d) != %d", m.Len(), len(ref))
+	}
+	keys := make([]int, 0, len(ref))
+	for k := range ref {
+		keys = append(keys, int(k))
+	}
+	sort.Ints(keys)
+	for i, k := range keys {
+		if v, ok := m.Get(testElement(k)); !ok || v != ref[testElement(k)] {
+			t.Fatalf("Get(%d) = %v, %t", k, v, ok)
+		}
+		if r := m.Rank(testElement(k)); r != i {
+			t.Fatalf("Rank(%d) = %d != %d", k, r, i)
+		}
+		if sk, _, ok := m.Select(i); !ok || sk != testElement(k) {
+			t.Fatalf("Select(%d) = %v, %t, want %d", i, sk, ok, k)
+		}
+	}
+	for len(keys) > 0 {
+		k, _, ok := m.PopMin()
+		if !ok || k != testElement(keys[0]) {
+			t.Fatalf("PopMin() = %v, %t, want %d", k, ok, keys[0])
+		}
+		keys = keys[1:]
+		if len(keys) > 0 {
+			k, _, ok = m.PopMax()
+			if !ok || k != testElement(keys[len(keys)-1]) {
+				t.Fatalf("PopMax() = %v, %t", k, ok)
+			}
+			keys = keys[:len(keys)-1]
+		}
+		if err := checkTree(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, ok := m.Min(); ok || m.Len() != 0 {
+		t.Fatal("The map is not empty.")
+	}
+}
+
+func TestOrderedMapQueries(t *testing.T) {
+	m := NewOrderedMapFunc(func(a, b interface{}) bool {
+		return a.(string) < b.(string)
+	}, false)
+	for _, k := range []string{"d", "b", "f", "h", "a"} {
+		m.Put(k, len(k))
+	}
+	search := []struct {
+		name string
+		f    func(key interface{}) (k, v interface{}, ok bool)
+		key  string
+		want interface{}
+	}{
+		{"Floor", m.Floor, "c", "b"},
+		{"Floor", m.Floor, "d", "d"},
+		{"Floor", m.Floor, "0", nil},
+		{"Ceiling", m.Ceiling, "c", "d"},
+		{"Ceiling", m.Ceiling, "h", "h"},
+		{"Ceiling", m.Ceiling, "i", nil},
+		{"Lower", m.Lower, "d", "b"},
+		{"Lower", m.Lower, "a", nil},
+		{"Higher", m.Higher, "d", "f"},
+		{"Higher", m.Higher, "h", nil},
+	}
+	for _, s := range search {
+		k, _, ok := s.f(s.key)
+		if ok != (s.want != nil) || ok && k != s.want {
+			t.Errorf("%s(%q) = %v, %t, want %v", s.name, s.key, k, ok, s.want)
+		}
+	}
+	if k, _, _ := m.Min(); k != "a" {
+		t.Errorf("Min() = %v", k)
+	}
+	if k, _, _ := m.Max(); k != "h" {
+		t.Errorf("Max() = %v", k)
+	}
+
+	collect := func(isReverse bool, lo, hi interface{}, limit int) string {
+		var s string
+		f := func(key, value interface{}) bool {
+			s += key.(string)
+			return len(s) >= limit
+		}
+		if isReverse {
+			m.ReverseRange(lo, hi, f)
+		} else {
+			m.Range(lo, hi, f)
+		}
+		return s
+	}
+	ranges := []struct {
+		isReverse bool
+		lo, hi    interface{}
+		limit     int
+		want      string
+	}{
+		{false, nil, nil, 10, "abdfh"},
+		{true, nil, nil, 10, "hfdba"},
+		{false, "b", "f", 10, "bd"},
+		{true, "b", "f", 10, "db"},
+		{false, "c", nil, 2, "df"},
+		{true, nil, "g", 2, "fd"},
+		{false, "e", "e", 10, ""},
+	}
+	for _, r := range ranges {
+		if s := collect(r.isReverse, r.lo, r.hi, r.limit); s != r.want {
+			t.Errorf("Range(reverse: %t, %v, %v) = %q, want %q",
+				r.isReverse, r.lo, r.hi, s, r.want)
+		}
+	}
+	if r := m.Rank("e"); r != 3 {
+		t.Errorf("Rank(e) = %d != 3", r)
+	}
+	if _, _, ok := m.Select(5); ok {
+		t.Error("Select(5) is ok")
+	}
+}
+
+func TestNewOrderedMapFuncNil(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, gocontainer.ErrNilLess) {
+			t.Errorf("NewOrderedMapFunc(nil) panics with %v", err)
+		}
+	}()
+	NewOrderedMapFunc(nil, false)
+}
+
+func BenchmarkOrderedMapPut(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	keys := make([]testElement, 1<<16)
+	for i := range keys {
+		keys[i] = testElement(r.Int())
+	}
+	m := NewOrderedMap(false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Put(keys[i&(len(keys)-1)], i)
+	}
+}
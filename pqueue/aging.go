@@ -0,0 +1,195 @@
+package pqueue
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Options of priority aging for HandlePriorityQueue and PriorityQueueEx,
+// with which the effective priority of a handle improves
+// with the time it waits in the queue, to prevent starvation.
+type Aging struct {
+	// Linear aging, used if Func is nil:
+	// the effective priority improves by Rate per second.
+	// The priorities must be Float64Priority or Int64Priority.
+	// The queue keeps a fixed sort key for each item,
+	// so aging costs nothing when time passes.
+	Rate float64
+
+	// Custom aging: return the effective priority of an item with
+	// priority p, which has waited for the duration waited.
+	// The queue recomputes the effective priorities and re-heapifies
+	// in a batch, at most once per Interval, when Top, Dequeue, PeekN
+	// or Kth is called, or, on PriorityQueueEx, DequeueIf, DequeueWhile,
+	// DequeueWhileN, DequeueBatch or their Wait variants.
+	// RefreshAging does it at any time.
+	Func func(p gocontainer.Comparable,
+		waited time.Duration) gocontainer.Comparable
+
+	// Interval of refreshing for Func. Default: 1 second.
+	Interval time.Duration
+
+	// The clock. Default: time.Now.
+	Now func() time.Time
+}
+
+type agingState struct {
+	Aging
+	isTopMax    bool
+	epoch       time.Time // Origin of the linear sort keys.
+	lastRefresh time.Time
+}
+
+func newAgingState(aging *Aging, isTopMax bool) *agingState {
+	as := &agingState{Aging: *aging, isTopMax: isTopMax}
+	if as.Now == nil {
+		as.Now = time.Now
+	}
+	if as.Interval <= 0 {
+		as.Interval = time.Second
+	}
+	as.epoch = as.Now()
+	as.lastRefresh = as.epoch
+	return as
+}
+
+// Return the sort key at now of an item with priority p,
+// which was enqueued at enqueuedAt.
+func (as *agingState) key(p gocontainer.Comparable, enqueuedAt,
+	now time.Time) (gocontainer.Comparable, error) {
+	if as.Func != nil {
+		return as.Func(p, now.Sub(enqueuedAt)), nil
+	}
+	var base float64
+	switch v := p.(type) {
+	case Float64Priority:
+		base = float64(v)
+	case Int64Priority:
+		base = float64(v)
+	default:
+		return nil, fmt.Errorf("%w: linear aging needs Float64Priority "+
+			"or Int64Priority, got %T", gocontainer.ErrIncomparable, p)
+	}
+	// Effective priority = base -/+ Rate * (now - enqueuedAt).
+	// Since Rate * now is the same for all items,
+	// the order is kept by the fixed key: base +/- Rate * enqueuedAt.
+	t := enqueuedAt.Sub(as.epoch).Seconds()
+	if as.isTopMax {
+		return Float64Priority(base - as.Rate*t), nil
+	}
+	return Float64Priority(base + as.Rate*t), nil
+}
+
+// Implementation of basePriorityQueue.setKey for HandlePriorityQueue.
+func (pq *HandlePriorityQueue) setHandleKey(x gocontainer.Comparable,
+	isEnqueue bool) (err error) {
+	h := x.(*Handle)
+	if pq.aging == nil {
+		h.key = nil // The handle may come from a queue with aging.
+		return nil
+	}
+	now := pq.aging.Now()
+	if isEnqueue {
+		h.enqueuedAt = now
+	}
+	h.key, err = pq.aging.key(h.item.Get(), h.enqueuedAt, now)
+	return
+}
+
+// Sort key of an item of PriorityQueueEx with aging.
+type agingKey struct {
+	key        gocontainer.Comparable
+	enqueuedAt time.Time
+}
+
+// Enable aging on a new PriorityQueueEx.
+// The heap orders the items by the keys in pq.agingKeys.
+func (pq *PriorityQueueEx) enableAging(aging *Aging, isTopMax bool) {
+	pq.aging = newAgingState(aging, isTopMax)
+	pq.agingKeys = make(map[*gocontainer.IndexedComparableItem]*agingKey)
+	pq.setKey = pq.setItemKey
+	pq.h.SetKey(func(x gocontainer.Comparable) gocontainer.Comparable {
+		if k := pq.agingKeys[x.(*gocontainer.IndexedComparableItem)]; k != nil {
+			return k.key
+		}
+		return x
+	})
+}
+
+// Implementation of basePriorityQueue.setKey for PriorityQueueEx.
+func (pq *PriorityQueueEx) setItemKey(x gocontainer.Comparable,
+	isEnqueue bool) error {
+	ici := x.(*gocontainer.IndexedComparableItem)
+	now := pq.aging.Now()
+	k := pq.agingKeys[ici]
+	if isEnqueue || k == nil {
+		k = &agingKey{enqueuedAt: now}
+	}
+	key, err := pq.aging.key(ici.Get(), k.enqueuedAt, now)
+	if err != nil {
+		if isEnqueue {
+			delete(pq.agingKeys, ici)
+		}
+		return err
+	}
+	k.key = key
+	pq.agingKeys[ici] = k
+	return nil
+}
+
+// Recompute the effective priorities of all the items
+// and re-heapify the queue, if the queue uses Aging.Func.
+// It is done automatically at most once per Aging.Interval
+// (see Aging.Func), so it's unnecessary to call it in most cases.
+func (pq *PriorityQueueEx) RefreshAging() {
+	if pq == nil {
+		return
+	}
+	pq.refreshAging(pq.aging, true)
+}
+
+func (pq *PriorityQueueEx) refreshIfDue() {
+	pq.refreshAging(pq.aging, false)
+}
+
+// Recompute the effective priorities of all the handles
+// and re-heapify the queue, if the queue uses Aging.Func.
+// It is done automatically at most once per Aging.Interval
+// (see Aging.Func), so it's unnecessary to call it in most cases.
+func (pq *HandlePriorityQueue) RefreshAging() {
+	if pq == nil {
+		return
+	}
+	pq.refreshAging(pq.aging, true)
+}
+
+func (pq *HandlePriorityQueue) refreshIfDue() {
+	pq.refreshAging(pq.aging, false)
+}
+
+// Recompute the sort keys by setKey and re-heapify the queue,
+// if as uses Aging.Func, and force is true or Aging.Interval has passed
+// since the last refresh.
+func (pq *basePriorityQueue) refreshAging(as *agingState, force bool) {
+	if as == nil || as.Func == nil {
+		return
+	}
+	if pq.lock != nil {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+	}
+	now := as.Now()
+	if !force && now.Sub(as.lastRefresh) < as.Interval {
+		return
+	}
+	pq.h.Scan(func(x gocontainer.Comparable) bool {
+		pq.setKey(x, false) // Func never returns an error.
+		return false
+	})
+	heap.Init(pq.h)
+	as.lastRefresh = now
+	pq.mutated()
+}
@@ -0,0 +1,241 @@
+package pqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestLinearAging(t *testing.T) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	pq := NewHandlePriorityQueueWithAging(0, true, true, &Aging{
+		Rate: 1,
+		Now:  clock.Now,
+	})
+	pq.SetDebug(true)
+	low := pq.EnqueueValue("low", Int64Priority(1))
+	clock.Advance(5 * time.Second)
+	high := pq.EnqueueValue("high", Float64Priority(4))
+	// Effective priorities: low = 1 + 5 = 6, high = 4 + 0.
+	if h := pq.Top(); h != low {
+		t.Fatalf("Top(%v) != %v", h, low)
+	}
+	if !pq.UpdatePriority(high, Float64Priority(7)) {
+		t.Fatal("UpdatePriority failed!")
+	}
+	if h := pq.Top(); h != high {
+		t.Fatalf("Top(%v) != %v", h, high)
+	}
+	// Updating priority keeps the waiting time.
+	if !pq.UpdatePriority(low, Float64Priority(3)) {
+		t.Fatal("UpdatePriority failed!")
+	}
+	if h := pq.Top(); h != low {
+		t.Fatalf("Top(%v) != %v", h, low)
+	}
+	err := pq.TryEnqueue(NewHandle("wrong", &testPriority{}))
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	if n := pq.Len(); n != 2 {
+		t.Fatalf("Len(%d) != 2", n)
+	}
+
+	// Aging keys are dropped when a handle moves to a queue without aging.
+	pq.Remove(low)
+	pq.Remove(high)
+	pq2 := NewHandlePriorityQueue(0, true, false)
+	pq2.Enqueue(low)
+	pq2.Enqueue(high)
+	if h := pq2.Top(); h != high {
+		t.Fatalf("Top(%v) != %v", h, high)
+	}
+}
+
+type testPriority struct{}
+
+func (tp *testPriority) Less(another interface{}) bool {
+	return false
+}
+
+func TestFuncAging(t *testing.T) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	// Min first. The effective priority halves every 10 seconds of waiting.
+	pq := NewHandlePriorityQueueWithAging(0, false, false, &Aging{
+		Func: func(p gocontainer.Comparable,
+			waited time.Duration) gocontainer.Comparable {
+			v := float64(p.(Float64Priority))
+			for ; waited >= 10*time.Second; waited -= 10 * time.Second {
+				v /= 2
+			}
+			return Float64Priority(v)
+		},
+		Interval: 10 * time.Second,
+		Now:      clock.Now,
+	})
+	pq.SetDebug(true)
+	old := pq.EnqueueValue("old", Float64Priority(100))
+	clock.Advance(25 * time.Second)
+	fresh := pq.EnqueueValue("fresh", Float64Priority(30))
+	// Refreshed: old = 25, fresh = 30.
+	if h := pq.Top(); h != old {
+		t.Fatalf("Top(%v) != %v", h, old)
+	}
+	clock.Advance(5 * time.Second)
+	// Not refreshed within Interval.
+	if h := pq.Top(); h != old {
+		t.Fatalf("Top(%v) != %v", h, old)
+	}
+	pq.UpdatePriority(old, Float64Priority(160))
+	// old = 160 / 8 = 20 after 30 seconds.
+	clock.Advance(10 * time.Second)
+	// Refreshed: old = 160 / 16 = 10, fresh = 30 / 2 = 15.
+	h, ok := pq.Dequeue()
+	if !ok || h != old {
+		t.Fatalf("Dequeue(%v, %t), want %v", h, ok, old)
+	}
+	pq.RefreshAging()
+	if h := pq.Top(); h != fresh {
+		t.Fatalf("Top(%v) != %v", h, fresh)
+	}
+}
+
+func TestPriorityQueueExAging(t *testing.T) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	pq := NewPriorityQueueExWithAging(0, true, true, &Aging{
+		Rate: 1,
+		Now:  clock.Now,
+	})
+	pq.SetDebug(true)
+	low := gocontainer.NewIndexedComparableItem(Int64Priority(1))
+	pq.Enqueue(low)
+	clock.Advance(5 * time.Second)
+	high := gocontainer.NewIndexedComparableItem(Float64Priority(4))
+	pq.Enqueue(high)
+	// Effective priorities: low = 1 + 5 = 6, high = 4 + 0.
+	if x := pq.Top(); x != low {
+		t.Fatalf("Top(%v) != %v", x, low)
+	}
+	if !pq.Update(high, Float64Priority(7)) {
+		t.Fatal("Update failed!")
+	}
+	if x := pq.Top(); x != high {
+		t.Fatalf("Top(%v) != %v", x, high)
+	}
+	// UpdateAll keeps the waiting time, and ignores a wrong priority.
+	pq.UpdateAll(func(
+		ici *gocontainer.IndexedComparableItem) gocontainer.Comparable {
+		if ici == low {
+			return Float64Priority(3)
+		}
+		return &testPriority{}
+	})
+	if x := pq.Top(); x != low || high.Get() != Float64Priority(7) {
+		t.Fatalf("Top(%v) != %v", x, low)
+	}
+	err := pq.TryEnqueue(gocontainer.NewIndexedComparableItem(&testPriority{}))
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	if n := len(pq.agingKeys); n != 2 {
+		t.Fatalf("%d aging keys, want 2", n)
+	}
+	if x, ok := pq.Dequeue(); !ok || x != low {
+		t.Fatalf("Dequeue(%v, %t), want %v", x, ok, low)
+	}
+	if _, ok := pq.agingKeys[low]; ok {
+		t.Fatal("Aging key is not dropped by Dequeue.")
+	}
+
+	// Custom aging, min first.
+	// The effective priority halves every 10 seconds of waiting.
+	pq = NewPriorityQueueExWithAging(0, false, false, &Aging{
+		Func: func(p gocontainer.Comparable,
+			waited time.Duration) gocontainer.Comparable {
+			v := float64(p.(Float64Priority))
+			for ; waited >= 10*time.Second; waited -= 10 * time.Second {
+				v /= 2
+			}
+			return Float64Priority(v)
+		},
+		Interval: 10 * time.Second,
+		Now:      clock.Now,
+	})
+	pq.SetDebug(true)
+	old := gocontainer.NewIndexedComparableItem(Float64Priority(100))
+	pq.Enqueue(old)
+	clock.Advance(25 * time.Second)
+	fresh := gocontainer.NewIndexedComparableItem(Float64Priority(30))
+	pq.Enqueue(fresh)
+	// Refreshed: old = 25, fresh = 30.
+	if xs := pq.PeekN(2); len(xs) != 2 || xs[0] != old || xs[1] != fresh {
+		t.Fatalf("PeekN: %v, want [%v %v]", xs, old, fresh)
+	}
+	pq.Update(old, Float64Priority(200))
+	// old = 200 / 4 = 50 after 25 seconds.
+	if x := pq.Top(); x != fresh {
+		t.Fatalf("Top(%v) != %v", x, fresh)
+	}
+	clock.Advance(10 * time.Second)
+	pq.RefreshAging()
+	// old = 200 / 8 = 25, fresh = 30 / 2 = 15.
+	if x := pq.Top(); x != fresh {
+		t.Fatalf("Top(%v) != %v", x, fresh)
+	}
+	clock.Advance(30 * time.Second)
+	// Refreshed by Dequeue: old = 200 / 64 = 3.125, fresh = 30 / 16 = 1.875.
+	if x, ok := pq.Dequeue(); !ok || x != fresh {
+		t.Fatalf("Dequeue(%v, %t), want %v", x, ok, fresh)
+	}
+}
+
+func TestPriorityQueueExAgingConditionalDequeue(t *testing.T) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	// Min first. The effective priority halves every 10 seconds of waiting.
+	pq := NewPriorityQueueExWithAging(0, false, true, &Aging{
+		Func: func(p gocontainer.Comparable,
+			waited time.Duration) gocontainer.Comparable {
+			v := float64(p.(Float64Priority))
+			for ; waited >= 10*time.Second; waited -= 10 * time.Second {
+				v /= 2
+			}
+			return Float64Priority(v)
+		},
+		Interval: 10 * time.Second,
+		Now:      clock.Now,
+	})
+	pq.SetDebug(true)
+	old := gocontainer.NewIndexedComparableItem(Float64Priority(100))
+	pq.Enqueue(old)
+	clock.Advance(25 * time.Second)
+	fresh := gocontainer.NewIndexedComparableItem(Float64Priority(30))
+	pq.Enqueue(fresh)
+	// Stale keys: old = 100, fresh = 30. Refreshed: old = 25, fresh = 30.
+	if x, ok := pq.DequeueIf(nil); !ok || x != old {
+		t.Fatalf("DequeueIf(%v, %t), want %v", x, ok, old)
+	}
+	clock.Advance(10 * time.Second)
+	late := gocontainer.NewIndexedComparableItem(Float64Priority(25))
+	pq.Enqueue(late)
+	clock.Advance(10 * time.Second)
+	// Stale keys: fresh = 30, late = 25. Refreshed: fresh = 7.5, late = 12.5.
+	x, err := pq.DequeueIfWait(context.Background(), nil)
+	if err != nil || x != fresh {
+		t.Fatalf("DequeueIfWait(%v, %v), want %v", x, err, fresh)
+	}
+}
@@ -5,13 +5,31 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/donyori/gocontainer"
 	iheap "github.com/donyori/gocontainer/internal/heap"
 )
 
-// Base type of PriorityQueue and PriorityQueueEx.
+// Base type of PriorityQueue, PriorityQueueEx and HandlePriorityQueue.
 type basePriorityQueue struct {
-	h    iheap.Heap
-	lock *sync.RWMutex
+	h       iheap.Heap
+	lock    *sync.RWMutex
+	isDebug bool
+
+	// Optional. Called with the lock held, after x is enqueued
+	// (isEnqueue = true) or its priority is updated, and before the heap
+	// is fixed, to compute the sort key of x. Used by HandlePriorityQueue.
+	setKey func(x gocontainer.Comparable, isEnqueue bool) error
+
+	// Optional. Called with the lock held, after x leaves the queue
+	// by Dequeue, Remove, Clear or Reset. Used by PriorityQueueEx.
+	onLeave func(x gocontainer.Comparable)
+
+	obs    gocontainer.Observer // Optional. Set by SetObserver.
+	hasObs int32                // 1 if obs is not nil. Accessed atomically.
+
+	// Closed and reset to nil on mutation, to wake up the blocked
+	// dequeuers. Created by the first of them.
+	waitCh chan struct{}
 }
 
 func (pq *basePriorityQueue) Len() int {
@@ -37,15 +55,25 @@ func (pq *basePriorityQueue) Cap() int {
 }
 
 func (pq *basePriorityQueue) Reset(capacity int) {
+	if err := pq.TryReset(capacity); err != nil {
+		panic(err)
+	}
+}
+
+// Same as Reset, but return an error instead of panic.
+func (pq *basePriorityQueue) TryReset(capacity int) error {
 	if capacity < 0 {
-		panic(fmt.Errorf("gocontainer: capacity(%d) is negative", capacity))
+		return newNegativeCapacityError(capacity)
 	}
 	if pq.lock != nil {
 		pq.lock.Lock()
 		defer pq.lock.Unlock()
 	}
+	pq.releaseAll()
 	pq.h.Reset(capacity)
 	heap.Init(pq.h)
+	pq.mutated()
+	return nil
 }
 
 func (pq *basePriorityQueue) Clear() {
@@ -56,6 +84,104 @@ func (pq *basePriorityQueue) Clear() {
 		pq.lock.Lock()
 		defer pq.lock.Unlock()
 	}
+	pq.releaseAll()
 	pq.h.Clear()
 	heap.Init(pq.h)
+	pq.mutated()
+}
+
+// Set the debug mode. In debug mode, the queue checks itself by Validate()
+// after every mutation, and panics if the check fails.
+// It helps to find an inconsistent Less method
+// or an item modified in place after enqueued.
+func (pq *basePriorityQueue) SetDebug(isDebug bool) {
+	if pq.lock != nil {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+	}
+	pq.isDebug = isDebug
+}
+
+// Check the heap property of the queue, and for PriorityQueueEx,
+// check that the index of each item matches its position.
+// Return nil if the queue is valid.
+func (pq *basePriorityQueue) Validate() error {
+	if pq == nil {
+		return nil
+	}
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	return pq.h.Validate()
+}
+
+// Return a human-readable dump of the underlying heap tree.
+func (pq *basePriorityQueue) DebugString() string {
+	if pq == nil {
+		return "<nil>"
+	}
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	return pq.h.DebugString()
+}
+
+// Return the underlying heap tree in Graphviz DOT language.
+func (pq *basePriorityQueue) DOT() string {
+	if pq == nil {
+		return "digraph heap {\n}\n"
+	}
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	return pq.h.DOT()
+}
+
+// Call it after each mutation, with the lock held.
+// It checks the queue in debug mode, and wakes up the blocked dequeuers.
+func (pq *basePriorityQueue) mutated() {
+	pq.checkIfDebug()
+	if pq.waitCh != nil {
+		close(pq.waitCh)
+		pq.waitCh = nil
+	}
+}
+
+func (pq *basePriorityQueue) checkIfDebug() {
+	if !pq.isDebug {
+		return
+	}
+	if err := pq.h.Validate(); err != nil {
+		panic(err)
+	}
+}
+
+// Release the ownership of all the items of PriorityQueueEx
+// or HandlePriorityQueue in the queue,
+// so that they can be enqueued again,
+// and report them as removed to the observer.
+// Call it with the lock held.
+func (pq *basePriorityQueue) releaseAll() {
+	pq.h.Scan(func(x gocontainer.Comparable) bool {
+		if item := trackerOf(x); item != nil {
+			item.Release(pq)
+		}
+		if pq.onLeave != nil {
+			pq.onLeave(x)
+		}
+		if pq.obs != nil {
+			pq.obs.Observe(gocontainer.Event{
+				Kind: gocontainer.EventRemove,
+				Item: x,
+			})
+		}
+		return false
+	})
+}
+
+func newNegativeCapacityError(capacity int) error {
+	return fmt.Errorf("%w: %d", gocontainer.ErrNegativeCapacity, capacity)
 }
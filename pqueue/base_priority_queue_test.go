@@ -0,0 +1,59 @@
+package pqueue
+
+import (
+	"testing"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gorecover"
+)
+
+func TestValidate(t *testing.T) {
+	inputs := []testElement1{3, 0, 9, -4, 3, -5, 8}
+	pq := NewPriorityQueue(len(inputs), false, true)
+	for i := range inputs {
+		pq.Enqueue(&inputs[i])
+	}
+	if err := pq.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	// Modify an item in place after enqueued.
+	*pq.Top().(*testElement1) = 100
+	if err := pq.Validate(); err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+	t.Log("\n" + pq.DebugString())
+
+	pq.SetDebug(true)
+	x := testElement1(1)
+	err := gorecover.Recover(func() {
+		pq.Enqueue(&x)
+	})
+	if err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+	pq.Clear()
+	pq.Enqueue(&x)
+
+	pqEx := NewPriorityQueueEx(len(inputs), true, false)
+	pqEx.SetDebug(true)
+	items := make([]*gocontainer.IndexedComparableItem, len(inputs))
+	for i := range inputs {
+		inputs[i] = testElement1(i)
+		items[i] = gocontainer.NewIndexedComparableItem(&inputs[i])
+		pqEx.Enqueue(items[i])
+	}
+	if err := pqEx.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + pqEx.DOT())
+	items[3].UpdateIndex(0)
+	if err := pqEx.Validate(); err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+}
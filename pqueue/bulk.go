@@ -0,0 +1,137 @@
+package pqueue
+
+import "github.com/donyori/gocontainer"
+
+// Remove all the items for which pred returns true, and return them.
+// The queue is re-heapified only once, in O(n) time.
+func (pq *PriorityQueue) RemoveIf(
+	pred func(x gocontainer.Comparable) bool) []gocontainer.Comparable {
+	if pq == nil || pred == nil {
+		return nil
+	}
+	return pq.Retain(func(x gocontainer.Comparable) bool {
+		return !pred(x)
+	})
+}
+
+// Keep only the items for which pred returns true,
+// and return the removed items.
+// The queue is re-heapified only once, in O(n) time.
+func (pq *PriorityQueue) Retain(
+	pred func(x gocontainer.Comparable) bool) []gocontainer.Comparable {
+	if pq == nil || pred == nil {
+		return nil
+	}
+	if pq.lock != nil {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+	}
+	removed := pq.h.Filter(pred)
+	pq.mutated()
+	for _, x := range removed {
+		pq.notify(gocontainer.EventRemove, x, 0, nil)
+	}
+	return removed
+}
+
+// Replace each item x with f(x), and re-heapify the queue once,
+// in O(n) time. If f returns nil, x is kept.
+// f can also modify x in place (and return nil) to change its priority.
+func (pq *PriorityQueue) UpdateAll(
+	f func(x gocontainer.Comparable) gocontainer.Comparable) {
+	if pq == nil || f == nil {
+		return
+	}
+	if pq.lock != nil {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+	}
+	if pq.obs == nil {
+		pq.h.ReplaceAll(f)
+	} else {
+		pq.h.ReplaceAll(func(x gocontainer.Comparable) gocontainer.Comparable {
+			newX := f(x)
+			e := gocontainer.Event{Kind: gocontainer.EventUpdate, Item: x}
+			if newX != nil {
+				e.Item, e.Prev = newX, x
+			}
+			e.Len = pq.h.Len()
+			pq.obs.Observe(e)
+			return newX
+		})
+	}
+	pq.mutated()
+}
+
+// Remove all the items for which pred returns true, and return them.
+// The queue is re-heapified only once, in O(n) time.
+func (pq *PriorityQueueEx) RemoveIf(
+	pred func(ici *gocontainer.IndexedComparableItem) bool) (
+	removed []*gocontainer.IndexedComparableItem) {
+	if pq == nil || pred == nil {
+		return nil
+	}
+	return pq.Retain(func(ici *gocontainer.IndexedComparableItem) bool {
+		return !pred(ici)
+	})
+}
+
+// Keep only the items for which pred returns true,
+// and return the removed items.
+// The queue is re-heapified only once, in O(n) time.
+func (pq *PriorityQueueEx) Retain(
+	pred func(ici *gocontainer.IndexedComparableItem) bool) (
+	removed []*gocontainer.IndexedComparableItem) {
+	if pq == nil || pred == nil {
+		return nil
+	}
+	pq.purgeExpired()
+	if pq.lock != nil {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+	}
+	xs := pq.h.Filter(func(x gocontainer.Comparable) bool {
+		return pred(x.(*gocontainer.IndexedComparableItem))
+	})
+	removed = make([]*gocontainer.IndexedComparableItem, len(xs))
+	for i, x := range xs {
+		pq.leave(x)
+		removed[i] = x.(*gocontainer.IndexedComparableItem)
+	}
+	pq.mutated()
+	for _, x := range xs {
+		pq.notify(gocontainer.EventRemove, x, 0, nil)
+	}
+	return
+}
+
+// Set the priority of each item ici to f(ici), and re-heapify the queue once,
+// in O(n) time. If f returns nil, the priority of ici is not changed.
+// In a queue with linear aging, a new priority other than
+// Float64Priority and Int64Priority is ignored.
+func (pq *PriorityQueueEx) UpdateAll(
+	f func(ici *gocontainer.IndexedComparableItem) gocontainer.Comparable) {
+	if pq == nil || f == nil {
+		return
+	}
+	pq.purgeExpired()
+	if pq.lock != nil {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+	}
+	pq.h.ReplaceAll(func(x gocontainer.Comparable) gocontainer.Comparable {
+		ici := x.(*gocontainer.IndexedComparableItem)
+		if newX := f(ici); newX != nil {
+			oldX := ici.Get()
+			ici.Set(newX)
+			if pq.setKey != nil && pq.setKey(ici, false) != nil {
+				// Keep the old priority, which has a valid aging key.
+				ici.Set(oldX)
+				return nil
+			}
+			pq.notify(gocontainer.EventUpdate, ici, 0, nil)
+		}
+		return nil
+	})
+	pq.mutated()
+}
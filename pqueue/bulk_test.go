@@ -0,0 +1,90 @@
+package pqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestPriorityQueueBulk(t *testing.T) {
+	inputs := []testElement1{3, 0, 9, -4, 3, -5, 8, 1, 6}
+	pq := NewPriorityQueue(0, true, true)
+	pq.SetDebug(true)
+	for i := range inputs {
+		pq.Enqueue(&inputs[i])
+	}
+	removed := pq.RemoveIf(func(x gocontainer.Comparable) bool {
+		return *x.(*testElement1) < 0
+	})
+	if len(removed) != 2 || pq.Len() != 7 {
+		t.Fatalf("Removed %d items, %d left", len(removed), pq.Len())
+	}
+	removed = pq.Retain(func(x gocontainer.Comparable) bool {
+		return *x.(*testElement1) != 9
+	})
+	if len(removed) != 1 || *removed[0].(*testElement1) != 9 {
+		t.Fatalf("Removed %v, want [9]", removed)
+	}
+	// Reverse the order.
+	pq.UpdateAll(func(x gocontainer.Comparable) gocontainer.Comparable {
+		y := -*x.(*testElement1)
+		return &y
+	})
+	var outputs []testElement1
+	for pq.Len() > 0 {
+		x, _ := pq.Dequeue()
+		outputs = append(outputs, *x.(*testElement1))
+	}
+	want := []testElement1{0, -1, -3, -3, -6, -8}
+	for i := range want {
+		if i >= len(outputs) || outputs[i] != want[i] {
+			t.Fatalf("Outputs(%v) != %v", outputs, want)
+		}
+	}
+}
+
+func TestPriorityQueueExBulk(t *testing.T) {
+	now := time.Unix(1000, 0)
+	pq := NewPriorityQueueExWithExpiry(0, false, true, &ExpiryOptions{
+		Now: func() time.Time {
+			return now
+		},
+	})
+	pq.SetDebug(true)
+	inputs := []testElement1{3, 0, 9, -4, 3, -5, 8, 1, 6}
+	icis := make([]*gocontainer.IndexedComparableItem, len(inputs))
+	for i := range inputs {
+		icis[i] = gocontainer.NewIndexedComparableItem(&inputs[i])
+		pq.EnqueueWithTTL(icis[i], time.Second)
+	}
+	removed := pq.RemoveIf(func(ici *gocontainer.IndexedComparableItem) bool {
+		return *ici.Get().(*testElement1)%2 != 0
+	})
+	if len(removed) != 5 || pq.Len() != 4 {
+		t.Fatalf("Removed %d items, %d left", len(removed), pq.Len())
+	}
+	for _, ici := range removed {
+		if ici.Owner() != nil || ici.Index() != -1 {
+			t.Errorf("Removed item %v is still owned", ici.Get())
+		}
+		if _, ok := pq.Deadline(ici); ok {
+			t.Errorf("Removed item %v still has a deadline", ici.Get())
+		}
+		pq.Enqueue(ici) // Can be enqueued again.
+	}
+	pq.UpdateAll(func(ici *gocontainer.IndexedComparableItem) gocontainer.Comparable {
+		if *ici.Get().(*testElement1) == 8 {
+			y := testElement1(-10)
+			return &y
+		}
+		return nil
+	})
+	if top := pq.Top(); *top.Get().(*testElement1) != -10 {
+		t.Fatalf("Top(%v) != -10", *top.Get().(*testElement1))
+	}
+	now = now.Add(time.Second)
+	if n := pq.Len(); n != 5 { // The re-enqueued items have no deadline.
+		t.Fatalf("Len(%d) != 5", n)
+	}
+}
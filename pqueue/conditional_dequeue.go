@@ -0,0 +1,338 @@
+package pqueue
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Dequeue the top item if pred returns true for it, or pred is nil.
+// It is atomic on a sync queue, unlike Top followed by Dequeue.
+// pred is called with the lock held, so it must NOT call any method
+// of the queue.
+func (pq *PriorityQueue) DequeueIf(pred func(x gocontainer.Comparable) bool) (
+	x gocontainer.Comparable, ok bool) {
+	xs := pq.DequeueWhileN(pred, 1)
+	if len(xs) == 0 {
+		return // nil, false
+	}
+	return xs[0], true
+}
+
+// Dequeue the items from the top while pred returns true for them,
+// in one lock acquisition. If pred is nil, it dequeues all the items.
+// pred is called with the lock held, so it must NOT call any method
+// of the queue.
+func (pq *PriorityQueue) DequeueWhile(
+	pred func(x gocontainer.Comparable) bool) []gocontainer.Comparable {
+	return pq.DequeueWhileN(pred, -1)
+}
+
+// Same as DequeueWhile, but dequeue at most n items.
+// n < 0 means no limit.
+func (pq *PriorityQueue) DequeueWhileN(
+	pred func(x gocontainer.Comparable) bool,
+	n int) []gocontainer.Comparable {
+	if pq == nil {
+		return nil
+	}
+	var wait time.Duration
+	if pq.lock != nil {
+		wait = pq.writeLock()
+		defer pq.lock.Unlock()
+	}
+	return pq.popWhile(pred, n, wait)
+}
+
+// Dequeue the items from the top as long as their total cost
+// is not greater than maxCost, in one lock acquisition.
+// The cost of x is budget(x), or 1 if budget is nil,
+// in which case maxCost is the maximum number of items.
+// It stops at the first item exceeding the budget, so it may return
+// nothing if the top item alone exceeds maxCost.
+// budget is called with the lock held, so it must NOT call any method
+// of the queue.
+func (pq *PriorityQueue) DequeueBatch(maxCost int,
+	budget func(x gocontainer.Comparable) int) []gocontainer.Comparable {
+	return pq.DequeueWhile(newBudgetPred(maxCost, budget))
+}
+
+// Same as DequeueIf, but if the top item doesn't satisfy pred,
+// wait until it does, or ctx is done.
+// pred is called once after each mutation of the queue.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (pq *PriorityQueue) DequeueIfWait(ctx context.Context,
+	pred func(x gocontainer.Comparable) bool) (
+	x gocontainer.Comparable, err error) {
+	xs, err := pq.DequeueWhileNWait(ctx, pred, 1)
+	if err != nil {
+		return nil, err
+	}
+	return xs[0], nil
+}
+
+// Same as DequeueWhile, but wait until at least one item is dequeued,
+// or ctx is done.
+// pred is called once after each mutation of the queue.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (pq *PriorityQueue) DequeueWhileWait(ctx context.Context,
+	pred func(x gocontainer.Comparable) bool) (
+	[]gocontainer.Comparable, error) {
+	return pq.DequeueWhileNWait(ctx, pred, -1)
+}
+
+// Same as DequeueWhileN, but wait until at least one item is dequeued,
+// or ctx is done.
+// pred is called once after each mutation of the queue.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (pq *PriorityQueue) DequeueWhileNWait(ctx context.Context,
+	pred func(x gocontainer.Comparable) bool, n int) (
+	[]gocontainer.Comparable, error) {
+	return pq.popWhileWait(ctx, func() func(x gocontainer.Comparable) bool {
+		return pred
+	}, n, nil)
+}
+
+// Same as DequeueBatch, but wait until at least one item is dequeued,
+// or ctx is done.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (pq *PriorityQueue) DequeueBatchWait(ctx context.Context, maxCost int,
+	budget func(x gocontainer.Comparable) int) (
+	[]gocontainer.Comparable, error) {
+	return pq.popWhileWait(ctx, func() func(x gocontainer.Comparable) bool {
+		return newBudgetPred(maxCost, budget)
+	}, -1, nil)
+}
+
+// Dequeue the top item if pred returns true for it, or pred is nil.
+// It is atomic on a sync queue, unlike Top followed by Dequeue.
+// pred is called with the lock held, so it must NOT call any method
+// of the queue.
+func (pq *PriorityQueueEx) DequeueIf(
+	pred func(ici *gocontainer.IndexedComparableItem) bool) (
+	ici *gocontainer.IndexedComparableItem, ok bool) {
+	icis := pq.DequeueWhileN(pred, 1)
+	if len(icis) == 0 {
+		return // nil, false
+	}
+	return icis[0], true
+}
+
+// Dequeue the items from the top while pred returns true for them,
+// in one lock acquisition. If pred is nil, it dequeues all the items.
+// pred is called with the lock held, so it must NOT call any method
+// of the queue.
+func (pq *PriorityQueueEx) DequeueWhile(
+	pred func(ici *gocontainer.IndexedComparableItem) bool) (
+	icis []*gocontainer.IndexedComparableItem) {
+	return pq.DequeueWhileN(pred, -1)
+}
+
+// Same as DequeueWhile, but dequeue at most n items.
+// n < 0 means no limit.
+func (pq *PriorityQueueEx) DequeueWhileN(
+	pred func(ici *gocontainer.IndexedComparableItem) bool,
+	n int) []*gocontainer.IndexedComparableItem {
+	if pq == nil {
+		return nil
+	}
+	pq.beforeDequeue()
+	var wait time.Duration
+	if pq.lock != nil {
+		wait = pq.writeLock()
+		defer pq.lock.Unlock()
+	}
+	return toICIs(pq.popWhile(icPred(pred), n, wait))
+}
+
+// Dequeue the items from the top as long as their total cost
+// is not greater than maxCost, in one lock acquisition.
+// The cost of ici is budget(ici), or 1 if budget is nil,
+// in which case maxCost is the maximum number of items.
+// It stops at the first item exceeding the budget, so it may return
+// nothing if the top item alone exceeds maxCost.
+// budget is called with the lock held, so it must NOT call any method
+// of the queue.
+func (pq *PriorityQueueEx) DequeueBatch(maxCost int,
+	budget func(ici *gocontainer.IndexedComparableItem) int) (
+	icis []*gocontainer.IndexedComparableItem) {
+	pred := newBudgetPred(maxCost, icBudget(budget))
+	return pq.DequeueWhile(func(ici *gocontainer.IndexedComparableItem) bool {
+		return pred(ici)
+	})
+}
+
+// Same as DequeueIf, but if the top item doesn't satisfy pred,
+// wait until it does, or ctx is done.
+// pred is called once after each mutation of the queue.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (pq *PriorityQueueEx) DequeueIfWait(ctx context.Context,
+	pred func(ici *gocontainer.IndexedComparableItem) bool) (
+	*gocontainer.IndexedComparableItem, error) {
+	icis, err := pq.DequeueWhileNWait(ctx, pred, 1)
+	if err != nil {
+		return nil, err
+	}
+	return icis[0], nil
+}
+
+// Same as DequeueWhile, but wait until at least one item is dequeued,
+// or ctx is done.
+// pred is called once after each mutation of the queue.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (pq *PriorityQueueEx) DequeueWhileWait(ctx context.Context,
+	pred func(ici *gocontainer.IndexedComparableItem) bool) (
+	[]*gocontainer.IndexedComparableItem, error) {
+	return pq.DequeueWhileNWait(ctx, pred, -1)
+}
+
+// Same as DequeueWhileN, but wait until at least one item is dequeued,
+// or ctx is done.
+// pred is called once after each mutation of the queue.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (pq *PriorityQueueEx) DequeueWhileNWait(ctx context.Context,
+	pred func(ici *gocontainer.IndexedComparableItem) bool, n int) (
+	[]*gocontainer.IndexedComparableItem, error) {
+	xs, err := pq.popWhileWait(ctx,
+		func() func(x gocontainer.Comparable) bool {
+			return icPred(pred)
+		}, n, pq.beforeDequeue)
+	return toICIs(xs), err
+}
+
+// Same as DequeueBatch, but wait until at least one item is dequeued,
+// or ctx is done.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (pq *PriorityQueueEx) DequeueBatchWait(ctx context.Context, maxCost int,
+	budget func(ici *gocontainer.IndexedComparableItem) int) (
+	[]*gocontainer.IndexedComparableItem, error) {
+	xs, err := pq.popWhileWait(ctx,
+		func() func(x gocontainer.Comparable) bool {
+			return newBudgetPred(maxCost, icBudget(budget))
+		}, -1, pq.beforeDequeue)
+	return toICIs(xs), err
+}
+
+// Drop the expired items and refresh the aging keys if due,
+// before taking the lock to dequeue.
+func (pq *PriorityQueueEx) beforeDequeue() {
+	pq.purgeExpired()
+	pq.refreshIfDue()
+}
+
+// Pop at most n (n < 0: no limit) items from the top while pred returns
+// true for them. wait is the time spent waiting for the lock,
+// reported to the observer. pred == nil accepts all the items.
+// Call it with the lock held.
+func (pq *basePriorityQueue) popWhile(pred func(x gocontainer.Comparable) bool,
+	n int, wait time.Duration) []gocontainer.Comparable {
+	var xs []gocontainer.Comparable
+	for (n < 0 || len(xs) < n) && pq.h.Len() > 0 &&
+		(pred == nil || pred(pq.h.Top())) {
+		x := heap.Pop(pq.h).(gocontainer.Comparable)
+		if trackerOf(x) != nil {
+			pq.leave(x)
+		}
+		xs = append(xs, x)
+	}
+	if len(xs) == 0 {
+		return nil
+	}
+	pq.mutated()
+	for _, x := range xs {
+		pq.notify(gocontainer.EventDequeue, x, wait, nil)
+		wait = 0 // Only one lock acquisition.
+	}
+	return xs
+}
+
+// Call popWhile with the predicate returned by newPred,
+// until it pops at least one item, or ctx is done.
+// newPred is called on each attempt, to reset the state of the predicate.
+// beforeLock is optional, and called before each attempt without the lock.
+func (pq *basePriorityQueue) popWhileWait(ctx context.Context,
+	newPred func() func(x gocontainer.Comparable) bool, n int,
+	beforeLock func()) ([]gocontainer.Comparable, error) {
+	if pq == nil || pq.lock == nil {
+		return nil, gocontainer.ErrNotSync
+	}
+	for {
+		if beforeLock != nil {
+			beforeLock()
+		}
+		wait := pq.writeLock()
+		if xs := pq.popWhile(newPred(), n, wait); len(xs) > 0 {
+			pq.lock.Unlock()
+			return xs, nil
+		}
+		if pq.waitCh == nil {
+			pq.waitCh = make(chan struct{})
+		}
+		waitCh := pq.waitCh
+		pq.lock.Unlock()
+		select {
+		case <-waitCh:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Return a predicate accepting the items as long as their total cost
+// is not greater than maxCost.
+func newBudgetPred(maxCost int, budget func(x gocontainer.Comparable) int) func(
+	x gocontainer.Comparable) bool {
+	var total int
+	return func(x gocontainer.Comparable) bool {
+		cost := 1
+		if budget != nil {
+			cost = budget(x)
+		}
+		if total+cost > maxCost {
+			return false
+		}
+		total += cost
+		return true
+	}
+}
+
+func icPred(pred func(ici *gocontainer.IndexedComparableItem) bool) func(
+	x gocontainer.Comparable) bool {
+	if pred == nil {
+		return nil
+	}
+	return func(x gocontainer.Comparable) bool {
+		return pred(x.(*gocontainer.IndexedComparableItem))
+	}
+}
+
+func icBudget(budget func(ici *gocontainer.IndexedComparableItem) int) func(
+	x gocontainer.Comparable) int {
+	if budget == nil {
+		return nil
+	}
+	return func(x gocontainer.Comparable) int {
+		return budget(x.(*gocontainer.IndexedComparableItem))
+	}
+}
+
+func toICIs(xs []gocontainer.Comparable) []*gocontainer.IndexedComparableItem {
+	if xs == nil {
+		return nil
+	}
+	icis := make([]*gocontainer.IndexedComparableItem, len(xs))
+	for i, x := range xs {
+		icis[i] = x.(*gocontainer.IndexedComparableItem)
+	}
+	return icis
+}
@@ -0,0 +1,128 @@
+package pqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestConditionalDequeue(t *testing.T) {
+	inputs := []testElement1{3, 0, 9, -4, 3, -5, 8, 1, 6}
+	pq := NewPriorityQueue(0, true, true)
+	pq.SetDebug(true)
+	for i := range inputs {
+		pq.Enqueue(&inputs[i])
+	}
+	isBig := func(x gocontainer.Comparable) bool {
+		return *x.(*testElement1) >= 6
+	}
+	if x, ok := pq.DequeueIf(isBig); !ok || *x.(*testElement1) != 9 {
+		t.Fatalf("DequeueIf: %v, %t", x, ok)
+	}
+	xs := pq.DequeueWhile(isBig)
+	if len(xs) != 2 || *xs[0].(*testElement1) != 8 ||
+		*xs[1].(*testElement1) != 6 {
+		t.Fatalf("DequeueWhile: %v", xs)
+	}
+	if x, ok := pq.DequeueIf(isBig); ok {
+		t.Fatalf("DequeueIf: %v, %t", x, ok)
+	}
+	// 3 + 3 + 1 <= 7 < 3 + 3 + 1 + 0 + 1 (cost of 0 is 1).
+	xs = pq.DequeueBatch(7, func(x gocontainer.Comparable) int {
+		if c := int(*x.(*testElement1)); c > 0 {
+			return c
+		}
+		return 1
+	})
+	if len(xs) != 3 {
+		t.Fatalf("DequeueBatch: %d items", len(xs))
+	}
+	if xs = pq.DequeueBatch(2, nil); len(xs) != 2 || pq.Len() != 1 {
+		t.Fatalf("DequeueBatch(2, nil): %d items, %d left", len(xs), pq.Len())
+	}
+
+	pqx := NewPriorityQueueEx(0, false, false)
+	icis := make([]*gocontainer.IndexedComparableItem, len(inputs))
+	for i := range inputs {
+		icis[i] = gocontainer.NewIndexedComparableItem(&inputs[i])
+		pqx.Enqueue(icis[i])
+	}
+	out := pqx.DequeueWhile(func(ici *gocontainer.IndexedComparableItem) bool {
+		return *ici.Get().(*testElement1) < 0
+	})
+	if len(out) != 2 || out[0] != icis[5] || out[1] != icis[3] {
+		t.Fatalf("DequeueWhile: %v", out)
+	}
+	for _, ici := range out {
+		if ici.Owner() != nil {
+			t.Errorf("Dequeued item %v is still owned", ici.Get())
+		}
+	}
+	if out = pqx.DequeueBatch(3, nil); len(out) != 3 {
+		t.Fatalf("DequeueBatch(3, nil): %d items", len(out))
+	}
+	if out = pqx.DequeueWhile(nil); len(out) != 4 || pqx.Len() != 0 {
+		t.Fatalf("DequeueWhile(nil): %d items, %d left", len(out), pqx.Len())
+	}
+}
+
+func TestConditionalDequeueWait(t *testing.T) {
+	_, err := NewPriorityQueue(0, false, false).DequeueIfWait(
+		context.Background(), nil)
+	if !errors.Is(err, gocontainer.ErrNotSync) {
+		t.Errorf("err(%v) is not ErrNotSync", err)
+	}
+
+	pq := NewPriorityQueueEx(0, true, true)
+	small := testElement1(1)
+	pq.Enqueue(gocontainer.NewIndexedComparableItem(&small))
+	isBig := func(ici *gocontainer.IndexedComparableItem) bool {
+		return *ici.Get().(*testElement1) >= 5
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if _, err = pq.DequeueIfWait(ctx, isBig); !errors.Is(err,
+		context.DeadlineExceeded) {
+		t.Fatalf("err(%v) is not DeadlineExceeded", err)
+	}
+
+	big := gocontainer.NewIndexedComparableItem(new(testElement1))
+	done := make(chan *gocontainer.IndexedComparableItem)
+	go func() {
+		ici, err := pq.DequeueIfWait(context.Background(), isBig)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- ici
+	}()
+	pq.Enqueue(big) // 0, not big enough.
+	p := testElement1(5)
+	pq.Update(big, &p)
+	select {
+	case ici := <-done:
+		if ici != big {
+			t.Errorf("Dequeued %v, want 5", ici.Get())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("DequeueIfWait is not woken up")
+	}
+
+	pq.Dequeue() // Dequeue small, to make the queue empty.
+	go func() {
+		time.Sleep(time.Millisecond)
+		for i := 0; i < 3; i++ {
+			pq.Enqueue(gocontainer.NewIndexedComparableItem(&p))
+		}
+	}()
+	icis, err := pq.DequeueBatchWait(context.Background(), 20,
+		func(ici *gocontainer.IndexedComparableItem) int {
+			return int(*ici.Get().(*testElement1))
+		})
+	if err != nil || len(icis) == 0 || icis[0].Get() != &p {
+		t.Fatalf("DequeueBatchWait: %v, %v", icis, err)
+	}
+}
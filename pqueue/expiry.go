@@ -0,0 +1,198 @@
+package pqueue
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/internal/expiry"
+)
+
+type ExpiryOptions struct {
+	// The clock. Default: time.Now.
+	Now func() time.Time
+
+	// Called with each expired item, without the lock held. Optional.
+	OnExpire func(ici *gocontainer.IndexedComparableItem)
+
+	// Sweep the expired items in a background goroutine
+	// on every receive from SweepTick, or every SweepInterval
+	// if SweepTick is nil.
+	// If both are unset, expired items are only removed lazily,
+	// when the queue is accessed, or when Sweep is called.
+	// The sweeper needs the queue to be created with isSync = true.
+	SweepInterval time.Duration
+	SweepTick     <-chan time.Time
+}
+
+type pqExpiry struct {
+	now      func() time.Time
+	onExpire func(ici *gocontainer.IndexedComparableItem)
+	index    *expiry.Index // Deadlines, in a min-heap.
+	entries  map[*gocontainer.IndexedComparableItem]*expiry.Entry
+	stop     func() // Stop the sweeper.
+}
+
+// A background sweeper needs a lock, so it returns an error wrapping
+// gocontainer.ErrNotSync if opts sets one and isSync is false.
+func (pq *PriorityQueueEx) enableExpiry(opts *ExpiryOptions,
+	isSync bool) error {
+	if (opts.SweepTick != nil || opts.SweepInterval > 0) && !isSync {
+		return fmt.Errorf("%w: background sweeper needs a lock",
+			gocontainer.ErrNotSync)
+	}
+	pq.exp = &pqExpiry{
+		now:      opts.Now,
+		onExpire: opts.OnExpire,
+		index:    expiry.NewIndex(),
+		entries:  make(map[*gocontainer.IndexedComparableItem]*expiry.Entry),
+	}
+	if pq.exp.now == nil {
+		pq.exp.now = time.Now
+	}
+	pq.exp.stop = expiry.StartSweeper(opts.SweepTick, opts.SweepInterval,
+		func() { pq.TrySweep() })
+	return nil
+}
+
+// Same as Enqueue, but ici expires at deadline.
+// The queue must be created with expiry enabled.
+func (pq *PriorityQueueEx) EnqueueWithDeadline(
+	ici *gocontainer.IndexedComparableItem, deadline time.Time) {
+	if err := pq.TryEnqueueWithDeadline(ici, deadline); err != nil {
+		panic(err)
+	}
+}
+
+// Same as EnqueueWithDeadline, but return an error instead of panic.
+func (pq *PriorityQueueEx) TryEnqueueWithDeadline(
+	ici *gocontainer.IndexedComparableItem, deadline time.Time) error {
+	if pq.exp == nil {
+		return gocontainer.ErrExpiryDisabled
+	}
+	if ici == nil {
+		return gocontainer.ErrNilItem
+	}
+	return pq.enqueueIndexed(ici, func() {
+		pq.exp.entries[ici] = pq.exp.index.Add(ici, deadline)
+	})
+}
+
+// Same as Enqueue, but ici expires after ttl.
+// The queue must be created with expiry enabled.
+func (pq *PriorityQueueEx) EnqueueWithTTL(
+	ici *gocontainer.IndexedComparableItem, ttl time.Duration) {
+	if err := pq.TryEnqueueWithTTL(ici, ttl); err != nil {
+		panic(err)
+	}
+}
+
+// Same as EnqueueWithTTL, but return an error instead of panic.
+func (pq *PriorityQueueEx) TryEnqueueWithTTL(
+	ici *gocontainer.IndexedComparableItem, ttl time.Duration) error {
+	if pq.exp == nil {
+		return gocontainer.ErrExpiryDisabled
+	}
+	return pq.TryEnqueueWithDeadline(ici, pq.exp.now().Add(ttl))
+}
+
+// Return the deadline of ici, and ok = false if ici is not in the queue
+// or has no deadline.
+func (pq *PriorityQueueEx) Deadline(ici *gocontainer.IndexedComparableItem) (
+	deadline time.Time, ok bool) {
+	if pq == nil || pq.exp == nil || ici == nil {
+		return
+	}
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	e, ok := pq.exp.entries[ici]
+	if ok {
+		deadline = e.Deadline
+	}
+	return
+}
+
+// Remove the expired items, call ExpiryOptions.OnExpire with them,
+// and return the number of them.
+// Expired items are also removed lazily when the queue is accessed,
+// so it's unnecessary to call it in most cases.
+func (pq *PriorityQueueEx) Sweep() int {
+	n, err := pq.TrySweep()
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Same as Sweep, but return an error instead of panic,
+// if an expired item is not found in the queue.
+// Such an item is dropped from the deadlines, and the others are
+// removed as usual.
+func (pq *PriorityQueueEx) TrySweep() (int, error) {
+	if pq == nil || pq.exp == nil {
+		return 0, nil
+	}
+	expired, err := pq.popExpired()
+	if pq.exp.onExpire != nil {
+		for _, ici := range expired {
+			pq.exp.onExpire(ici)
+		}
+	}
+	return len(expired), err
+}
+
+// Stop the background sweeper. Do nothing if there is no sweeper.
+func (pq *PriorityQueueEx) StopSweeper() {
+	if pq == nil || pq.exp == nil {
+		return
+	}
+	pq.exp.stop()
+}
+
+// Remove the expired items before an access.
+// An error of TrySweep is ignored, as the inconsistent items are
+// already dropped, and the queue is still valid.
+func (pq *PriorityQueueEx) purgeExpired() {
+	if pq.exp != nil && pq.hasExpired() {
+		pq.TrySweep()
+	}
+}
+
+// Check the earliest deadline under the read lock,
+// so that the readers don't take the write lock if nothing expired.
+func (pq *PriorityQueueEx) hasExpired() bool {
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	return pq.exp.index.HasExpired(pq.exp.now())
+}
+
+func (pq *PriorityQueueEx) popExpired() (
+	expired []*gocontainer.IndexedComparableItem, err error) {
+	if pq.lock != nil {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+	}
+	pq.exp.index.PopExpired(pq.exp.now(), func(item interface{}) {
+		ici := item.(*gocontainer.IndexedComparableItem)
+		delete(pq.exp.entries, ici)
+		idx, ok := pq.indexOf(ici, 0)
+		if !ok {
+			if err == nil {
+				err = fmt.Errorf("%w: expired item %v",
+					gocontainer.ErrForeignItem, ici.Get())
+			}
+			return
+		}
+		pq.removeAt(idx)
+		pq.notify(gocontainer.EventEvict, ici, 0, nil)
+		expired = append(expired, ici)
+	})
+	if len(expired) > 0 {
+		pq.mutated()
+	}
+	return
+}
@@ -0,0 +1,160 @@
+package pqueue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestPriorityQueueExExpiry(t *testing.T) {
+	err := NewPriorityQueueEx(0, false, false).TryEnqueueWithTTL(
+		gocontainer.NewIndexedComparableItem(new(testElement1)), time.Second)
+	if !errors.Is(err, gocontainer.ErrExpiryDisabled) {
+		t.Fatalf("err(%v) is not ErrExpiryDisabled", err)
+	}
+
+	clock := &testClock{now: time.Unix(1000, 0)}
+	var expired []*gocontainer.IndexedComparableItem
+	pq := NewPriorityQueueExWithExpiry(0, false, true, &ExpiryOptions{
+		Now: clock.Now,
+		OnExpire: func(ici *gocontainer.IndexedComparableItem) {
+			expired = append(expired, ici)
+		},
+	})
+	pq.SetDebug(true)
+	inputsData := []testElement1{3, 0, 9, -4, 3}
+	inputs := make([]*gocontainer.IndexedComparableItem, len(inputsData))
+	for i := range inputsData {
+		inputs[i] = gocontainer.NewIndexedComparableItem(&inputsData[i])
+	}
+	pq.EnqueueWithTTL(inputs[0], 3*time.Second)
+	pq.EnqueueWithTTL(inputs[1], 1*time.Second)
+	pq.EnqueueWithTTL(inputs[2], 2*time.Second)
+	pq.EnqueueWithTTL(inputs[3], 5*time.Second)
+	pq.Enqueue(inputs[4]) // Never expires.
+	if d, ok := pq.Deadline(inputs[2]); !ok || !d.Equal(
+		clock.now.Add(2*time.Second)) {
+		t.Fatalf("Deadline: %v, %t", d, ok)
+	}
+	// Removing an item removes its deadline.
+	if !pq.Remove(inputs[2]) {
+		t.Fatal("Remove failed!")
+	}
+	if _, ok := pq.Deadline(inputs[2]); ok {
+		t.Fatal("Removed item still has a deadline.")
+	}
+	clock.Advance(3 * time.Second)
+	// Expired items are removed lazily.
+	if n := pq.Len(); n != 2 {
+		t.Fatalf("Len(%d) != 2", n)
+	}
+	if len(expired) != 2 || expired[0] != inputs[1] || expired[1] != inputs[0] {
+		t.Fatalf("Expired items are wrong: %v", expired)
+	}
+	if inputs[0].Owner() != nil {
+		t.Fatal("Expired item is not released.")
+	}
+	x, ok := pq.Dequeue()
+	if !ok || x != inputs[3] {
+		t.Fatalf("Dequeue: %v, %t", x, ok)
+	}
+	clock.Advance(10 * time.Second)
+	if n := pq.Sweep(); n != 0 {
+		t.Fatalf("Sweep %d items after the item is dequeued.", n)
+	}
+	pq.EnqueueWithTTL(inputs[0], time.Second)
+	pq.Clear()
+	clock.Advance(10 * time.Second)
+	if n := pq.Sweep(); n != 0 {
+		t.Fatalf("Sweep %d items after Clear.", n)
+	}
+}
+
+func TestPriorityQueueExSweeper(t *testing.T) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	tick := make(chan time.Time)
+	expired := make(chan *gocontainer.IndexedComparableItem, 1)
+	var mu sync.Mutex
+	pq := NewPriorityQueueExWithExpiry(0, false, true, &ExpiryOptions{
+		Now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return clock.Now()
+		},
+		OnExpire: func(ici *gocontainer.IndexedComparableItem) {
+			expired <- ici
+		},
+		SweepTick: tick,
+	})
+	defer pq.StopSweeper()
+	x := testElement1(1)
+	ici := gocontainer.NewIndexedComparableItem(&x)
+	pq.EnqueueWithTTL(ici, time.Second)
+	mu.Lock()
+	clock.Advance(time.Second)
+	mu.Unlock()
+	tick <- time.Time{}
+	if e := <-expired; e != ici {
+		t.Fatalf("Expired item: %v", e)
+	}
+}
+
+func TestPriorityQueueExExpiryDefaultOptions(t *testing.T) {
+	pq := NewPriorityQueueExWithExpiry(0, false, false, nil)
+	ici := gocontainer.NewIndexedComparableItem(new(testElement1))
+	if err := pq.TryEnqueueWithTTL(ici, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := pq.Deadline(ici); !ok || d.Before(time.Now()) {
+		t.Fatalf("Deadline: %v, %t", d, ok)
+	}
+	if n := pq.Len(); n != 1 {
+		t.Fatalf("Len(%d) != 1", n)
+	}
+}
+
+func TestPriorityQueueExExpiryErrors(t *testing.T) {
+	_, err := NewPriorityQueueExWithExpiryE(0, false, false, &ExpiryOptions{
+		SweepInterval: time.Second,
+	})
+	if !errors.Is(err, gocontainer.ErrNotSync) {
+		t.Fatalf("err(%v) is not ErrNotSync", err)
+	}
+
+	clock := &testClock{now: time.Unix(1000, 0)}
+	pq := NewPriorityQueueExWithExpiry(0, false, false, &ExpiryOptions{
+		Now: clock.Now,
+	})
+	x := testElement1(1)
+	ici := gocontainer.NewIndexedComparableItem(&x)
+	pq.EnqueueWithTTL(ici, time.Second)
+	clock.Advance(time.Second)
+	// TryUpdate and TryRemove don't act on an expired item.
+	if err = pq.TryUpdate(ici, new(testElement1)); !errors.Is(err,
+		gocontainer.ErrForeignItem) {
+		t.Fatalf("err(%v) is not ErrForeignItem", err)
+	}
+	if ici.Get() != &x {
+		t.Fatal("TryUpdate changed an expired item.")
+	}
+	if err = pq.TryRemove(ici); !errors.Is(err,
+		gocontainer.ErrForeignItem) {
+		t.Fatalf("err(%v) is not ErrForeignItem", err)
+	}
+
+	// A deadline of an item not in the queue is reported by TrySweep.
+	foreign := gocontainer.NewIndexedComparableItem(new(testElement1))
+	pq.exp.entries[foreign] = pq.exp.index.Add(foreign, clock.now)
+	pq.EnqueueWithTTL(ici, 0)
+	n, err := pq.TrySweep()
+	if !errors.Is(err, gocontainer.ErrForeignItem) {
+		t.Fatalf("err(%v) is not ErrForeignItem", err)
+	}
+	if n != 1 || pq.Len() != 0 || len(pq.exp.entries) != 0 {
+		t.Fatalf("TrySweep removed %d, Len %d, %d deadlines left",
+			n, pq.Len(), len(pq.exp.entries))
+	}
+}
@@ -0,0 +1,306 @@
+package pqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Statistics of a class of FairQueue.
+type ClassStats struct {
+	Weight   int
+	Len      int
+	Enqueued uint64 // Number of items enqueued.
+	Dequeued uint64 // Number of items dequeued.
+}
+
+type fairClass struct {
+	pq      *PriorityQueue // Not synchronized. Protected by the FairQueue.
+	weight  int
+	deficit int
+	stats   ClassStats
+}
+
+// A queue of several classes (e.g. tenants or priority bands),
+// each of which is a PriorityQueue.
+// Dequeue shares the items between the classes by deficit round robin:
+// in each round, a non-empty class can dequeue as many items as its weight.
+// Inside a class, the items are dequeued in the order of priority.
+type FairQueue struct {
+	classes []*fairClass
+	cur     int // The class in service.
+	n       int // Total length.
+	lock    *sync.RWMutex
+
+	// Closed and reset to nil on Enqueue, to wake up the blocked
+	// dequeuers. Created by the first of them.
+	waitCh chan struct{}
+}
+
+// Create a FairQueue with len(weights) classes, numbered from 0.
+func NewFairQueue(weights []int, isTopMax, isSync bool) *FairQueue {
+	fq, err := NewFairQueueE(weights, isTopMax, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return fq
+}
+
+// Same as NewFairQueue, but return an error instead of panic.
+func NewFairQueueE(weights []int, isTopMax, isSync bool) (*FairQueue, error) {
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("%w: no class", gocontainer.ErrInvalidWeight)
+	}
+	fq := &FairQueue{classes: make([]*fairClass, len(weights))}
+	for i, w := range weights {
+		if w <= 0 {
+			return nil, fmt.Errorf("%w: weight[%d] = %d",
+				gocontainer.ErrInvalidWeight, i, w)
+		}
+		fq.classes[i] = &fairClass{
+			pq:     NewPriorityQueue(0, isTopMax, false),
+			weight: w,
+		}
+	}
+	fq.classes[0].deficit = weights[0]
+	if isSync {
+		fq.lock = new(sync.RWMutex)
+	}
+	return fq, nil
+}
+
+func (fq *FairQueue) NumClasses() int {
+	if fq == nil {
+		return 0
+	}
+	return len(fq.classes)
+}
+
+func (fq *FairQueue) Len() int {
+	if fq == nil {
+		return 0
+	}
+	if fq.lock != nil {
+		fq.lock.RLock()
+		defer fq.lock.RUnlock()
+	}
+	return fq.n
+}
+
+// Return the number of items of the class, or 0 if class is out of range.
+func (fq *FairQueue) ClassLen(class int) int {
+	if fq == nil || class < 0 || class >= len(fq.classes) {
+		return 0
+	}
+	if fq.lock != nil {
+		fq.lock.RLock()
+		defer fq.lock.RUnlock()
+	}
+	return fq.classes[class].pq.Len()
+}
+
+func (fq *FairQueue) Enqueue(class int, x gocontainer.Comparable) {
+	if err := fq.TryEnqueue(class, x); err != nil {
+		panic(err)
+	}
+}
+
+// Same as Enqueue, but return an error instead of panic.
+func (fq *FairQueue) TryEnqueue(class int, x gocontainer.Comparable) error {
+	if err := fq.checkClass(class); err != nil {
+		return err
+	}
+	if fq.lock != nil {
+		fq.lock.Lock()
+		defer fq.lock.Unlock()
+	}
+	c := fq.classes[class]
+	if err := c.pq.TryEnqueue(x); err != nil {
+		return err
+	}
+	c.stats.Enqueued++
+	fq.n++
+	if fq.waitCh != nil {
+		close(fq.waitCh)
+		fq.waitCh = nil
+	}
+	return nil
+}
+
+// Dequeue the top item of the class chosen by deficit round robin.
+func (fq *FairQueue) Dequeue() (x gocontainer.Comparable, class int,
+	ok bool) {
+	if fq == nil {
+		return nil, -1, false
+	}
+	if fq.lock != nil {
+		fq.lock.Lock()
+		defer fq.lock.Unlock()
+	}
+	return fq.dequeue()
+}
+
+// Same as Dequeue, but if the queue is empty, wait until an item
+// is enqueued or ctx is done.
+// It returns ctx.Err() if ctx is done,
+// or gocontainer.ErrNotSync if the queue is not created with isSync = true.
+func (fq *FairQueue) DequeueWait(ctx context.Context) (
+	x gocontainer.Comparable, class int, err error) {
+	if fq == nil || fq.lock == nil {
+		return nil, -1, gocontainer.ErrNotSync
+	}
+	for {
+		fq.lock.Lock()
+		x, class, ok := fq.dequeue()
+		if ok {
+			fq.lock.Unlock()
+			return x, class, nil
+		}
+		if fq.waitCh == nil {
+			fq.waitCh = make(chan struct{})
+		}
+		waitCh := fq.waitCh
+		fq.lock.Unlock()
+		select {
+		case <-waitCh:
+		case <-ctx.Done():
+			return nil, -1, ctx.Err()
+		}
+	}
+}
+
+// Call it with the lock held.
+func (fq *FairQueue) dequeue() (x gocontainer.Comparable, class int,
+	ok bool) {
+	if fq.n <= 0 {
+		return nil, -1, false
+	}
+	for {
+		c := fq.classes[fq.cur]
+		if c.deficit > 0 && c.pq.h.Len() > 0 {
+			c.deficit--
+			x, _ = c.pq.Dequeue()
+			if c.pq.h.Len() == 0 {
+				c.deficit = 0 // An empty class keeps no credit.
+			}
+			c.stats.Dequeued++
+			fq.n--
+			return x, fq.cur, true
+		}
+		if c.pq.h.Len() == 0 {
+			c.deficit = 0
+		}
+		// Serve the next class.
+		fq.cur = (fq.cur + 1) % len(fq.classes)
+		next := fq.classes[fq.cur]
+		next.deficit += next.weight
+	}
+}
+
+func (fq *FairQueue) Weight(class int) int {
+	if fq == nil || class < 0 || class >= len(fq.classes) {
+		return 0
+	}
+	if fq.lock != nil {
+		fq.lock.RLock()
+		defer fq.lock.RUnlock()
+	}
+	return fq.classes[class].weight
+}
+
+// Set the weight of the class. It takes effect from the next round.
+func (fq *FairQueue) SetWeight(class, weight int) error {
+	if err := fq.checkClass(class); err != nil {
+		return err
+	}
+	if weight <= 0 {
+		return fmt.Errorf("%w: %d", gocontainer.ErrInvalidWeight, weight)
+	}
+	if fq.lock != nil {
+		fq.lock.Lock()
+		defer fq.lock.Unlock()
+	}
+	fq.classes[class].weight = weight
+	return nil
+}
+
+// Return the statistics of all the classes, indexed by class.
+func (fq *FairQueue) Stats() []ClassStats {
+	if fq == nil {
+		return nil
+	}
+	if fq.lock != nil {
+		fq.lock.RLock()
+		defer fq.lock.RUnlock()
+	}
+	stats := make([]ClassStats, len(fq.classes))
+	for i, c := range fq.classes {
+		stats[i] = c.stats
+		stats[i].Weight = c.weight
+		stats[i].Len = c.pq.h.Len()
+	}
+	return stats
+}
+
+func (fq *FairQueue) Scan(
+	f func(class int, x gocontainer.Comparable) (doesStop bool)) {
+	if fq == nil || f == nil {
+		return
+	}
+	if fq.lock != nil {
+		fq.lock.RLock()
+		defer fq.lock.RUnlock()
+	}
+	for i, c := range fq.classes {
+		var doesStop bool
+		c.pq.Scan(func(x gocontainer.Comparable) bool {
+			doesStop = f(i, x)
+			return doesStop
+		})
+		if doesStop {
+			return
+		}
+	}
+}
+
+// Remove all the items. The statistics are kept.
+func (fq *FairQueue) Clear() {
+	if fq == nil {
+		return
+	}
+	if fq.lock != nil {
+		fq.lock.Lock()
+		defer fq.lock.Unlock()
+	}
+	for _, c := range fq.classes {
+		c.pq.Clear()
+		c.deficit = 0
+	}
+	fq.n = 0
+}
+
+// Check the heap property of all the classes.
+func (fq *FairQueue) Validate() error {
+	if fq == nil {
+		return nil
+	}
+	if fq.lock != nil {
+		fq.lock.RLock()
+		defer fq.lock.RUnlock()
+	}
+	for i, c := range fq.classes {
+		if err := c.pq.Validate(); err != nil {
+			return fmt.Errorf("class %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (fq *FairQueue) checkClass(class int) error {
+	if fq == nil || class < 0 || class >= len(fq.classes) {
+		return fmt.Errorf("%w: class %d", gocontainer.ErrOutOfRange, class)
+	}
+	return nil
+}
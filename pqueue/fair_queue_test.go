@@ -0,0 +1,107 @@
+package pqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestFairQueue(t *testing.T) {
+	_, err := NewFairQueueE([]int{1, 0}, false, false)
+	if !errors.Is(err, gocontainer.ErrInvalidWeight) {
+		t.Fatalf("err(%v) is not ErrInvalidWeight", err)
+	}
+	_, err = NewFairQueueE(nil, false, false)
+	if !errors.Is(err, gocontainer.ErrInvalidWeight) {
+		t.Fatalf("err(%v) is not ErrInvalidWeight", err)
+	}
+	fq := NewFairQueue([]int{3, 1}, false, true)
+	inputs := make([]testElement1, 16)
+	for i := range inputs {
+		inputs[i] = testElement1(len(inputs) - i)
+		fq.Enqueue(i%2, &inputs[i])
+	}
+	if err = fq.TryEnqueue(2, &inputs[0]); !errors.Is(err,
+		gocontainer.ErrOutOfRange) {
+		t.Fatalf("err(%v) is not ErrOutOfRange", err)
+	}
+	if n := fq.ClassLen(0); n != 8 {
+		t.Fatalf("ClassLen(0) = %d != 8", n)
+	}
+	// Class 0 is served 3 times as class 1, and each class is in order.
+	var classes []int
+	last := []testElement1{-1, -1}
+	for i := 0; i < 8; i++ {
+		x, class, ok := fq.Dequeue()
+		if !ok {
+			t.Fatal("Dequeue failed!")
+		}
+		v := *x.(*testElement1)
+		if v < last[class] {
+			t.Errorf("Class %d: dequeue %d after %d", class, v, last[class])
+		}
+		last[class] = v
+		classes = append(classes, class)
+	}
+	want := []int{0, 0, 0, 1, 0, 0, 0, 1}
+	for i := range want {
+		if classes[i] != want[i] {
+			t.Fatalf("Classes: %v, want %v", classes, want)
+		}
+	}
+	if err = fq.SetWeight(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	stats := fq.Stats()
+	if stats[0].Dequeued != 6 || stats[1].Dequeued != 2 ||
+		stats[0].Len != 2 || stats[1].Weight != 2 {
+		t.Errorf("Wrong stats: %+v", stats)
+	}
+	// Class 0 has 2 items left, then class 1 takes all the turns.
+	for fq.Len() > 0 {
+		fq.Dequeue()
+	}
+	if _, _, ok := fq.Dequeue(); ok {
+		t.Fatal("Dequeue from empty queue succeeded!")
+	}
+	stats = fq.Stats()
+	if stats[0].Dequeued != 8 || stats[1].Dequeued != 8 {
+		t.Errorf("Wrong stats: %+v", stats)
+	}
+	if err = fq.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFairQueueDequeueWait(t *testing.T) {
+	_, _, err := NewFairQueue([]int{1}, false, false).DequeueWait(
+		context.Background())
+	if !errors.Is(err, gocontainer.ErrNotSync) {
+		t.Errorf("err(%v) is not ErrNotSync", err)
+	}
+
+	fq := NewFairQueue([]int{1, 1}, false, true)
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if _, _, err = fq.DequeueWait(ctx); !errors.Is(err,
+		context.DeadlineExceeded) {
+		t.Fatalf("err(%v) is not DeadlineExceeded", err)
+	}
+
+	x := testElement1(1)
+	go func() {
+		time.Sleep(time.Millisecond)
+		fq.Enqueue(1, &x)
+	}()
+	y, class, err := fq.DequeueWait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y != &x || class != 1 {
+		t.Errorf("DequeueWait = %v, %d, want %v, 1", y, class, &x)
+	}
+}
@@ -0,0 +1,106 @@
+package pqueue
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+// An item of HandlePriorityQueue, carrying an opaque value
+// and a separate priority.
+// The value doesn't take part in the order,
+// so it needn't implement gocontainer.Comparable.
+type Handle struct {
+	// Its x is the priority. It tracks the index and the owner of the handle.
+	item gocontainer.IndexedComparableItem
+
+	value     interface{}
+	valueLock sync.RWMutex
+
+	// Set by the queue holding the handle, and accessed with its lock held.
+	enqueuedAt time.Time
+	key        gocontainer.Comparable // Sort key if not nil, for aging.
+}
+
+func NewHandle(value interface{}, priority gocontainer.Comparable) *Handle {
+	h := &Handle{value: value}
+	h.item.Set(priority)
+	return h
+}
+
+func (h *Handle) Value() interface{} {
+	if h == nil {
+		return nil
+	}
+	h.valueLock.RLock()
+	defer h.valueLock.RUnlock()
+	return h.value
+}
+
+func (h *Handle) Priority() gocontainer.Comparable {
+	if h == nil {
+		return nil
+	}
+	return h.item.Get()
+}
+
+// Return the number of times the handle has been put into a queue.
+func (h *Handle) Generation() uint64 {
+	if h == nil {
+		return 0
+	}
+	return h.item.Generation()
+}
+
+func (h *Handle) Index() int {
+	if h == nil {
+		return 0
+	}
+	return h.item.Index()
+}
+
+// This method should be called by other container.
+// Do NOT call it directly.
+func (h *Handle) UpdateIndex(idx int) {
+	h.item.UpdateIndex(idx)
+}
+
+// Compare the priorities of the handles,
+// or their effective priorities in a queue with aging.
+func (h *Handle) Less(another interface{}) bool {
+	a := another.(*Handle)
+	return a != nil && (h == nil || h.sortKey().Less(a.sortKey()))
+}
+
+func (h *Handle) String() string {
+	return fmt.Sprintf("%v (priority: %v)", h.Value(), h.Priority())
+}
+
+func (h *Handle) sortKey() gocontainer.Comparable {
+	if h.key != nil {
+		return h.key
+	}
+	return h.item.Get()
+}
+
+func (h *Handle) setValue(value interface{}) {
+	h.valueLock.Lock()
+	defer h.valueLock.Unlock()
+	h.value = value
+}
+
+// Priority of float64 type, for handles.
+type Float64Priority float64
+
+func (p Float64Priority) Less(another interface{}) bool {
+	return p < another.(Float64Priority)
+}
+
+// Priority of int64 type, for handles.
+type Int64Priority int64
+
+func (p Int64Priority) Less(another interface{}) bool {
+	return p < another.(Int64Priority)
+}
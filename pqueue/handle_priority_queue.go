@@ -0,0 +1,184 @@
+package pqueue
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+	iheap "github.com/donyori/gocontainer/internal/heap"
+)
+
+// Same as PriorityQueueEx, but the items are handles
+// whose values and priorities are separate.
+// Changing the priority of a handle fixes the queue,
+// while changing its value doesn't.
+type HandlePriorityQueue struct {
+	basePriorityQueue
+	aging *agingState // nil if no aging.
+}
+
+func NewHandlePriorityQueue(capacity int, isTopMax, isSync bool) *HandlePriorityQueue {
+	pq, err := NewHandlePriorityQueueE(capacity, isTopMax, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return pq
+}
+
+// Same as NewHandlePriorityQueue, but return an error instead of panic.
+func NewHandlePriorityQueueE(capacity int, isTopMax, isSync bool) (
+	*HandlePriorityQueue, error) {
+	return NewHandlePriorityQueueWithAgingE(capacity, isTopMax, isSync, nil)
+}
+
+// Same as NewHandlePriorityQueue, with priority aging.
+// aging can be nil for no aging.
+func NewHandlePriorityQueueWithAging(capacity int, isTopMax, isSync bool,
+	aging *Aging) *HandlePriorityQueue {
+	pq, err := NewHandlePriorityQueueWithAgingE(capacity, isTopMax, isSync,
+		aging)
+	if err != nil {
+		panic(err)
+	}
+	return pq
+}
+
+// Same as NewHandlePriorityQueueWithAging,
+// but return an error instead of panic.
+func NewHandlePriorityQueueWithAgingE(capacity int, isTopMax, isSync bool,
+	aging *Aging) (*HandlePriorityQueue, error) {
+	if capacity < 0 {
+		return nil, newNegativeCapacityError(capacity)
+	}
+	pq := new(HandlePriorityQueue)
+	if aging != nil {
+		pq.aging = newAgingState(aging, isTopMax)
+	}
+	pq.setKey = pq.setHandleKey
+	if isSync {
+		pq.lock = new(sync.RWMutex)
+	}
+	// Not necessary to lock during init.
+	if isTopMax {
+		pq.h = iheap.NewMaxHeap(capacity, true)
+	} else {
+		pq.h = iheap.NewMinHeap(capacity, true)
+	}
+	return pq, nil
+}
+
+func (pq *HandlePriorityQueue) Top() *Handle {
+	if pq == nil {
+		return nil
+	}
+	pq.refreshIfDue()
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	h, _ := pq.h.Top().(*Handle) // nil if empty
+	return h
+}
+
+func (pq *HandlePriorityQueue) Enqueue(h *Handle) {
+	if err := pq.TryEnqueue(h); err != nil {
+		panic(err)
+	}
+}
+
+// Same as Enqueue, but return an error instead of panic.
+// The queue is not modified if it returns an error.
+// A handle can be in only one queue at a time, so it returns an error
+// wrapping gocontainer.ErrAlreadyOwned if h is already in a queue.
+func (pq *HandlePriorityQueue) TryEnqueue(h *Handle) error {
+	if h == nil || h.Priority() == nil {
+		return gocontainer.ErrNilItem
+	}
+	return pq.enqueueIndexed(h, nil)
+}
+
+// Create a handle with value and priority, and enqueue it.
+func (pq *HandlePriorityQueue) EnqueueValue(value interface{},
+	priority gocontainer.Comparable) *Handle {
+	h := NewHandle(value, priority)
+	pq.Enqueue(h)
+	return h
+}
+
+func (pq *HandlePriorityQueue) Dequeue() (h *Handle, ok bool) {
+	if pq == nil {
+		return // nil, false
+	}
+	pq.refreshIfDue()
+	x, ok := pq.dequeueIndexed()
+	if ok {
+		h = x.(*Handle)
+	}
+	return
+}
+
+// Set the priority of h to p, and fix the queue.
+// Return false if h is not in the queue.
+func (pq *HandlePriorityQueue) UpdatePriority(h *Handle,
+	p gocontainer.Comparable) (ok bool) {
+	err := pq.TryUpdatePriority(h, p)
+	if errors.Is(err, gocontainer.ErrForeignItem) {
+		return false
+	} else if err != nil {
+		panic(err)
+	}
+	return true
+}
+
+// Same as UpdatePriority, but return an error instead of panic or false.
+func (pq *HandlePriorityQueue) TryUpdatePriority(h *Handle,
+	p gocontainer.Comparable) error {
+	if pq == nil || h == nil {
+		return gocontainer.ErrForeignItem
+	}
+	return pq.updateIndexed(h, 0, p)
+}
+
+// Set the value of h to v, without fixing the queue.
+// Return false if h is not in the queue.
+func (pq *HandlePriorityQueue) UpdateValue(h *Handle, v interface{}) (
+	ok bool) {
+	if pq == nil || h == nil {
+		return false
+	}
+	// Read lock is enough, because the heap is not modified.
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	if _, ok = pq.indexOf(h, 0); ok {
+		h.setValue(v)
+	}
+	return
+}
+
+func (pq *HandlePriorityQueue) Remove(h *Handle) (ok bool) {
+	return pq.TryRemove(h) == nil
+}
+
+// Same as Remove, but return an error wrapping gocontainer.ErrForeignItem
+// instead of false if h is not in the queue.
+func (pq *HandlePriorityQueue) TryRemove(h *Handle) error {
+	if pq == nil || h == nil {
+		return gocontainer.ErrForeignItem
+	}
+	return pq.removeIndexed(h, 0)
+}
+
+func (pq *HandlePriorityQueue) Scan(f func(h *Handle) (doesStop bool)) {
+	if pq == nil || f == nil {
+		return
+	}
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	pq.h.Scan(func(x gocontainer.Comparable) bool {
+		return f(x.(*Handle))
+	})
+}
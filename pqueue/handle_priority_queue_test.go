@@ -0,0 +1,78 @@
+package pqueue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+type testTask struct {
+	name string
+}
+
+func TestHandlePriorityQueue(t *testing.T) {
+	pq := NewHandlePriorityQueue(0, true, true)
+	pq.SetDebug(true)
+	priorities := []Float64Priority{3, 0, 9, -4, 3.5, -5, 8}
+	handles := make([]*Handle, len(priorities))
+	for i, p := range priorities {
+		handles[i] = pq.EnqueueValue(&testTask{name: string(rune('a' + i))}, p)
+	}
+	t.Log("\n" + pq.DebugString())
+	if h := pq.Top(); h != handles[2] {
+		t.Fatalf("Top(%v) != %v", h, handles[2])
+	}
+
+	// Update the value without moving the handle.
+	idx := handles[3].Index()
+	task := &testTask{name: "new"}
+	if !pq.UpdateValue(handles[3], task) {
+		t.Fatal("UpdateValue failed!")
+	}
+	if handles[3].Value() != task || handles[3].Index() != idx {
+		t.Fatal("UpdateValue didn't set the value or moved the handle.")
+	}
+	// Move the lowest handle to the top.
+	if !pq.UpdatePriority(handles[5], Float64Priority(10)) {
+		t.Fatal("UpdatePriority failed!")
+	}
+	if h := pq.Top(); h != handles[5] {
+		t.Fatalf("Top(%v) != %v", h, handles[5])
+	}
+	err := pq.TryUpdatePriority(handles[5], Int64Priority(1))
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	if p := handles[5].Priority(); p != Float64Priority(10) {
+		t.Fatalf("Failed update didn't restore the priority, got %v", p)
+	}
+
+	if !pq.Remove(handles[0]) {
+		t.Fatal("Remove failed!")
+	}
+	if pq.UpdateValue(handles[0], task) || pq.UpdatePriority(handles[0],
+		Float64Priority(0)) {
+		t.Fatal("Update a removed handle succeeded!")
+	}
+	other := NewHandlePriorityQueue(0, false, false)
+	if err = other.TryEnqueue(handles[1]); !errors.Is(err,
+		gocontainer.ErrAlreadyOwned) {
+		t.Fatalf("err(%v) is not ErrAlreadyOwned", err)
+	}
+
+	last := Float64Priority(100)
+	for pq.Len() > 0 {
+		h, ok := pq.Dequeue()
+		if !ok {
+			t.Fatal("Dequeue failed!")
+		}
+		p := h.Priority().(Float64Priority)
+		if last < p {
+			t.Fatalf("Dequeue %v after %v", p, last)
+		}
+		last = p
+		t.Logf("Dequeue %v", h)
+	}
+	other.Enqueue(handles[1])
+}
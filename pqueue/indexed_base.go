@@ -0,0 +1,178 @@
+package pqueue
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	iheap "github.com/donyori/gocontainer/internal/heap"
+)
+
+// Operations shared by PriorityQueueEx and HandlePriorityQueue,
+// whose items track their index and owner
+// by a gocontainer.IndexedComparableItem.
+// The owner token is the *basePriorityQueue, as used by releaseAll.
+
+// Return the IndexedComparableItem tracking the index and the owner of x,
+// or nil if x is not an item of PriorityQueueEx or HandlePriorityQueue.
+func trackerOf(x gocontainer.Comparable) *gocontainer.IndexedComparableItem {
+	switch v := x.(type) {
+	case *gocontainer.IndexedComparableItem:
+		return v
+	case *Handle:
+		if v != nil {
+			return &v.item
+		}
+	}
+	return nil
+}
+
+// onPush is optional, and called with the lock held after x is pushed.
+func (pq *basePriorityQueue) enqueueIndexed(x gocontainer.Comparable,
+	onPush func()) error {
+	var wait time.Duration
+	if pq.lock != nil {
+		wait = pq.writeLock()
+		defer pq.lock.Unlock()
+	}
+	item := trackerOf(x)
+	if !item.Acquire(pq) {
+		pq.notify(gocontainer.EventReject, x, wait,
+			gocontainer.ErrAlreadyOwned)
+		return gocontainer.ErrAlreadyOwned
+	}
+	if pq.setKey != nil {
+		if err := pq.setKey(x, true); err != nil {
+			item.Release(pq)
+			pq.notify(gocontainer.EventReject, x, wait, err)
+			return err
+		}
+	}
+	if err := iheap.TryPush(pq.h, x); err != nil {
+		item.Release(pq)
+		pq.notify(gocontainer.EventReject, x, wait, err)
+		return err
+	}
+	if onPush != nil {
+		onPush()
+	}
+	pq.mutated()
+	pq.notify(gocontainer.EventEnqueue, x, wait, nil)
+	return nil
+}
+
+func (pq *basePriorityQueue) dequeueIndexed() (
+	x gocontainer.Comparable, ok bool) {
+	var wait time.Duration
+	if pq.lock != nil {
+		wait = pq.writeLock()
+		defer pq.lock.Unlock()
+	}
+	if pq.h.Len() <= 0 { // Do NOT call pq.Len(), which will dead lock!
+		return // nil, false
+	}
+	x = heap.Pop(pq.h).(gocontainer.Comparable)
+	pq.leave(x)
+	pq.mutated()
+	pq.notify(gocontainer.EventDequeue, x, wait, nil)
+	return x, true
+}
+
+// Set the priority of x to newP, which is stored in the tracker of x,
+// and fix the heap.
+// If gen is not 0, x must be in the queue since its generation gen.
+func (pq *basePriorityQueue) updateIndexed(x gocontainer.Comparable,
+	gen uint64, newP gocontainer.Comparable) error {
+	if newP == nil {
+		return gocontainer.ErrNilItem
+	}
+	var wait time.Duration
+	if pq.lock != nil {
+		wait = pq.writeLock()
+		defer pq.lock.Unlock()
+	}
+	idx, ok := pq.indexOf(x, gen)
+	if !ok {
+		return gocontainer.ErrForeignItem
+	}
+	item := trackerOf(x)
+	oldP := item.Get()
+	item.Set(newP)
+	if pq.setKey != nil {
+		if err := pq.setKey(x, false); err != nil {
+			item.Set(oldP)
+			pq.setKey(x, false)
+			return err
+		}
+	}
+	if r := tryFix(pq.h, idx); r != nil {
+		// Restore the old priority, which was comparable with other items.
+		item.Set(oldP)
+		if pq.setKey != nil {
+			pq.setKey(x, false)
+		}
+		heap.Fix(pq.h, item.Index())
+		return fmt.Errorf("%w: %v", gocontainer.ErrIncomparable, r)
+	}
+	pq.mutated()
+	pq.notify(gocontainer.EventUpdate, x, wait, nil)
+	return nil
+}
+
+// If gen is not 0, x must be in the queue since its generation gen.
+func (pq *basePriorityQueue) removeIndexed(x gocontainer.Comparable,
+	gen uint64) error {
+	var wait time.Duration
+	if pq.lock != nil {
+		wait = pq.writeLock()
+		defer pq.lock.Unlock()
+	}
+	idx, ok := pq.indexOf(x, gen)
+	if !ok {
+		return gocontainer.ErrForeignItem
+	}
+	pq.removeAt(idx)
+	pq.mutated()
+	pq.notify(gocontainer.EventRemove, x, wait, nil)
+	return nil
+}
+
+// Remove the item at idx. Call it with the lock held.
+func (pq *basePriorityQueue) removeAt(idx int) gocontainer.Comparable {
+	x := heap.Remove(pq.h, idx).(gocontainer.Comparable)
+	pq.leave(x)
+	return x
+}
+
+// Call it with the lock held, after x is popped or removed from the heap.
+func (pq *basePriorityQueue) leave(x gocontainer.Comparable) {
+	trackerOf(x).Release(pq)
+	if pq.onLeave != nil {
+		pq.onLeave(x)
+	}
+}
+
+// Return the position of x in the queue,
+// and ok = false if x belongs to another queue,
+// or gen is not 0 and x has been put into a queue again since
+// its generation gen, i.e. the caller holds a stale handle.
+// Call it with the lock held.
+func (pq *basePriorityQueue) indexOf(x gocontainer.Comparable, gen uint64) (
+	idx int, ok bool) {
+	item := trackerOf(x)
+	if item.Owner() != pq || gen != 0 && item.Generation() != gen {
+		return -1, false
+	}
+	idx = item.Index()
+	return idx, pq.h.Get(idx) == x
+}
+
+// Call container/heap.Fix, and return the recovered panic value if any.
+func tryFix(h heap.Interface, i int) (r interface{}) {
+	defer func() {
+		r = recover()
+	}()
+	heap.Fix(h, i)
+	return nil
+}
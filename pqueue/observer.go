@@ -0,0 +1,50 @@
+package pqueue
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Set the observer receiving the events of the queue.
+// Set it to nil to stop observing.
+// See gocontainer.Observer for details.
+func (pq *basePriorityQueue) SetObserver(obs gocontainer.Observer) {
+	if pq.lock != nil {
+		pq.lock.Lock()
+		defer pq.lock.Unlock()
+	}
+	pq.obs = obs
+	var hasObs int32
+	if obs != nil {
+		hasObs = 1
+	}
+	atomic.StoreInt32(&pq.hasObs, hasObs)
+}
+
+// Lock the queue for writing, and return the time spent waiting for
+// the lock if there is an observer. pq.lock must not be nil.
+func (pq *basePriorityQueue) writeLock() (wait time.Duration) {
+	if atomic.LoadInt32(&pq.hasObs) == 0 {
+		pq.lock.Lock()
+		return 0
+	}
+	start := time.Now()
+	pq.lock.Lock()
+	return time.Since(start)
+}
+
+// Report an event to the observer if any. Call it with the lock held.
+func (pq *basePriorityQueue) notify(kind gocontainer.EventKind,
+	x gocontainer.Comparable, wait time.Duration, err error) {
+	if pq.obs != nil {
+		pq.obs.Observe(gocontainer.Event{
+			Kind:     kind,
+			Item:     x,
+			Len:      pq.h.Len(),
+			LockWait: wait,
+			Err:      err,
+		})
+	}
+}
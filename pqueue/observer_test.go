@@ -0,0 +1,127 @@
+package pqueue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+type testObserver struct {
+	events []gocontainer.Event
+}
+
+func (to *testObserver) Observe(e gocontainer.Event) {
+	to.events = append(to.events, e)
+}
+
+func (to *testObserver) kinds() []gocontainer.EventKind {
+	kinds := make([]gocontainer.EventKind, len(to.events))
+	for i, e := range to.events {
+		kinds[i] = e.Kind
+	}
+	return kinds
+}
+
+func TestPriorityQueueObserver(t *testing.T) {
+	obs := new(testObserver)
+	pq := NewPriorityQueue(0, true, true)
+	pq.SetObserver(obs)
+	inputs := []testElement1{3, 0, 9}
+	for i := range inputs {
+		pq.Enqueue(&inputs[i])
+	}
+	var incomparable testElement2
+	if err := pq.TryEnqueue(&incomparable); err == nil {
+		t.Fatal("No error for an incomparable item")
+	}
+	pq.Dequeue()
+	pq.RemoveIf(func(x gocontainer.Comparable) bool {
+		return *x.(*testElement1) == 0
+	})
+	pq.Clear()
+	pq.SetObserver(nil)
+	pq.Enqueue(&inputs[0])
+
+	want := []gocontainer.EventKind{
+		gocontainer.EventEnqueue, gocontainer.EventEnqueue,
+		gocontainer.EventEnqueue, gocontainer.EventReject,
+		gocontainer.EventDequeue, gocontainer.EventRemove,
+		gocontainer.EventRemove,
+	}
+	kinds := obs.kinds()
+	if len(kinds) != len(want) {
+		t.Fatalf("Events: %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Fatalf("Events: %v, want %v", kinds, want)
+		}
+	}
+	if e := obs.events[3]; !errors.Is(e.Err, gocontainer.ErrIncomparable) {
+		t.Errorf("Reject error(%v) is not ErrIncomparable", e.Err)
+	}
+	if e := obs.events[4]; e.Item != &inputs[2] || e.Len != 2 {
+		t.Errorf("Dequeue event: %+v", e)
+	}
+	if e := obs.events[6]; e.Item != &inputs[0] || e.Len != 0 {
+		t.Errorf("Clear event: %+v", e)
+	}
+}
+
+func TestPriorityQueueExObserver(t *testing.T) {
+	obs := new(testObserver)
+	pq := NewPriorityQueueEx(0, false, false)
+	pq.SetObserver(obs)
+	a := gocontainer.NewIndexedComparableItem(new(testElement1))
+	pq.Enqueue(a)
+	if err := pq.TryEnqueue(a); !errors.Is(err, gocontainer.ErrAlreadyOwned) {
+		t.Fatalf("err(%v) is not ErrAlreadyOwned", err)
+	}
+	p := testElement1(2)
+	pq.Update(a, &p)
+	pq.Remove(a)
+	want := []gocontainer.EventKind{
+		gocontainer.EventEnqueue, gocontainer.EventReject,
+		gocontainer.EventUpdate, gocontainer.EventRemove,
+	}
+	kinds := obs.kinds()
+	if len(kinds) != len(want) {
+		t.Fatalf("Events: %v, want %v", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] || obs.events[i].Item != a {
+			t.Fatalf("Events: %v, want %v", kinds, want)
+		}
+	}
+}
+
+func BenchmarkPriorityQueueWithoutObserver(b *testing.B) {
+	benchmarkPriorityQueueObserver(b, nil)
+}
+
+func BenchmarkPriorityQueueWithObserver(b *testing.B) {
+	benchmarkPriorityQueueObserver(b, gocontainer.Observer(nopObserver{}))
+}
+
+type nopObserver struct{}
+
+func (nopObserver) Observe(gocontainer.Event) {}
+
+func benchmarkPriorityQueueObserver(b *testing.B, obs gocontainer.Observer) {
+	pq := NewPriorityQueue(0, false, true)
+	pq.SetObserver(obs)
+	xs := make([]testElement1, 1024)
+	for i := range xs {
+		xs[i] = testElement1(i * 7919 % 1024)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pq.Enqueue(&xs[i%len(xs)])
+		if pq.Len() >= len(xs) {
+			for pq.Len() > 0 {
+				pq.Dequeue()
+			}
+		}
+	}
+}
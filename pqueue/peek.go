@@ -0,0 +1,116 @@
+package pqueue
+
+import "github.com/donyori/gocontainer"
+
+// Return the n best items, in the order they would be dequeued,
+// or all the items if n > pq.Len(). It doesn't modify the queue.
+// O(n*log(n)) time, regardless of the length of the queue.
+func (pq *PriorityQueue) PeekN(n int) []gocontainer.Comparable {
+	if pq == nil {
+		return nil
+	}
+	return pq.peekN(n)
+}
+
+// Return the k-th best item, which is the k-th item to be dequeued,
+// and ok = false if k is out of [1, pq.Len()]. Kth(1) is the top.
+// It doesn't modify the queue. O(k*log(k)) time.
+func (pq *PriorityQueue) Kth(k int) (x gocontainer.Comparable, ok bool) {
+	if pq == nil {
+		return
+	}
+	return pq.kth(k)
+}
+
+// Return the n best items, in the order they would be dequeued,
+// or all the items if n > pq.Len(). It doesn't modify the queue.
+// O(n*log(n)) time, regardless of the length of the queue.
+func (pq *PriorityQueueEx) PeekN(n int) []*gocontainer.IndexedComparableItem {
+	if pq == nil {
+		return nil
+	}
+	pq.purgeExpired()
+	pq.refreshIfDue()
+	xs := pq.peekN(n)
+	if xs == nil {
+		return nil
+	}
+	icis := make([]*gocontainer.IndexedComparableItem, len(xs))
+	for i, x := range xs {
+		icis[i] = x.(*gocontainer.IndexedComparableItem)
+	}
+	return icis
+}
+
+// Return the k-th best item, which is the k-th item to be dequeued,
+// and ok = false if k is out of [1, pq.Len()]. Kth(1) is the top.
+// It doesn't modify the queue. O(k*log(k)) time.
+func (pq *PriorityQueueEx) Kth(k int) (
+	ici *gocontainer.IndexedComparableItem, ok bool) {
+	if pq == nil {
+		return
+	}
+	pq.purgeExpired()
+	pq.refreshIfDue()
+	x, ok := pq.kth(k)
+	if ok {
+		ici = x.(*gocontainer.IndexedComparableItem)
+	}
+	return
+}
+
+// Return the n best items, in the order they would be dequeued,
+// or all the items if n > pq.Len(). It doesn't modify the queue.
+// O(n*log(n)) time, regardless of the length of the queue.
+func (pq *HandlePriorityQueue) PeekN(n int) []*Handle {
+	if pq == nil {
+		return nil
+	}
+	pq.refreshIfDue()
+	xs := pq.peekN(n)
+	if xs == nil {
+		return nil
+	}
+	hs := make([]*Handle, len(xs))
+	for i, x := range xs {
+		hs[i] = x.(*Handle)
+	}
+	return hs
+}
+
+// Return the k-th best item, which is the k-th item to be dequeued,
+// and ok = false if k is out of [1, pq.Len()]. Kth(1) is the top.
+// It doesn't modify the queue. O(k*log(k)) time.
+func (pq *HandlePriorityQueue) Kth(k int) (h *Handle, ok bool) {
+	if pq == nil {
+		return
+	}
+	pq.refreshIfDue()
+	x, ok := pq.kth(k)
+	if ok {
+		h = x.(*Handle)
+	}
+	return
+}
+
+func (pq *basePriorityQueue) peekN(n int) []gocontainer.Comparable {
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	return pq.h.PeekN(n)
+}
+
+func (pq *basePriorityQueue) kth(k int) (x gocontainer.Comparable, ok bool) {
+	if pq.lock != nil {
+		pq.lock.RLock()
+		defer pq.lock.RUnlock()
+	}
+	if k <= 0 || k > pq.h.Len() {
+		return // nil, false
+	}
+	if k == 1 {
+		return pq.h.Top(), true
+	}
+	return pq.h.PeekN(k)[k-1], true
+}
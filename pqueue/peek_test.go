@@ -0,0 +1,67 @@
+package pqueue
+
+import (
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestPeekNAndKth(t *testing.T) {
+	inputs := []testElement1{3, 0, 9, -4, 3, -5, 8, 1, 6}
+	want := []testElement1{9, 8, 6, 3, 3, 1, 0, -4, -5}
+	pq := NewPriorityQueue(0, true, true)
+	pqx := NewPriorityQueueEx(0, true, true)
+	for i := range inputs {
+		pq.Enqueue(&inputs[i])
+		pqx.Enqueue(gocontainer.NewIndexedComparableItem(&inputs[i]))
+	}
+	xs := pq.PeekN(4)
+	icis := pqx.PeekN(20)
+	if len(xs) != 4 || len(icis) != len(want) {
+		t.Fatalf("len(PeekN(4)) = %d, len(PeekN(20)) = %d", len(xs), len(icis))
+	}
+	for i := range xs {
+		if *xs[i].(*testElement1) != want[i] {
+			t.Errorf("PriorityQueue.PeekN(4)[%d] = %v != %v",
+				i, *xs[i].(*testElement1), want[i])
+		}
+	}
+	for i := range icis {
+		if *icis[i].Get().(*testElement1) != want[i] {
+			t.Errorf("PriorityQueueEx.PeekN(20)[%d] = %v != %v",
+				i, *icis[i].Get().(*testElement1), want[i])
+		}
+	}
+	for k := 0; k <= len(want)+1; k++ {
+		x, ok := pq.Kth(k)
+		ici, okx := pqx.Kth(k)
+		if k < 1 || k > len(want) {
+			if ok || okx {
+				t.Errorf("Kth(%d) is ok", k)
+			}
+			continue
+		}
+		if !ok || *x.(*testElement1) != want[k-1] {
+			t.Errorf("PriorityQueue.Kth(%d) = %v, %t", k, x, ok)
+		}
+		if !okx || *ici.Get().(*testElement1) != want[k-1] {
+			t.Errorf("PriorityQueueEx.Kth(%d) = %v, %t", k, ici, okx)
+		}
+	}
+	if pq.Len() != len(want) || pqx.Len() != len(want) {
+		t.Error("The queues are modified.")
+	}
+
+	hpq := NewHandlePriorityQueue(0, false, false)
+	for i := range inputs {
+		hpq.EnqueueValue(i, &inputs[i])
+	}
+	hs := hpq.PeekN(3)
+	if len(hs) != 3 || hs[0].Value() != 5 || hs[1].Value() != 3 ||
+		hs[2].Value() != 1 {
+		t.Errorf("HandlePriorityQueue.PeekN(3): %v", hs)
+	}
+	if h, ok := hpq.Kth(9); !ok || h.Value() != 2 {
+		t.Errorf("HandlePriorityQueue.Kth(9) = %v, %t", h, ok)
+	}
+}
@@ -0,0 +1,81 @@
+package pqueue
+
+import (
+	"context"
+
+	"github.com/donyori/gocontainer"
+)
+
+type PipeOptions struct {
+	// Maximum number of items held by the pipe.
+	// When it is reached, the pipe stops receiving from the input channel
+	// until an item is sent out. Non-positive for unbounded.
+	BufferSize int
+
+	// Send the greatest item first if true, otherwise the smallest one.
+	IsTopMax bool
+
+	// Called with each item left in the pipe when it is canceled,
+	// or when it stops due to an error. Optional.
+	OnDiscard func(x gocontainer.Comparable)
+}
+
+// Receive items from in, and send them to out in the order of priority.
+// The items in flight are reordered, i.e., when out is not ready,
+// the received items are held in a priority queue,
+// and the top of them is sent first when out becomes ready.
+//
+// It blocks until in is closed and all the items held are sent to out,
+// or until ctx is canceled, in which case the items held are discarded.
+// It closes out before return.
+// It returns ctx.Err() if canceled, or an error if an incomparable item
+// is received, otherwise nil.
+//
+// opts can be nil, for an unbounded min-first pipe.
+func Pipe(ctx context.Context, in <-chan gocontainer.Comparable,
+	out chan<- gocontainer.Comparable, opts *PipeOptions) (err error) {
+	defer close(out)
+	if opts == nil {
+		opts = new(PipeOptions)
+	}
+	pq := NewPriorityQueue(0, opts.IsTopMax, false)
+	defer func() {
+		if err == nil || opts.OnDiscard == nil {
+			return
+		}
+		for x, ok := pq.Dequeue(); ok; x, ok = pq.Dequeue() {
+			opts.OnDiscard(x)
+		}
+	}()
+	done := ctx.Done()
+	for in != nil || pq.Len() > 0 {
+		// Disable a case by setting its channel to nil.
+		recv := in
+		if opts.BufferSize > 0 && pq.Len() >= opts.BufferSize {
+			recv = nil
+		}
+		var send chan<- gocontainer.Comparable
+		top := pq.Top()
+		if pq.Len() > 0 {
+			send = out
+		}
+		select {
+		case <-done:
+			return ctx.Err()
+		case x, ok := <-recv:
+			if !ok {
+				in = nil // Drain the items held.
+				continue
+			}
+			if err = pq.TryEnqueue(x); err != nil {
+				if opts.OnDiscard != nil {
+					opts.OnDiscard(x)
+				}
+				return err
+			}
+		case send <- top:
+			pq.Dequeue()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestPipe(t *testing.T) {
+	inputs := []testElement1{3, 0, 9, -4, 3, -5, 8}
+	in := make(chan gocontainer.Comparable)
+	out := make(chan gocontainer.Comparable)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- Pipe(context.Background(), in, out,
+			&PipeOptions{BufferSize: len(inputs), IsTopMax: true})
+	}()
+	// in is unbuffered, so all inputs are held by the pipe
+	// before out is ready, and they must come out in order.
+	for i := range inputs {
+		in <- &inputs[i]
+	}
+	close(in)
+	var n int
+	last := testElement1(100)
+	for x := range out {
+		v := *x.(*testElement1)
+		if last < v {
+			t.Errorf("Receive %d after %d", v, last)
+		}
+		last = v
+		n++
+	}
+	if n != len(inputs) {
+		t.Errorf("Receive %d items, want %d", n, len(inputs))
+	}
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPipeCancel(t *testing.T) {
+	inputs := []testElement1{3, 0, 9}
+	in := make(chan gocontainer.Comparable)
+	out := make(chan gocontainer.Comparable)
+	ctx, cancel := context.WithCancel(context.Background())
+	discarded := make(chan gocontainer.Comparable, len(inputs))
+	errC := make(chan error, 1)
+	go func() {
+		errC <- Pipe(ctx, in, out, &PipeOptions{
+			BufferSize: 2,
+			OnDiscard: func(x gocontainer.Comparable) {
+				discarded <- x
+			},
+		})
+	}()
+	// Nobody receives from out, so the pipe holds the first two items
+	// and then stops receiving.
+	in <- &inputs[0]
+	in <- &inputs[1]
+	select {
+	case in <- &inputs[2]:
+		t.Fatal("Pipe receives more items than BufferSize.")
+	default:
+	}
+	cancel()
+	if err := <-errC; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err(%v) is not context.Canceled", err)
+	}
+	if n := len(discarded); n != 2 {
+		t.Errorf("%d items discarded, want 2", n)
+	}
+	if _, ok := <-out; ok {
+		t.Error("out is not closed.")
+	}
+}
+
+func TestPipeIncomparable(t *testing.T) {
+	in := make(chan gocontainer.Comparable, 2)
+	out := make(chan gocontainer.Comparable)
+	x := testElement1(1)
+	var y testElement2 = 1.2
+	in <- &x
+	in <- &y
+	close(in)
+	var n int
+	err := Pipe(context.Background(), in, out, &PipeOptions{
+		BufferSize: 2,
+		OnDiscard: func(x gocontainer.Comparable) {
+			n++
+		},
+	})
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	if n != 2 {
+		t.Errorf("%d items discarded, want 2", n)
+	}
+}
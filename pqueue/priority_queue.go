@@ -2,12 +2,11 @@ package pqueue
 
 import (
 	"container/heap"
-	"fmt"
 	"sync"
+	"time"
 
 	"github.com/donyori/gocontainer"
 	iheap "github.com/donyori/gocontainer/internal/heap"
-	"github.com/donyori/gorecover"
 )
 
 type PriorityQueue struct {
@@ -15,8 +14,18 @@ type PriorityQueue struct {
 }
 
 func NewPriorityQueue(capacity int, isTopMax, isSync bool) *PriorityQueue {
+	pq, err := NewPriorityQueueE(capacity, isTopMax, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return pq
+}
+
+// Same as NewPriorityQueue, but return an error instead of panic.
+func NewPriorityQueueE(capacity int, isTopMax, isSync bool) (
+	*PriorityQueue, error) {
 	if capacity < 0 {
-		panic(fmt.Errorf("gocontainer: capacity(%d) is negative", capacity))
+		return nil, newNegativeCapacityError(capacity)
 	}
 	pq := new(PriorityQueue)
 	if isSync {
@@ -28,7 +37,7 @@ func NewPriorityQueue(capacity int, isTopMax, isSync bool) *PriorityQueue {
 	} else {
 		pq.h = iheap.NewMinHeap(capacity, false)
 	}
-	return pq
+	return pq, nil
 }
 
 func (pq *PriorityQueue) Top() gocontainer.Comparable {
@@ -43,59 +52,43 @@ func (pq *PriorityQueue) Top() gocontainer.Comparable {
 }
 
 func (pq *PriorityQueue) Enqueue(x gocontainer.Comparable) {
+	if err := pq.TryEnqueue(x); err != nil {
+		panic(err)
+	}
+}
+
+// Same as Enqueue, but return an error instead of panic.
+// The queue is not modified if it returns an error.
+func (pq *PriorityQueue) TryEnqueue(x gocontainer.Comparable) error {
+	var wait time.Duration
 	if pq.lock != nil {
-		pq.lock.Lock()
+		wait = pq.writeLock()
 		defer pq.lock.Unlock()
 	}
-	nBefore := pq.h.Len() // Do NOT call pq.Len(), which will dead lock!
-	pErr := gorecover.Recover(func() {
-		heap.Push(pq.h, x)
-	})
-	if pErr == nil {
-		return
-	}
-	// Try to recover the queue and then panic.
-	defer panic(pErr)
-	nAfter := pq.h.Len() // Do NOT call pq.Len(), which will dead lock!
-	if nAfter == nBefore {
-		// Push didn't modified the queue.
-		// Just return.
-		return
-	}
-	// Try to recover the queue:
-	var idx int
-	// Traverse the whole queue to find wrong item:
-	for idx = 0; idx < nAfter; idx++ {
-		if pq.h.Get(idx) == x {
-			break
-		}
-	}
-	if idx >= nAfter {
-		panic(fmt.Errorf("%v; cannot recover the queue, "+
-			"because cannot find the wrong item to remove", pErr))
-	}
-	pErr2 := gorecover.Recover(func() {
-		heap.Remove(pq.h, idx)
-	})
-	if pErr2 != nil {
-		panic(fmt.Errorf("%v; error occurs when recover the queue: %v",
-			pErr, pErr2))
+	if err := iheap.TryPush(pq.h, x); err != nil {
+		pq.notify(gocontainer.EventReject, x, wait, err)
+		return err
 	}
-	// Succeed to recover the queue.
+	pq.mutated()
+	pq.notify(gocontainer.EventEnqueue, x, wait, nil)
+	return nil
 }
 
 func (pq *PriorityQueue) Dequeue() (x gocontainer.Comparable, ok bool) {
 	if pq == nil {
 		return // nil, false
 	}
+	var wait time.Duration
 	if pq.lock != nil {
-		pq.lock.Lock()
+		wait = pq.writeLock()
 		defer pq.lock.Unlock()
 	}
 	if pq.h.Len() <= 0 { // Do NOT call pq.Len(), which will dead lock!
 		return // nil, false
 	}
 	x = heap.Pop(pq.h).(gocontainer.Comparable)
+	pq.mutated()
+	pq.notify(gocontainer.EventDequeue, x, wait, nil)
 	ok = true
 	return
 }
@@ -1,22 +1,79 @@
 package pqueue
 
 import (
-	"container/heap"
-	"fmt"
+	"errors"
 	"sync"
 
 	"github.com/donyori/gocontainer"
 	iheap "github.com/donyori/gocontainer/internal/heap"
-	"github.com/donyori/gorecover"
 )
 
 type PriorityQueueEx struct {
 	basePriorityQueue
+	exp *pqExpiry // nil if expiry is not enabled.
+
+	aging     *agingState // nil if no aging.
+	agingKeys map[*gocontainer.IndexedComparableItem]*agingKey
 }
 
 func NewPriorityQueueEx(capacity int, isTopMax, isSync bool) *PriorityQueueEx {
+	pq, err := NewPriorityQueueExE(capacity, isTopMax, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return pq
+}
+
+// Same as NewPriorityQueueEx, but return an error instead of panic.
+func NewPriorityQueueExE(capacity int, isTopMax, isSync bool) (
+	*PriorityQueueEx, error) {
+	return newPriorityQueueEx(capacity, isTopMax, isSync, nil, nil)
+}
+
+// Same as NewPriorityQueueEx, with priority aging.
+// aging can be nil for no aging.
+func NewPriorityQueueExWithAging(capacity int, isTopMax, isSync bool,
+	aging *Aging) *PriorityQueueEx {
+	pq, err := NewPriorityQueueExWithAgingE(capacity, isTopMax, isSync, aging)
+	if err != nil {
+		panic(err)
+	}
+	return pq
+}
+
+// Same as NewPriorityQueueExWithAging, but return an error instead of panic.
+func NewPriorityQueueExWithAgingE(capacity int, isTopMax, isSync bool,
+	aging *Aging) (*PriorityQueueEx, error) {
+	return newPriorityQueueEx(capacity, isTopMax, isSync, nil, aging)
+}
+
+// Same as NewPriorityQueueEx, with per-item expiry enabled.
+// opts can be nil for the default options.
+// Call StopSweeper to stop the background sweeper if opts sets one.
+func NewPriorityQueueExWithExpiry(capacity int, isTopMax, isSync bool,
+	opts *ExpiryOptions) *PriorityQueueEx {
+	pq, err := NewPriorityQueueExWithExpiryE(capacity, isTopMax, isSync, opts)
+	if err != nil {
+		panic(err)
+	}
+	return pq
+}
+
+// Same as NewPriorityQueueExWithExpiry, but return an error instead of panic.
+func NewPriorityQueueExWithExpiryE(capacity int, isTopMax, isSync bool,
+	opts *ExpiryOptions) (*PriorityQueueEx, error) {
+	if opts == nil {
+		opts = new(ExpiryOptions)
+	}
+	return newPriorityQueueEx(capacity, isTopMax, isSync, opts, nil)
+}
+
+// Create a PriorityQueueEx. Expiry is enabled if opts is not nil,
+// and aging is enabled if aging is not nil.
+func newPriorityQueueEx(capacity int, isTopMax, isSync bool,
+	opts *ExpiryOptions, aging *Aging) (*PriorityQueueEx, error) {
 	if capacity < 0 {
-		panic(fmt.Errorf("gocontainer: capacity(%d) is negative", capacity))
+		return nil, newNegativeCapacityError(capacity)
 	}
 	pq := new(PriorityQueueEx)
 	if isSync {
@@ -28,63 +85,56 @@ func NewPriorityQueueEx(capacity int, isTopMax, isSync bool) *PriorityQueueEx {
 	} else {
 		pq.h = iheap.NewMinHeap(capacity, true)
 	}
-	return pq
+	if aging != nil {
+		pq.enableAging(aging, isTopMax)
+	}
+	if opts != nil {
+		if err := pq.enableExpiry(opts, isSync); err != nil {
+			return nil, err
+		}
+	}
+	pq.onLeave = pq.leaveItem
+	return pq, nil
+}
+
+func (pq *PriorityQueueEx) Len() int {
+	if pq == nil {
+		return 0
+	}
+	pq.purgeExpired()
+	return pq.basePriorityQueue.Len()
 }
 
 func (pq *PriorityQueueEx) Top() *gocontainer.IndexedComparableItem {
 	if pq == nil {
 		return nil
 	}
+	pq.purgeExpired()
+	pq.refreshIfDue()
 	if pq.lock != nil {
 		pq.lock.RLock()
 		defer pq.lock.RUnlock()
 	}
-	x := pq.h.Top()
-	return x.(*gocontainer.IndexedComparableItem)
+	ici, _ := pq.h.Top().(*gocontainer.IndexedComparableItem) // nil if empty
+	return ici
 }
 
 func (pq *PriorityQueueEx) Enqueue(ici *gocontainer.IndexedComparableItem) {
-	if pq.lock != nil {
-		pq.lock.Lock()
-		defer pq.lock.Unlock()
-	}
-	nBefore := pq.h.Len() // Do NOT call pq.Len(), which will dead lock!
-	pErr := gorecover.Recover(func() {
-		heap.Push(pq.h, ici)
-	})
-	if pErr == nil {
-		return
-	}
-	// Try to recover the queue and then panic.
-	defer panic(pErr)
-	nAfter := pq.h.Len() // Do NOT call pq.Len(), which will dead lock!
-	if nAfter == nBefore {
-		// Push didn't modified the queue.
-		// Just return.
-		return
+	if err := pq.TryEnqueue(ici); err != nil {
+		panic(err)
 	}
-	// Try to recover the queue:
-	idx := ici.Index()
-	if pq.h.Get(idx) != ici {
-		// Traverse the whole queue to find wrong item:
-		for idx = 0; idx < nAfter; idx++ {
-			if pq.h.Get(idx) == ici {
-				break
-			}
-		}
-		if idx >= nAfter {
-			panic(fmt.Errorf("%v; cannot recover the queue, "+
-				"because cannot find the wrong item to remove", pErr))
-		}
-	}
-	pErr2 := gorecover.Recover(func() {
-		heap.Remove(pq.h, idx)
-	})
-	if pErr2 != nil {
-		panic(fmt.Errorf("%v; error occurs when recover the queue: %v",
-			pErr, pErr2))
-	}
-	// Succeed to recover the queue.
+}
+
+// Same as Enqueue, but return an error instead of panic.
+// The queue is not modified if it returns an error.
+// An item can be in only one queue at a time, so it returns an error
+// wrapping gocontainer.ErrAlreadyOwned if ici is already in a queue.
+func (pq *PriorityQueueEx) TryEnqueue(
+	ici *gocontainer.IndexedComparableItem) error {
+	if ici == nil {
+		return gocontainer.ErrNilItem
+	}
+	return pq.enqueueIndexed(ici, nil)
 }
 
 func (pq *PriorityQueueEx) Dequeue() (
@@ -92,48 +142,78 @@ func (pq *PriorityQueueEx) Dequeue() (
 	if pq == nil {
 		return // nil, false
 	}
-	if pq.lock != nil {
-		pq.lock.Lock()
-		defer pq.lock.Unlock()
+	pq.purgeExpired()
+	pq.refreshIfDue()
+	x, ok := pq.dequeueIndexed()
+	if ok {
+		ici = x.(*gocontainer.IndexedComparableItem)
 	}
-	if pq.h.Len() <= 0 { // Do NOT call pq.Len(), which will dead lock!
-		return // nil, false
-	}
-	ici = heap.Pop(pq.h).(*gocontainer.IndexedComparableItem)
-	ok = true
 	return
 }
 
 func (pq *PriorityQueueEx) Update(ici *gocontainer.IndexedComparableItem,
 	newX gocontainer.Comparable) (ok bool) {
-	if pq.lock != nil {
-		pq.lock.Lock()
-		defer pq.lock.Unlock()
-	}
-	idx := ici.Index()
-	if pq.h.Get(idx) != ici {
+	err := pq.TryUpdate(ici, newX)
+	if errors.Is(err, gocontainer.ErrForeignItem) {
 		return false
+	} else if err != nil {
+		panic(err)
 	}
-	ici.Set(newX)
-	heap.Fix(pq.h, idx)
 	return true
 }
 
+// Same as Update, but return an error instead of panic or false.
+// It returns an error wrapping gocontainer.ErrForeignItem
+// if ici is not in the queue.
+func (pq *PriorityQueueEx) TryUpdate(ici *gocontainer.IndexedComparableItem,
+	newX gocontainer.Comparable) error {
+	if pq == nil || ici == nil {
+		return gocontainer.ErrForeignItem
+	}
+	pq.purgeExpired()
+	return pq.updateIndexed(ici, 0, newX)
+}
+
+// Same as TryUpdate, but also return an error wrapping
+// gocontainer.ErrForeignItem if ici has been dequeued and enqueued again
+// since gen, which is the value of ici.Generation() after it was enqueued.
+// Use it to avoid acting on an item through a stale handle.
+func (pq *PriorityQueueEx) TryUpdateGeneration(
+	ici *gocontainer.IndexedComparableItem, gen uint64,
+	newX gocontainer.Comparable) error {
+	if pq == nil || ici == nil {
+		return gocontainer.ErrForeignItem
+	}
+	pq.purgeExpired()
+	return pq.updateIndexed(ici, gen, newX)
+}
+
 func (pq *PriorityQueueEx) Remove(ici *gocontainer.IndexedComparableItem) (
 	ok bool) {
-	if pq == nil {
-		return false
-	}
-	if pq.lock != nil {
-		pq.lock.Lock()
-		defer pq.lock.Unlock()
-	}
-	idx := ici.Index()
-	if pq.h.Get(idx) != ici {
-		return false
-	}
-	heap.Remove(pq.h, idx)
-	return true
+	return pq.TryRemove(ici) == nil
+}
+
+// Same as Remove, but return an error wrapping gocontainer.ErrForeignItem
+// instead of false if ici is not in the queue.
+func (pq *PriorityQueueEx) TryRemove(
+	ici *gocontainer.IndexedComparableItem) error {
+	if pq == nil || ici == nil {
+		return gocontainer.ErrForeignItem
+	}
+	pq.purgeExpired()
+	return pq.removeIndexed(ici, 0)
+}
+
+// Same as TryRemove, but also return an error wrapping
+// gocontainer.ErrForeignItem if ici has been dequeued and enqueued again
+// since gen, which is the value of ici.Generation() after it was enqueued.
+func (pq *PriorityQueueEx) TryRemoveGeneration(
+	ici *gocontainer.IndexedComparableItem, gen uint64) error {
+	if pq == nil || ici == nil {
+		return gocontainer.ErrForeignItem
+	}
+	pq.purgeExpired()
+	return pq.removeIndexed(ici, gen)
 }
 
 func (pq *PriorityQueueEx) Scan(
@@ -141,6 +221,7 @@ func (pq *PriorityQueueEx) Scan(
 	if pq == nil || f == nil {
 		return
 	}
+	pq.purgeExpired()
 	if pq.lock != nil {
 		pq.lock.RLock()
 		defer pq.lock.RUnlock()
@@ -149,3 +230,18 @@ func (pq *PriorityQueueEx) Scan(
 		return f(x.(*gocontainer.IndexedComparableItem))
 	})
 }
+
+// Implementation of basePriorityQueue.onLeave for PriorityQueueEx,
+// dropping the deadline and the aging key of x.
+func (pq *PriorityQueueEx) leaveItem(x gocontainer.Comparable) {
+	ici := x.(*gocontainer.IndexedComparableItem)
+	if pq.exp != nil {
+		if e, ok := pq.exp.entries[ici]; ok {
+			pq.exp.index.Remove(e)
+			delete(pq.exp.entries, ici)
+		}
+	}
+	if pq.agingKeys != nil {
+		delete(pq.agingKeys, ici)
+	}
+}
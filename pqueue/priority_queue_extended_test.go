@@ -1,6 +1,7 @@
 package pqueue
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/donyori/gocontainer"
@@ -100,3 +101,142 @@ func TestPriorityQueueEx(t *testing.T) {
 		t.Fatalf("Index(%d) != 0", idx)
 	}
 }
+
+func TestPriorityQueueExErrors(t *testing.T) {
+	_, err := NewPriorityQueueExE(-2, false, false)
+	if !errors.Is(err, gocontainer.ErrNegativeCapacity) {
+		t.Fatalf("err(%v) is not ErrNegativeCapacity", err)
+	}
+	pq, err := NewPriorityQueueExE(0, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ici := pq.Top(); ici != nil {
+		t.Fatalf("Top of empty queue is %v", ici)
+	}
+	inputsData := []testElement1{3, 0, 9}
+	inputs := make([]*gocontainer.IndexedComparableItem, len(inputsData))
+	for i := range inputsData {
+		inputs[i] = gocontainer.NewIndexedComparableItem(&inputsData[i])
+		if err = pq.TryEnqueue(inputs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wrongInput testElement2 = 1.2
+	err = pq.TryEnqueue(gocontainer.NewIndexedComparableItem(&wrongInput))
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	if err = pq.TryEnqueue(nil); !errors.Is(err, gocontainer.ErrNilItem) {
+		t.Fatalf("err(%v) is not ErrNilItem", err)
+	}
+	err = pq.TryUpdate(inputs[1], &wrongInput)
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	if x := inputs[1].Get(); x != &inputsData[1] {
+		t.Fatal("Failed update didn't restore the old value.")
+	}
+	if err = pq.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	foreign := gocontainer.NewIndexedComparableItem(&inputsData[0])
+	err = pq.TryUpdate(foreign, &inputsData[0])
+	if !errors.Is(err, gocontainer.ErrForeignItem) {
+		t.Fatalf("err(%v) is not ErrForeignItem", err)
+	}
+	if err = pq.TryRemove(foreign); !errors.Is(err,
+		gocontainer.ErrForeignItem) {
+		t.Fatalf("err(%v) is not ErrForeignItem", err)
+	}
+	if err = pq.TryRemove(inputs[2]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPriorityQueueExOwnership(t *testing.T) {
+	pq1 := NewPriorityQueueEx(0, false, true)
+	pq2 := NewPriorityQueueEx(0, false, true)
+	inputsData := []testElement1{3, 0, 9}
+	inputs := make([]*gocontainer.IndexedComparableItem, len(inputsData))
+	for i := range inputsData {
+		inputs[i] = gocontainer.NewIndexedComparableItem(&inputsData[i])
+		pq1.Enqueue(inputs[i])
+	}
+	ici := inputs[0]
+	gen := ici.Generation()
+	if err := pq2.TryEnqueue(ici); !errors.Is(err,
+		gocontainer.ErrAlreadyOwned) {
+		t.Fatalf("err(%v) is not ErrAlreadyOwned", err)
+	}
+	if err := pq1.TryEnqueue(ici); !errors.Is(err,
+		gocontainer.ErrAlreadyOwned) {
+		t.Fatalf("err(%v) is not ErrAlreadyOwned", err)
+	}
+	if pq2.Len() != 0 || pq1.Len() != len(inputs) {
+		t.Fatal("Rejected enqueue modified the queue.")
+	}
+	if pq2.Remove(ici) {
+		t.Fatal("Remove a foreign item succeeded!")
+	}
+
+	// Reuse the item after it leaves the queue.
+	if !pq1.Remove(ici) {
+		t.Fatal("Remove failed!")
+	}
+	if ici.Owner() != nil {
+		t.Fatal("Owner is not cleared after Remove.")
+	}
+	pq2.Enqueue(ici)
+	if g := ici.Generation(); g == gen {
+		t.Fatalf("Generation(%d) doesn't change after reused.", g)
+	}
+	// A stale handle for pq1 must not hit another item at the same index.
+	if pq1.Update(ici, &inputsData[1]) {
+		t.Fatal("Update a stale item succeeded!")
+	}
+	if err := pq1.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Items are released by Clear.
+	pq1.Clear()
+	for i := 1; i < len(inputs); i++ {
+		if inputs[i].Owner() != nil {
+			t.Fatalf("Item %d is not released by Clear.", i)
+		}
+	}
+	x, ok := pq2.Dequeue()
+	if !ok || x != ici || ici.Owner() != nil {
+		t.Fatal("Dequeue didn't release the item.")
+	}
+	pq1.Enqueue(ici)
+
+	// A handle kept from before Dequeue is stale after ici is enqueued again.
+	staleGen := ici.Generation()
+	if x, ok = pq1.Dequeue(); !ok || x != ici {
+		t.Fatal("Dequeue failed!")
+	}
+	pq1.Enqueue(ici)
+	gen = ici.Generation()
+	if err := pq1.TryUpdateGeneration(ici, staleGen,
+		&inputsData[2]); !errors.Is(err, gocontainer.ErrForeignItem) {
+		t.Fatalf("err(%v) is not ErrForeignItem", err)
+	}
+	if err := pq1.TryRemoveGeneration(ici, staleGen); !errors.Is(err,
+		gocontainer.ErrForeignItem) {
+		t.Fatalf("err(%v) is not ErrForeignItem", err)
+	}
+	if ici.Get() != &inputsData[0] || pq1.Len() != 1 {
+		t.Fatal("A stale handle modified the queue.")
+	}
+	if err := pq1.TryUpdateGeneration(ici, gen, &inputsData[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := pq1.TryRemoveGeneration(ici, gen); err != nil {
+		t.Fatal(err)
+	}
+	if pq1.Len() != 0 {
+		t.Fatal("TryRemoveGeneration didn't remove the item.")
+	}
+}
@@ -1,8 +1,10 @@
 package pqueue
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/donyori/gocontainer"
 	"github.com/donyori/gorecover"
 )
 
@@ -71,3 +73,38 @@ func TestPriorityQueue(t *testing.T) {
 	}
 	pq.Enqueue(x)
 }
+
+func TestPriorityQueueErrors(t *testing.T) {
+	_, err := NewPriorityQueueE(-2, false, false)
+	if !errors.Is(err, gocontainer.ErrNegativeCapacity) {
+		t.Fatalf("err(%v) is not ErrNegativeCapacity", err)
+	}
+	pq, err := NewPriorityQueueE(2, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []testElement1{3, 0, 9}
+	for i := range inputs {
+		if err = pq.TryEnqueue(&inputs[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wrongInput testElement2 = 1.2
+	err = pq.TryEnqueue(&wrongInput)
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	if n := pq.Len(); n != len(inputs) {
+		t.Fatalf("Len(%d) != %d after failed enqueue", n, len(inputs))
+	}
+	if err = pq.TryEnqueue(nil); !errors.Is(err, gocontainer.ErrNilItem) {
+		t.Fatalf("err(%v) is not ErrNilItem", err)
+	}
+	err = pq.TryReset(-1)
+	if !errors.Is(err, gocontainer.ErrNegativeCapacity) {
+		t.Fatalf("err(%v) is not ErrNegativeCapacity", err)
+	}
+	if err = pq.TryReset(1); err != nil {
+		t.Fatal(err)
+	}
+}
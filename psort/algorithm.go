@@ -0,0 +1,152 @@
+package psort
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// Ranges not longer than this are finished by insertion sort in introSelect.
+const insertionSortThreshold = 12
+
+func insertionSort(data sort.Interface, lo, hi int) {
+	for i := lo + 1; i < hi; i++ {
+		for j := i; j > lo && data.Less(j, j-1); j-- {
+			data.Swap(j, j-1)
+		}
+	}
+}
+
+// Sift the item at root down the max-heap stored in data[lo:hi].
+// root is relative to lo.
+func siftDown(data sort.Interface, lo, root, hi int) {
+	n := hi - lo
+	for {
+		child := 2*root + 1
+		if child >= n {
+			return
+		}
+		if child+1 < n && data.Less(lo+child, lo+child+1) {
+			child++
+		}
+		if !data.Less(lo+root, lo+child) {
+			return
+		}
+		data.Swap(lo+root, lo+child)
+		root = child
+	}
+}
+
+func heapify(data sort.Interface, lo, hi int) {
+	for i := (hi-lo)/2 - 1; i >= 0; i-- {
+		siftDown(data, lo, i, hi)
+	}
+}
+
+func heapSort(data sort.Interface, lo, hi int) {
+	heapify(data, lo, hi)
+	for i := hi - 1; i > lo; i-- {
+		data.Swap(lo, i)
+		siftDown(data, lo, 0, i)
+	}
+}
+
+// Move the k smallest items of data[lo:hi] to data[lo:lo+k] in ascending order.
+// It keeps a max-heap of size k, so it takes O(n*log(k)) time.
+func partialSort(data sort.Interface, lo, hi, k int) {
+	if k <= 0 {
+		return
+	}
+	mid := lo + k
+	heapify(data, lo, mid)
+	for i := mid; i < hi; i++ {
+		if data.Less(i, lo) {
+			data.Swap(i, lo)
+			siftDown(data, lo, 0, mid)
+		}
+	}
+	for i := mid - 1; i > lo; i-- {
+		data.Swap(lo, i)
+		siftDown(data, lo, 0, i)
+	}
+}
+
+// Move the median of data[a], data[b] and data[c] to data[b].
+func medianOfThree(data sort.Interface, a, b, c int) {
+	if data.Less(b, a) {
+		data.Swap(a, b)
+	}
+	if data.Less(c, b) {
+		data.Swap(b, c)
+		if data.Less(b, a) {
+			data.Swap(a, b)
+		}
+	}
+}
+
+// Partition data[lo:hi] (hi - lo >= 2) around the pivot data[lo],
+// and return the final position of the pivot.
+// Items equal to the pivot are distributed to both sides.
+func partition(data sort.Interface, lo, hi int) int {
+	i, j := lo+1, hi-1
+	for {
+		for i <= j && data.Less(i, lo) {
+			i++
+		}
+		for i <= j && data.Less(lo, j) {
+			j--
+		}
+		if i >= j {
+			break
+		}
+		data.Swap(i, j)
+		i++
+		j--
+	}
+	data.Swap(lo, j)
+	return j
+}
+
+// Introselect: quickselect with median-of-three pivots,
+// falling back to a heap-based selection when the recursion goes too deep,
+// so the worst case is O(n*log(n)) instead of O(n^2).
+func introSelect(data sort.Interface, lo, hi, n int) {
+	depth := 2 * bits.Len(uint(hi-lo))
+	for hi-lo > insertionSortThreshold {
+		if depth == 0 {
+			partialSort(data, lo, hi, n-lo+1)
+			return
+		}
+		depth--
+		mid := lo + (hi-lo)/2
+		medianOfThree(data, lo, mid, hi-1)
+		data.Swap(lo, mid)
+		p := partition(data, lo, hi)
+		if p == n {
+			return
+		} else if n < p {
+			hi = p
+		} else {
+			lo = p + 1
+		}
+	}
+	insertionSort(data, lo, hi)
+}
+
+// Adapter used by the *Slice functions, in the same way as sort.Slice.
+type lessSwap struct {
+	n    int
+	less func(i, j int) bool
+	swap func(i, j int)
+}
+
+func (ls *lessSwap) Len() int {
+	return ls.n
+}
+
+func (ls *lessSwap) Less(i, j int) bool {
+	return ls.less(i, j)
+}
+
+func (ls *lessSwap) Swap(i, j int) {
+	ls.swap(i, j)
+}
@@ -0,0 +1,151 @@
+package psort
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Ascending order defined by gocontainer.Comparable.Less.
+type comparableSlice []gocontainer.Comparable
+
+func (cs comparableSlice) Len() int {
+	return len(cs)
+}
+
+func (cs comparableSlice) Less(i, j int) bool {
+	return cs[i].Less(cs[j])
+}
+
+func (cs comparableSlice) Swap(i, j int) {
+	cs[i], cs[j] = cs[j], cs[i]
+}
+
+func newLessSwap(slice interface{}, less func(i, j int) bool) *lessSwap {
+	return &lessSwap{
+		n:    reflect.ValueOf(slice).Len(),
+		less: less,
+		swap: reflect.Swapper(slice),
+	}
+}
+
+// Return min(k, n). Panic if k is negative.
+func checkK(k, n int) int {
+	if k < 0 {
+		panic(fmt.Errorf("gocontainer: k(%d) is negative", k))
+	}
+	if k > n {
+		return n
+	}
+	return k
+}
+
+func checkN(n, length int) {
+	if n < 0 || n >= length {
+		panic(fmt.Errorf("gocontainer: n(%d) is out of range [0, %d)",
+			n, length))
+	}
+}
+
+// Sort xs in ascending order in place.
+// Not stable. O(n*log(n)) time, O(1) extra space.
+func HeapSort(xs []gocontainer.Comparable) {
+	heapSort(comparableSlice(xs), 0, len(xs))
+}
+
+// Rearrange xs so that xs[:k] are the k smallest items in ascending order.
+// The order of the rest items is unspecified.
+// If k > len(xs), it sorts the whole slice. It panics if k is negative.
+// O(n*log(k)) time, O(1) extra space.
+func PartialSort(xs []gocontainer.Comparable, k int) {
+	k = checkK(k, len(xs))
+	partialSort(comparableSlice(xs), 0, len(xs), k)
+}
+
+// Rearrange xs so that xs[n] is the item which would be in that position
+// if xs were sorted, no item in xs[:n] is greater than xs[n],
+// and no item in xs[n+1:] is less than xs[n].
+// It panics if n is out of range.
+// O(n) average time, O(n*log(n)) worst time, O(1) extra space.
+func NthElement(xs []gocontainer.Comparable, n int) {
+	checkN(n, len(xs))
+	introSelect(comparableSlice(xs), 0, len(xs), n)
+}
+
+// Rearrange xs so that xs[:k] are the k greatest items, and return xs[:k].
+// The items in the result are in no particular order,
+// call HeapSort on it if the order matters.
+// If k > len(xs), it returns xs. It panics if k is negative.
+// O(n) average time.
+func TopK(xs []gocontainer.Comparable, k int) []gocontainer.Comparable {
+	k = checkK(k, len(xs))
+	if k > 0 && k < len(xs) {
+		introSelect(sort.Reverse(comparableSlice(xs)), 0, len(xs), k-1)
+	}
+	return xs[:k]
+}
+
+// Rearrange xs so that xs[:k] are the k smallest items, and return xs[:k].
+// The items in the result are in no particular order.
+// If k > len(xs), it returns xs. It panics if k is negative.
+// O(n) average time.
+func BottomK(xs []gocontainer.Comparable, k int) []gocontainer.Comparable {
+	k = checkK(k, len(xs))
+	if k > 0 && k < len(xs) {
+		introSelect(comparableSlice(xs), 0, len(xs), k-1)
+	}
+	return xs[:k]
+}
+
+// Same as HeapSort, but for any slice with a less function,
+// in the same way as sort.Slice. It panics if slice is not a slice.
+func HeapSortSlice(slice interface{}, less func(i, j int) bool) {
+	ls := newLessSwap(slice, less)
+	heapSort(ls, 0, ls.n)
+}
+
+// Same as PartialSort, but for any slice with a less function,
+// in the same way as sort.Slice. It panics if slice is not a slice.
+func PartialSortSlice(slice interface{}, k int, less func(i, j int) bool) {
+	ls := newLessSwap(slice, less)
+	k = checkK(k, ls.n)
+	partialSort(ls, 0, ls.n, k)
+}
+
+// Same as NthElement, but for any slice with a less function,
+// in the same way as sort.Slice. It panics if slice is not a slice.
+func NthElementSlice(slice interface{}, n int, less func(i, j int) bool) {
+	ls := newLessSwap(slice, less)
+	checkN(n, ls.n)
+	introSelect(ls, 0, ls.n, n)
+}
+
+// Same as TopK, but for any slice with a less function,
+// in the same way as sort.Slice. It panics if slice is not a slice.
+// The k greatest items are moved to the first k positions of the slice,
+// and the result is slice[:k], of the same type as slice.
+func TopKSlice(slice interface{}, k int,
+	less func(i, j int) bool) interface{} {
+	ls := newLessSwap(slice, less)
+	k = checkK(k, ls.n)
+	if k > 0 && k < ls.n {
+		introSelect(sort.Reverse(ls), 0, ls.n, k-1)
+	}
+	return reflect.ValueOf(slice).Slice(0, k).Interface()
+}
+
+// Same as BottomK, but for any slice with a less function,
+// in the same way as sort.Slice. It panics if slice is not a slice.
+// The k smallest items are moved to the first k positions of the slice,
+// and the result is slice[:k], of the same type as slice.
+func BottomKSlice(slice interface{}, k int,
+	less func(i, j int) bool) interface{} {
+	ls := newLessSwap(slice, less)
+	k = checkK(k, ls.n)
+	if k > 0 && k < ls.n {
+		introSelect(ls, 0, ls.n, k-1)
+	}
+	return reflect.ValueOf(slice).Slice(0, k).Interface()
+}
@@ -0,0 +1,246 @@
+package psort
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gorecover"
+)
+
+var testSizes = []int{0, 1, 2, 5, 13, 100, 1000}
+
+// Random inputs, inputs with many duplicates, and sorted inputs.
+func makeTestInputs(n int) [][]int {
+	r := rand.New(rand.NewSource(int64(n)))
+	random := make([]int, n)
+	dup := make([]int, n)
+	asc := make([]int, n)
+	desc := make([]int, n)
+	for i := 0; i < n; i++ {
+		random[i] = r.Intn(n*2 + 1)
+		dup[i] = r.Intn(3)
+		asc[i] = i
+		desc[i] = n - i
+	}
+	return [][]int{random, dup, asc, desc}
+}
+
+func toComparables(a []int) []gocontainer.Comparable {
+	xs := make([]gocontainer.Comparable, len(a))
+	for i := range a {
+		xs[i] = testElement(a[i])
+	}
+	return xs
+}
+
+func sortedCopy(a []int) []int {
+	s := make([]int, len(a))
+	copy(s, a)
+	sort.Ints(s)
+	return s
+}
+
+func TestHeapSort(t *testing.T) {
+	for _, n := range testSizes {
+		for _, a := range makeTestInputs(n) {
+			want := sortedCopy(a)
+			xs := toComparables(a)
+			HeapSort(xs)
+			for i := range xs {
+				if int(xs[i].(testElement)) != want[i] {
+					t.Fatalf("n = %d: xs[%d] = %v != %d", n, i, xs[i], want[i])
+				}
+			}
+			b := append([]int(nil), a...)
+			HeapSortSlice(b, func(i, j int) bool { return b[i] < b[j] })
+			for i := range b {
+				if b[i] != want[i] {
+					t.Fatalf("n = %d: b[%d] = %d != %d", n, i, b[i], want[i])
+				}
+			}
+		}
+	}
+}
+
+func TestPartialSort(t *testing.T) {
+	for _, n := range testSizes {
+		for _, a := range makeTestInputs(n) {
+			want := sortedCopy(a)
+			for _, k := range []int{0, 1, n / 3, n, n + 2} {
+				xs := toComparables(a)
+				PartialSort(xs, k)
+				b := append([]int(nil), a...)
+				PartialSortSlice(b, k, func(i, j int) bool { return b[i] < b[j] })
+				for i := 0; i < k && i < n; i++ {
+					if int(xs[i].(testElement)) != want[i] {
+						t.Fatalf("n = %d, k = %d: xs[%d] = %v != %d",
+							n, k, i, xs[i], want[i])
+					}
+					if b[i] != want[i] {
+						t.Fatalf("n = %d, k = %d: b[%d] = %d != %d",
+							n, k, i, b[i], want[i])
+					}
+				}
+			}
+		}
+	}
+	err := gorecover.Recover(func() {
+		PartialSort(toComparables([]int{1, 2}), -1)
+	})
+	if err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+}
+
+func TestNthElement(t *testing.T) {
+	for _, n := range testSizes {
+		if n == 0 {
+			continue
+		}
+		for _, a := range makeTestInputs(n) {
+			want := sortedCopy(a)
+			for _, m := range []int{0, n / 2, n - 1} {
+				xs := toComparables(a)
+				NthElement(xs, m)
+				checkNth(t, xs, m, want[m])
+				b := append([]int(nil), a...)
+				NthElementSlice(b, m, func(i, j int) bool { return b[i] < b[j] })
+				checkNth(t, toComparables(b), m, want[m])
+			}
+		}
+	}
+	err := gorecover.Recover(func() {
+		NthElement(toComparables([]int{1, 2}), 2)
+	})
+	if err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+}
+
+func checkNth(t *testing.T, xs []gocontainer.Comparable, n, want int) {
+	if int(xs[n].(testElement)) != want {
+		t.Fatalf("xs[%d] = %v != %d", n, xs[n], want)
+	}
+	for i := 0; i < n; i++ {
+		if xs[n].Less(xs[i]) {
+			t.Fatalf("xs[%d] = %v > xs[%d] = %v", i, xs[i], n, xs[n])
+		}
+	}
+	for i := n + 1; i < len(xs); i++ {
+		if xs[i].Less(xs[n]) {
+			t.Fatalf("xs[%d] = %v < xs[%d] = %v", i, xs[i], n, xs[n])
+		}
+	}
+}
+
+func TestTopKAndBottomK(t *testing.T) {
+	for _, n := range testSizes {
+		for _, a := range makeTestInputs(n) {
+			want := sortedCopy(a)
+			for _, k := range []int{0, 1, n / 3, n, n + 2} {
+				m := k
+				if m > n {
+					m = n
+				}
+				top := TopK(toComparables(a), k)
+				checkSameItems(t, top, want[n-m:])
+				bottom := BottomK(toComparables(a), k)
+				checkSameItems(t, bottom, want[:m])
+				b := append([]int(nil), a...)
+				r := TopKSlice(b, k, func(i, j int) bool { return b[i] < b[j] })
+				checkSameItems(t, toComparables(r.([]int)), want[n-m:])
+				r = BottomKSlice(b, k, func(i, j int) bool { return b[i] < b[j] })
+				checkSameItems(t, toComparables(r.([]int)), want[:m])
+			}
+		}
+	}
+}
+
+func checkSameItems(t *testing.T, xs []gocontainer.Comparable, want []int) {
+	if len(xs) != len(want) {
+		t.Fatalf("len(xs) = %d != %d", len(xs), len(want))
+	}
+	HeapSort(xs)
+	for i := range xs {
+		if int(xs[i].(testElement)) != want[i] {
+			t.Fatalf("got %v, want %v", xs, want)
+		}
+	}
+}
+
+const benchmarkSize = 100000
+
+func makeBenchmarkInput() []int {
+	r := rand.New(rand.NewSource(1))
+	a := make([]int, benchmarkSize)
+	for i := range a {
+		a[i] = r.Int()
+	}
+	return a
+}
+
+func BenchmarkSortSlice(b *testing.B) {
+	a := makeBenchmarkInput()
+	s := make([]int, len(a))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(s, a)
+		sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	}
+}
+
+func BenchmarkHeapSortSlice(b *testing.B) {
+	a := makeBenchmarkInput()
+	s := make([]int, len(a))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(s, a)
+		HeapSortSlice(s, func(i, j int) bool { return s[i] < s[j] })
+	}
+}
+
+func BenchmarkPartialSortSlice100(b *testing.B) {
+	a := makeBenchmarkInput()
+	s := make([]int, len(a))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(s, a)
+		PartialSortSlice(s, 100, func(i, j int) bool { return s[i] < s[j] })
+	}
+}
+
+func BenchmarkNthElementSlice(b *testing.B) {
+	a := makeBenchmarkInput()
+	s := make([]int, len(a))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(s, a)
+		NthElementSlice(s, len(s)/2, func(i, j int) bool { return s[i] < s[j] })
+	}
+}
+
+func BenchmarkTopKSlice100(b *testing.B) {
+	a := makeBenchmarkInput()
+	s := make([]int, len(a))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(s, a)
+		TopKSlice(s, 100, func(i, j int) bool { return s[i] < s[j] })
+	}
+}
+
+func BenchmarkPartialSortComparable100(b *testing.B) {
+	xs := toComparables(makeBenchmarkInput())
+	s := make([]gocontainer.Comparable, len(xs))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(s, xs)
+		PartialSort(s, 100)
+	}
+}
@@ -0,0 +1,8 @@
+package psort
+
+type testElement int
+
+func (te testElement) Less(another interface{}) bool {
+	a := another.(testElement)
+	return te < a
+}
@@ -0,0 +1,144 @@
+package radixtree
+
+import (
+	"math"
+	"sort"
+)
+
+type node struct {
+	label    string  // Label of the edge from the parent.
+	children []*node // Sorted by the first bytes of their labels.
+	e        *Entry  // The entry whose key ends here, or nil.
+
+	// The greatest score in the subtree, or -Inf if it has no entry.
+	maxScore float64
+}
+
+// Recompute maxScore from the entry and the children.
+func (n *node) update() {
+	n.maxScore = math.Inf(-1)
+	if n.e != nil {
+		n.maxScore = n.e.Score
+	}
+	for _, c := range n.children {
+		if c.maxScore > n.maxScore {
+			n.maxScore = c.maxScore
+		}
+	}
+}
+
+// Return the index of the child whose label starts with b,
+// or where it would be inserted, and the child or nil.
+func (n *node) child(b byte) (int, *node) {
+	i := sort.Search(len(n.children), func(i int) bool {
+		return n.children[i].label[0] >= b
+	})
+	if i < len(n.children) && n.children[i].label[0] == b {
+		return i, n.children[i]
+	}
+	return i, nil
+}
+
+func commonPrefixLen(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Set the entry of key, where rest is the part of key after n,
+// and return true if key is new.
+func insert(n *node, rest string, e *Entry) (isAdded bool) {
+	defer n.update()
+	if rest == "" {
+		isAdded = n.e == nil
+		n.e = e
+		return
+	}
+	i, c := n.child(rest[0])
+	if c == nil {
+		c = &node{label: rest, e: e, maxScore: e.Score}
+		n.children = append(n.children, nil)
+		copy(n.children[i+1:], n.children[i:])
+		n.children[i] = c
+		return true
+	}
+	l := commonPrefixLen(rest, c.label)
+	if l < len(c.label) {
+		// Split the edge.
+		mid := &node{label: c.label[:l], children: []*node{c}}
+		c.label = c.label[l:]
+		mid.update()
+		n.children[i] = mid
+		c = mid
+	}
+	return insert(c, rest[l:], e)
+}
+
+// Remove the entry of key, where rest is the part of key after n,
+// and return it, or nil if key is not found.
+// n is left to its parent to be removed or merged.
+func remove(n *node, rest string) (e *Entry) {
+	if rest == "" {
+		e = n.e
+		n.e = nil
+		n.update()
+		return
+	}
+	i, c := n.child(rest[0])
+	if c == nil || len(rest) < len(c.label) || rest[:len(c.label)] != c.label {
+		return nil
+	}
+	e = remove(c, rest[len(c.label):])
+	if e == nil {
+		return nil
+	}
+	if c.e == nil {
+		switch len(c.children) {
+		case 0:
+			n.children = append(n.children[:i], n.children[i+1:]...)
+		case 1:
+			gc := c.children[0]
+			gc.label = c.label + gc.label
+			n.children[i] = gc
+		}
+	}
+	n.update()
+	return e
+}
+
+// Return the node of key. If key ends in the middle of an edge,
+// return the node at the end of the edge.
+// path is the path of the node, which starts with key.
+func find(root *node, key string) (n *node, path string) {
+	n, rest := root, key
+	for rest != "" {
+		_, c := n.child(rest[0])
+		if c == nil {
+			return nil, ""
+		}
+		l := commonPrefixLen(rest, c.label)
+		if l == len(rest) {
+			return c, key + c.label[l:]
+		} else if l < len(c.label) {
+			return nil, ""
+		}
+		n, rest = c, rest[l:]
+	}
+	return n, key
+}
+
+// Call f with the entries in the subtree of n in ascending order of keys.
+// Return true if f asks to stop.
+func walk(n *node, f func(key string, value interface{}) (doesStop bool)) bool {
+	if n.e != nil && f(n.e.Key, n.e.Value) {
+		return true
+	}
+	for _, c := range n.children {
+		if walk(c, f) {
+			return true
+		}
+	}
+	return false
+}
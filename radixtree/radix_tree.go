@@ -0,0 +1,274 @@
+// Package radixtree provides a radix tree (compressed trie) over string keys,
+// with prefix queries and top-k completion by score.
+package radixtree
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+
+	iheap "github.com/donyori/gocontainer/internal/heap"
+)
+
+// A key in the tree, with its value and score.
+type Entry struct {
+	Key   string
+	Value interface{}
+	Score float64
+}
+
+// RadixTree maps string keys to values with scores.
+// Byte-slice keys and prefixes can be used by the *Bytes methods,
+// while the keys returned are strings.
+//
+// Each node is annotated with the greatest score in its subtree,
+// so that TopKWithPrefix only visits the nodes that may contain
+// the top k entries, instead of the whole subtree of the prefix.
+type RadixTree struct {
+	root *node
+	n    int
+	lock *sync.RWMutex
+}
+
+func NewRadixTree(isSync bool) *RadixTree {
+	t := &RadixTree{root: &node{maxScore: math.Inf(-1)}}
+	if isSync {
+		t.lock = new(sync.RWMutex)
+	}
+	return t
+}
+
+func (t *RadixTree) Len() int {
+	if t == nil {
+		return 0
+	}
+	if t.lock != nil {
+		t.lock.RLock()
+		defer t.lock.RUnlock()
+	}
+	return t.n
+}
+
+// Set the value and score of key, and return true if key is new.
+func (t *RadixTree) Insert(key string, value interface{},
+	score float64) (isAdded bool) {
+	if t.lock != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+	}
+	isAdded = insert(t.root, key, &Entry{key, value, score})
+	if isAdded {
+		t.n++
+	}
+	return
+}
+
+func (t *RadixTree) InsertBytes(key []byte, value interface{},
+	score float64) (isAdded bool) {
+	return t.Insert(string(key), value, score)
+}
+
+// Return the value and score of key. ok is false if key is not found.
+func (t *RadixTree) Get(key string) (value interface{}, score float64,
+	ok bool) {
+	if t == nil {
+		return
+	}
+	if t.lock != nil {
+		t.lock.RLock()
+		defer t.lock.RUnlock()
+	}
+	if n, path := find(t.root, key); n != nil && path == key && n.e != nil {
+		return n.e.Value, n.e.Score, true
+	}
+	return
+}
+
+func (t *RadixTree) GetBytes(key []byte) (value interface{}, score float64,
+	ok bool) {
+	return t.Get(string(key))
+}
+
+// Set the score of key, and return false if key is not found.
+func (t *RadixTree) SetScore(key string, score float64) bool {
+	if t == nil {
+		return false
+	}
+	if t.lock != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+	}
+	n, path := find(t.root, key)
+	if n == nil || path != key || n.e == nil {
+		return false
+	}
+	insert(t.root, key, &Entry{key, n.e.Value, score})
+	return true
+}
+
+// Delete key, and return its value. ok is false if key is not found.
+func (t *RadixTree) Delete(key string) (value interface{}, ok bool) {
+	if t == nil {
+		return
+	}
+	if t.lock != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+	}
+	e := remove(t.root, key)
+	if e == nil {
+		return
+	}
+	t.n--
+	return e.Value, true
+}
+
+func (t *RadixTree) DeleteBytes(key []byte) (value interface{}, ok bool) {
+	return t.Delete(string(key))
+}
+
+// Return the longest key that is a prefix of s, and its value.
+// ok is false if there is none.
+func (t *RadixTree) LongestPrefix(s string) (key string, value interface{},
+	ok bool) {
+	if t == nil {
+		return
+	}
+	if t.lock != nil {
+		t.lock.RLock()
+		defer t.lock.RUnlock()
+	}
+	var last *Entry
+	n, rest := t.root, s
+	for {
+		if n.e != nil {
+			last = n.e
+		}
+		if rest == "" {
+			break
+		}
+		_, c := n.child(rest[0])
+		if c == nil || len(rest) < len(c.label) ||
+			rest[:len(c.label)] != c.label {
+			break
+		}
+		n, rest = c, rest[len(c.label):]
+	}
+	if last == nil {
+		return
+	}
+	return last.Key, last.Value, true
+}
+
+func (t *RadixTree) LongestPrefixBytes(s []byte) (key string,
+	value interface{}, ok bool) {
+	return t.LongestPrefix(string(s))
+}
+
+// Call f with the keys starting with prefix and their values,
+// in ascending order of keys, until f returns true.
+//
+// Don't modify the tree in f.
+func (t *RadixTree) WalkPrefix(prefix string,
+	f func(key string, value interface{}) (doesStop bool)) {
+	if t == nil {
+		return
+	}
+	if t.lock != nil {
+		t.lock.RLock()
+		defer t.lock.RUnlock()
+	}
+	if n, _ := find(t.root, prefix); n != nil {
+		walk(n, f)
+	}
+}
+
+func (t *RadixTree) WalkPrefixBytes(prefix []byte,
+	f func(key string, value interface{}) (doesStop bool)) {
+	t.WalkPrefix(string(prefix), f)
+}
+
+// Call f with all the keys and their values, in ascending order of keys,
+// until f returns true.
+//
+// Don't modify the tree in f.
+func (t *RadixTree) Walk(f func(key string, value interface{}) (doesStop bool)) {
+	t.WalkPrefix("", f)
+}
+
+// Return the k entries with the greatest scores among the keys
+// starting with prefix, in descending order of scores,
+// and then in ascending order of keys.
+//
+// It is a best-first search over a max-heap of the nodes, keyed by
+// their greatest scores, so it only visits the nodes whose greatest
+// scores are in the top k, plus their children.
+func (t *RadixTree) TopKWithPrefix(prefix string, k int) []Entry {
+	if t == nil || k <= 0 {
+		return nil
+	}
+	if t.lock != nil {
+		t.lock.RLock()
+		defer t.lock.RUnlock()
+	}
+	n, path := find(t.root, prefix)
+	if n == nil || n.e == nil && len(n.children) == 0 {
+		return nil
+	}
+	h := iheap.NewMaxHeap(0, false)
+	heap.Push(h, &candidate{n: n, path: path, score: n.maxScore})
+	var top []Entry
+	for len(top) < k && h.Len() > 0 {
+		c := heap.Pop(h).(*candidate)
+		if c.n == nil {
+			top = append(top, *c.e)
+			continue
+		}
+		if c.n.e != nil {
+			heap.Push(h, &candidate{e: c.n.e, path: c.path, score: c.n.e.Score})
+		}
+		for _, child := range c.n.children {
+			heap.Push(h, &candidate{n: child, path: c.path + child.label,
+				score: child.maxScore})
+		}
+	}
+	return top
+}
+
+func (t *RadixTree) TopKWithPrefixBytes(prefix []byte, k int) []Entry {
+	return t.TopKWithPrefix(string(prefix), k)
+}
+
+func (t *RadixTree) Clear() {
+	if t == nil {
+		return
+	}
+	if t.lock != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+	}
+	t.root = &node{maxScore: math.Inf(-1)}
+	t.n = 0
+}
+
+// A node or an entry in the search of TopKWithPrefix.
+type candidate struct {
+	n     *node  // nil for an entry.
+	e     *Entry // nil for a node.
+	path  string // Path of the node, or key of the entry.
+	score float64
+}
+
+// Order by score, and then reversely by path, with entries before nodes,
+// as the keys in a node are not less than its path.
+// The order of the entries popped is then the order of the result.
+func (c *candidate) Less(another interface{}) bool {
+	a := another.(*candidate)
+	if c.score != a.score {
+		return c.score < a.score
+	}
+	if c.path != a.path {
+		return c.path > a.path
+	}
+	return c.n != nil && a.n == nil
+}
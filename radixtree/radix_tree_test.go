@@ -0,0 +1,209 @@
+package radixtree
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Check the compression, the order of children and the annotated
+// maxScore, and return the number of entries under n.
+func checkNode(n *node, path string, isRoot bool) (int, error) {
+	count := 0
+	maxScore := math.Inf(-1)
+	if n.e != nil {
+		if n.e.Key != path {
+			return 0, fmt.Errorf("key %q at path %q", n.e.Key, path)
+		}
+		count, maxScore = 1, n.e.Score
+	}
+	if !isRoot {
+		if n.label == "" {
+			return 0, fmt.Errorf("empty label at %q", path)
+		}
+		if n.e == nil && len(n.children) < 2 {
+			return 0, fmt.Errorf("uncompressed node at %q", path)
+		}
+	}
+	for i, c := range n.children {
+		if i > 0 && n.children[i-1].label[0] >= c.label[0] {
+			return 0, fmt.Errorf("children out of order at %q", path)
+		}
+		k, err := checkNode(c, path+c.label, false)
+		if err != nil {
+			return 0, err
+		}
+		count += k
+		if c.maxScore > maxScore {
+			maxScore = c.maxScore
+		}
+	}
+	if n.maxScore != maxScore {
+		return 0, fmt.Errorf("maxScore at %q is %v, want %v",
+			path, n.maxScore, maxScore)
+	}
+	return count, nil
+}
+
+func randKey(r *rand.Rand) string {
+	b := make([]byte, r.Intn(6))
+	for i := range b {
+		b[i] = "abc"[r.Intn(3)]
+	}
+	return string(b)
+}
+
+func TestRadixTreeRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tree := NewRadixTree(true)
+	ref := make(map[string]Entry)
+	for i := 0; i < 5000; i++ {
+		key := randKey(r)
+		switch r.Intn(4) {
+		case 0:
+			_, ok := ref[key]
+			value, ok2 := tree.Delete(key)
+			if ok2 != ok || ok && value != ref[key].Value {
+				t.Fatalf("Delete(%q) = %v, %t", key, value, ok2)
+			}
+			delete(ref, key)
+		case 1:
+			_, ok := ref[key]
+			score := float64(r.Intn(10))
+			if tree.SetScore(key, score) != ok {
+				t.Fatalf("SetScore(%q) = %t", key, !ok)
+			}
+			if ok {
+				e := ref[key]
+				e.Score = score
+				ref[key] = e
+			}
+		default:
+			_, ok := ref[key]
+			e := Entry{key, i, float64(r.Intn(10))}
+			if tree.InsertBytes([]byte(key), e.Value, e.Score) == ok {
+				t.Fatalf("Insert(%q) = %t", key, ok)
+			}
+			ref[key] = e
+		}
+		if n, err := checkNode(tree.root, "", true); err != nil {
+			t.Fatal(err)
+		} else if n != len(ref) || tree.Len() != len(ref) {
+			t.Fatalf("%d entries, Len %d, want %d", n, tree.Len(), len(ref))
+		}
+		key = randKey(r)
+		value, score, ok := tree.GetBytes([]byte(key))
+		if e, ok2 := ref[key]; ok != ok2 || ok && (value != e.Value ||
+			score != e.Score) {
+			t.Fatalf("Get(%q) = %v, %v, %t", key, value, score, ok)
+		}
+	}
+}
+
+func TestRadixTreePrefix(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	tree := NewRadixTree(false)
+	var keys []string
+	for i := 0; i < 300; i++ {
+		key := randKey(r)
+		if tree.Insert(key, len(key), 0) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for i := 0; i < 200; i++ {
+		s := randKey(r)
+		var want []string
+		wantLongest := ""
+		hasLongest := false
+		for _, key := range keys {
+			if strings.HasPrefix(key, s) {
+				want = append(want, key)
+			}
+			if strings.HasPrefix(s, key) && len(key) >= len(wantLongest) {
+				wantLongest, hasLongest = key, true
+			}
+		}
+		var got []string
+		tree.WalkPrefix(s, func(key string, value interface{}) bool {
+			if value != len(key) {
+				t.Errorf("value of %q is %v", key, value)
+			}
+			got = append(got, key)
+			return false
+		})
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("WalkPrefix(%q): %v, want %v", s, got, want)
+		}
+		got = got[:0]
+		tree.WalkPrefixBytes([]byte(s), func(key string, _ interface{}) bool {
+			got = append(got, key)
+			return false
+		})
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("WalkPrefixBytes(%q): %v, want %v", s, got, want)
+		}
+		key, value, ok := tree.LongestPrefix(s)
+		if key != wantLongest || ok != hasLongest || ok && value != len(key) {
+			t.Fatalf("LongestPrefix(%q) = %q, %v, %t", s, key, value, ok)
+		}
+		if k, _, _ := tree.LongestPrefixBytes([]byte(s)); k != key {
+			t.Fatalf("LongestPrefixBytes(%q) = %q, want %q", s, k, key)
+		}
+	}
+	n := 0
+	tree.Walk(func(key string, value interface{}) bool {
+		n++
+		return n == 5
+	})
+	if n != 5 {
+		t.Errorf("Walk didn't stop, %d calls", n)
+	}
+	tree.Clear()
+	if tree.Len() != 0 {
+		t.Errorf("Len after Clear = %d", tree.Len())
+	}
+}
+
+func TestRadixTreeTopKWithPrefix(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	tree := NewRadixTree(false)
+	ref := make(map[string]Entry)
+	for i := 0; i < 300; i++ {
+		key := randKey(r)
+		e := Entry{key, i, float64(r.Intn(20))}
+		tree.Insert(key, e.Value, e.Score)
+		ref[key] = e
+	}
+	for i := 0; i < 200; i++ {
+		prefix, k := randKey(r), r.Intn(12)-1
+		var want []Entry
+		for key, e := range ref {
+			if strings.HasPrefix(key, prefix) {
+				want = append(want, e)
+			}
+		}
+		sort.Slice(want, func(i, j int) bool {
+			if want[i].Score != want[j].Score {
+				return want[i].Score > want[j].Score
+			}
+			return want[i].Key < want[j].Key
+		})
+		if k <= 0 {
+			want = nil
+		} else if len(want) > k {
+			want = want[:k]
+		}
+		got := tree.TopKWithPrefix(prefix, k)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("TopKWithPrefix(%q, %d) = %v, want %v", prefix, k, got, want)
+		}
+		got = tree.TopKWithPrefixBytes([]byte(prefix), k)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("TopKWithPrefixBytes(%q, %d) = %v", prefix, k, got)
+		}
+	}
+}
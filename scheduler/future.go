@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+// The error of a task which panics.
+type PanicError struct {
+	Value interface{} // The value passed to panic.
+	Stack []byte      // The stack trace of the panic.
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("gocontainer: task panics: %v", pe.Value)
+}
+
+// The handle of a submitted task, to get its result,
+// or to change its priority or cancel it before it starts.
+type Future struct {
+	s      *Scheduler
+	task   Task
+	handle *pqueue.Handle
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+// Return a channel closed when the task is finished or canceled.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait for the task to finish, and return its result.
+// If the task panics, err is a *PanicError.
+// If the task is canceled, err is gocontainer.ErrCanceled,
+// or gocontainer.ErrClosed if canceled by Shutdown.
+// It returns ctx.Err() if ctx is done first.
+func (f *Future) Wait(ctx context.Context) (result interface{}, err error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Set the priority of the task.
+// Return false if the task has started or been canceled.
+func (f *Future) Update(priority gocontainer.Comparable) bool {
+	return f.s.pq.UpdatePriority(f.handle, priority)
+}
+
+// Cancel the task if it hasn't started.
+// Return false if the task has started or been canceled.
+func (f *Future) Cancel() bool {
+	if !f.s.pq.Remove(f.handle) {
+		return false
+	}
+	f.complete(nil, gocontainer.ErrCanceled)
+	return true
+}
+
+// Run the task, and recover the panic from it.
+func (f *Future) run(ctx context.Context) {
+	var result interface{}
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		result, err = f.task(ctx)
+	}()
+	f.complete(result, err)
+}
+
+func (f *Future) complete(result interface{}, err error) {
+	f.result, f.err = result, err
+	close(f.done)
+}
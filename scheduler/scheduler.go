@@ -0,0 +1,224 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+// A task run by the scheduler.
+// ctx is canceled when the shutdown of the scheduler times out.
+type Task func(ctx context.Context) (result interface{}, err error)
+
+type Options struct {
+	// Number of workers always running. Must be positive.
+	Workers int
+
+	// Maximum number of workers. If it is greater than Workers,
+	// extra workers are started when tasks are submitted
+	// and the idle workers are not enough, and they exit after idle for IdleTimeout.
+	// Non-positive for the same as Workers.
+	MaxWorkers int
+
+	// Idle time before an extra worker exits. Default: 1 second.
+	IdleTimeout time.Duration
+
+	// Run the task with the greatest priority first if true,
+	// otherwise the smallest one.
+	IsTopMax bool
+
+	// Priority aging of the waiting tasks. Optional.
+	Aging *pqueue.Aging
+}
+
+// Run submitted tasks on a pool of goroutines in the order of priority.
+type Scheduler struct {
+	pq          *pqueue.HandlePriorityQueue
+	minWorkers  int
+	maxWorkers  int
+	idleTimeout time.Duration
+
+	ctx    context.Context // Passed to the tasks.
+	cancel context.CancelFunc
+	wake   chan struct{} // Signal idle workers that a task is submitted.
+	quit   chan struct{} // Closed on shutdown.
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex // Protect the fields below.
+	nWorkers int
+	nIdle    int
+	isClosed bool
+}
+
+func New(opts *Options) *Scheduler {
+	s, err := NewE(opts)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Same as New, but return an error instead of panic.
+func NewE(opts *Options) (*Scheduler, error) {
+	if opts == nil || opts.Workers <= 0 {
+		var n int
+		if opts != nil {
+			n = opts.Workers
+		}
+		return nil, fmt.Errorf("%w: %d", gocontainer.ErrInvalidWorkers, n)
+	}
+	s := &Scheduler{
+		pq: pqueue.NewHandlePriorityQueueWithAging(0, opts.IsTopMax, true,
+			opts.Aging),
+		minWorkers:  opts.Workers,
+		maxWorkers:  opts.MaxWorkers,
+		idleTimeout: opts.IdleTimeout,
+		quit:        make(chan struct{}),
+	}
+	if s.maxWorkers < s.minWorkers {
+		s.maxWorkers = s.minWorkers
+	}
+	if s.idleTimeout <= 0 {
+		s.idleTimeout = time.Second
+	}
+	s.wake = make(chan struct{}, s.maxWorkers)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.nWorkers = s.minWorkers
+	s.wg.Add(s.minWorkers)
+	for i := 0; i < s.minWorkers; i++ {
+		go s.worker()
+	}
+	return s, nil
+}
+
+// Submit a task with priority, and return its future.
+// It returns an error wrapping gocontainer.ErrClosed after Shutdown.
+func (s *Scheduler) Submit(task Task, priority gocontainer.Comparable) (
+	*Future, error) {
+	if task == nil {
+		return nil, gocontainer.ErrNilItem
+	}
+	f := &Future{
+		s:    s,
+		task: task,
+		done: make(chan struct{}),
+	}
+	f.handle = pqueue.NewHandle(f, priority)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.isClosed {
+		return nil, gocontainer.ErrClosed
+	}
+	if err := s.pq.TryEnqueue(f.handle); err != nil {
+		return nil, err
+	}
+	// Start an extra worker if the idle workers are not enough.
+	if s.pq.Len() > s.nIdle && s.nWorkers < s.maxWorkers {
+		s.nWorkers++
+		s.wg.Add(1)
+		go s.worker()
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// Enough signals are pending.
+	}
+	return f, nil
+}
+
+// Return the number of tasks waiting to run.
+func (s *Scheduler) Len() int {
+	return s.pq.Len()
+}
+
+// Return the number of running workers, including idle ones.
+func (s *Scheduler) Workers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nWorkers
+}
+
+// Stop accepting tasks, and wait for the workers to finish
+// all the submitted tasks.
+// If ctx is done before that, it cancels the context of the running tasks,
+// cancels the tasks not started yet with gocontainer.ErrClosed,
+// and returns ctx.Err() without waiting for the running tasks.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.isClosed {
+		s.isClosed = true
+		close(s.quit)
+	}
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		for h, ok := s.pq.Dequeue(); ok; h, ok = s.pq.Dequeue() {
+			h.Value().(*Future).complete(nil, gocontainer.ErrClosed)
+		}
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) worker() {
+	defer s.wg.Done()
+	var timer *time.Timer
+	for {
+		if h, ok := s.pq.Dequeue(); ok {
+			h.Value().(*Future).run(s.ctx)
+			continue
+		}
+		select {
+		case <-s.quit:
+			// No more tasks will be submitted. Drain the queue.
+			for h, ok := s.pq.Dequeue(); ok; h, ok = s.pq.Dequeue() {
+				h.Value().(*Future).run(s.ctx)
+			}
+			return
+		default:
+		}
+		var timeoutC <-chan time.Time
+		if s.maxWorkers > s.minWorkers {
+			if timer == nil {
+				timer = time.NewTimer(s.idleTimeout)
+				defer timer.Stop()
+			} else {
+				timer.Reset(s.idleTimeout)
+			}
+			timeoutC = timer.C
+		}
+		s.mu.Lock()
+		s.nIdle++
+		s.mu.Unlock()
+		var isTimeout bool
+		select {
+		case <-s.wake:
+		case <-s.quit:
+		case <-timeoutC:
+			isTimeout = true
+		}
+		if timer != nil && !isTimeout && !timer.Stop() {
+			<-timer.C
+		}
+		s.mu.Lock()
+		s.nIdle--
+		if isTimeout && s.nWorkers > s.minWorkers {
+			s.nWorkers--
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+	}
+}
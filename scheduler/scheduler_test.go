@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+func TestScheduler(t *testing.T) {
+	_, err := NewE(&Options{})
+	if !errors.Is(err, gocontainer.ErrInvalidWorkers) {
+		t.Fatalf("err(%v) is not ErrInvalidWorkers", err)
+	}
+	s := New(&Options{Workers: 1, IsTopMax: true})
+	// Block the only worker, so that the tasks below are queued.
+	block := make(chan struct{})
+	first, err := s.Submit(func(ctx context.Context) (interface{}, error) {
+		<-block
+		return nil, nil
+	}, pqueue.Int64Priority(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []int64
+	var mu sync.Mutex
+	newTask := func(p int64) Task {
+		return func(ctx context.Context) (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, p)
+			return p * 10, nil
+		}
+	}
+	// Wait for the worker to take the first task.
+	for s.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	futures := make([]*Future, 5)
+	for i := range futures {
+		futures[i], err = s.Submit(newTask(int64(i)), pqueue.Int64Priority(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !futures[0].Update(pqueue.Int64Priority(10)) {
+		t.Fatal("Update failed!")
+	}
+	if !futures[3].Cancel() {
+		t.Fatal("Cancel failed!")
+	}
+	if futures[3].Cancel() || futures[3].Update(pqueue.Int64Priority(1)) {
+		t.Fatal("Operate a canceled task succeeded!")
+	}
+	close(block)
+	if _, err = first.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range futures {
+		result, err := f.Wait(context.Background())
+		if i == 3 {
+			if !errors.Is(err, gocontainer.ErrCanceled) {
+				t.Errorf("err(%v) is not ErrCanceled", err)
+			}
+			continue
+		}
+		if err != nil || result != int64(i)*10 {
+			t.Errorf("Task %d: result = %v, err = %v", i, result, err)
+		}
+	}
+	want := []int64{0, 4, 2, 1}
+	if len(order) != len(want) {
+		t.Fatalf("Order: %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("Order: %v, want %v", order, want)
+		}
+	}
+	if err = s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Submit(newTask(0), pqueue.Int64Priority(0))
+	if !errors.Is(err, gocontainer.ErrClosed) {
+		t.Fatalf("err(%v) is not ErrClosed", err)
+	}
+}
+
+func TestSchedulerPanic(t *testing.T) {
+	s := New(&Options{Workers: 1})
+	defer s.Shutdown(context.Background())
+	f, err := s.Submit(func(ctx context.Context) (interface{}, error) {
+		panic("test panic")
+	}, pqueue.Int64Priority(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.Wait(context.Background())
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "test panic" {
+		t.Fatalf("err(%v) is not the PanicError", err)
+	}
+	// The worker survives.
+	f, err = s.Submit(func(ctx context.Context) (interface{}, error) {
+		return 1, nil
+	}, pqueue.Int64Priority(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := f.Wait(context.Background()); err != nil || result != 1 {
+		t.Fatalf("result = %v, err = %v", result, err)
+	}
+}
+
+func TestSchedulerShutdown(t *testing.T) {
+	s := New(&Options{Workers: 1})
+	started := make(chan struct{})
+	running, err := s.Submit(func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, pqueue.Int64Priority(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	pending, err := s.Submit(func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	}, pqueue.Int64Priority(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = s.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err(%v) is not context.DeadlineExceeded", err)
+	}
+	if _, err = pending.Wait(context.Background()); !errors.Is(err,
+		gocontainer.ErrClosed) {
+		t.Fatalf("err(%v) is not ErrClosed", err)
+	}
+	if _, err = running.Wait(context.Background()); !errors.Is(err,
+		context.Canceled) {
+		t.Fatalf("err(%v) is not context.Canceled", err)
+	}
+}
+
+func TestSchedulerElastic(t *testing.T) {
+	s := New(&Options{
+		Workers:     1,
+		MaxWorkers:  3,
+		IdleTimeout: 5 * time.Millisecond,
+	})
+	block := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		_, err := s.Submit(func(ctx context.Context) (interface{}, error) {
+			wg.Done()
+			<-block
+			return nil, nil
+		}, pqueue.Int64Priority(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// All three tasks run at the same time.
+	wg.Wait()
+	if n := s.Workers(); n != 3 {
+		t.Errorf("Workers(%d) != 3", n)
+	}
+	close(block)
+	deadline := time.Now().Add(time.Second)
+	for s.Workers() > 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := s.Workers(); n != 1 {
+		t.Errorf("Workers(%d) != 1 after idle", n)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,170 @@
+package segtree
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// FenwickTree (binary indexed tree) keeps the prefix aggregates
+// of a sequence under a commutative Monoid, with less memory than
+// SegmentTree. Add and Prefix take O(log(n)) time.
+//
+// Range queries and Get need the monoid to be a Group.
+type FenwickTree struct {
+	m    Monoid
+	t    []interface{} // 1-based. t[i] aggregates (i - lowbit(i), i].
+	lock *sync.RWMutex
+}
+
+// Create a FenwickTree of n elements, all of which are the identity.
+// It panics with gocontainer.ErrNilMonoid if m is nil,
+// or with an error wrapping gocontainer.ErrNegativeCapacity
+// if n is negative.
+func NewFenwickTree(m Monoid, n int, isSync bool) *FenwickTree {
+	if n < 0 {
+		panic(fmt.Errorf("%w: %d", gocontainer.ErrNegativeCapacity, n))
+	}
+	return newFenwickTree(m, make([]interface{}, n), isSync)
+}
+
+// Create a FenwickTree over values in O(n) time.
+// nil values are regarded as the identity.
+// It panics with gocontainer.ErrNilMonoid if m is nil.
+func NewFenwickTreeFrom(m Monoid, values []interface{},
+	isSync bool) *FenwickTree {
+	return newFenwickTree(m, values, isSync)
+}
+
+func newFenwickTree(m Monoid, values []interface{},
+	isSync bool) *FenwickTree {
+	if m == nil {
+		panic(gocontainer.ErrNilMonoid)
+	}
+	ft := &FenwickTree{m: m, t: make([]interface{}, len(values)+1)}
+	id := m.Identity()
+	for i := range ft.t {
+		ft.t[i] = id
+	}
+	for i, x := range values {
+		if x != nil {
+			ft.t[i+1] = x
+		}
+	}
+	for i := 1; i < len(ft.t); i++ {
+		if j := i + i&-i; j < len(ft.t) {
+			ft.t[j] = m.Combine(ft.t[j], ft.t[i])
+		}
+	}
+	if isSync {
+		ft.lock = new(sync.RWMutex)
+	}
+	return ft
+}
+
+func (ft *FenwickTree) Len() int {
+	if ft == nil {
+		return 0
+	}
+	return len(ft.t) - 1
+}
+
+// Combine x into the element at index i. nil is regarded as the identity.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if i is out of range.
+func (ft *FenwickTree) Add(i int, x interface{}) {
+	ft.checkIndex(i, i+1)
+	if x == nil {
+		return
+	}
+	if ft.lock != nil {
+		ft.lock.Lock()
+		defer ft.lock.Unlock()
+	}
+	for i++; i < len(ft.t); i += i & -i {
+		ft.t[i] = ft.m.Combine(ft.t[i], x)
+	}
+}
+
+// Return the aggregate of the elements in [0, n).
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if n is out of range.
+func (ft *FenwickTree) Prefix(n int) interface{} {
+	ft.checkIndex(0, n)
+	if ft.lock != nil {
+		ft.lock.RLock()
+		defer ft.lock.RUnlock()
+	}
+	return ft.prefix(n)
+}
+
+// Return the aggregate of the elements in [lo, hi).
+// It panics with an error wrapping gocontainer.ErrNotGroup
+// if the monoid is not a Group,
+// or with an error wrapping gocontainer.ErrOutOfRange
+// if the range is invalid.
+func (ft *FenwickTree) Query(lo, hi int) interface{} {
+	g, ok := ft.m.(Group)
+	if !ok {
+		panic(fmt.Errorf("%w: Fenwick tree over %T",
+			gocontainer.ErrNotGroup, ft.m))
+	}
+	ft.checkIndex(lo, hi)
+	if ft.lock != nil {
+		ft.lock.RLock()
+		defer ft.lock.RUnlock()
+	}
+	return g.Combine(ft.prefix(hi), g.Inverse(ft.prefix(lo)))
+}
+
+// Return the element at index i.
+// It panics with an error wrapping gocontainer.ErrNotGroup
+// if the monoid is not a Group,
+// or with an error wrapping gocontainer.ErrOutOfRange
+// if i is out of range.
+func (ft *FenwickTree) Get(i int) interface{} {
+	return ft.Query(i, i+1)
+}
+
+// Set the element at index i to x.
+// It panics with an error wrapping gocontainer.ErrNotGroup
+// if the monoid is not a Group,
+// or with an error wrapping gocontainer.ErrOutOfRange
+// if i is out of range.
+func (ft *FenwickTree) Set(i int, x interface{}) {
+	g, ok := ft.m.(Group)
+	if !ok {
+		panic(fmt.Errorf("%w: Fenwick tree over %T",
+			gocontainer.ErrNotGroup, ft.m))
+	}
+	ft.checkIndex(i, i+1)
+	if x == nil {
+		x = g.Identity()
+	}
+	if ft.lock != nil {
+		ft.lock.Lock()
+		defer ft.lock.Unlock()
+	}
+	old := g.Combine(ft.prefix(i+1), g.Inverse(ft.prefix(i)))
+	d := g.Combine(x, g.Inverse(old))
+	for i++; i < len(ft.t); i += i & -i {
+		ft.t[i] = g.Combine(ft.t[i], d)
+	}
+}
+
+// Call it with the lock held.
+func (ft *FenwickTree) prefix(n int) interface{} {
+	res := ft.m.Identity()
+	for ; n > 0; n -= n & -n {
+		res = ft.m.Combine(res, ft.t[n])
+	}
+	return res
+}
+
+func (ft *FenwickTree) checkIndex(lo, hi int) {
+	if lo < 0 || hi > ft.Len() || lo > hi {
+		panic(fmt.Errorf("%w: [%d, %d) in a sequence of length %d",
+			gocontainer.ErrOutOfRange, lo, hi, ft.Len()))
+	}
+}
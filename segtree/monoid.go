@@ -0,0 +1,103 @@
+package segtree
+
+const (
+	maxInt = int(^uint(0) >> 1)
+	minInt = -maxInt - 1
+)
+
+// Monoid is an associative binary operation with an identity element,
+// combining the values of consecutive elements.
+type Monoid interface {
+	// The identity element e, with Combine(e, x) = Combine(x, e) = x.
+	Identity() interface{}
+
+	// Combine the aggregate a of a range with the aggregate b
+	// of the range right after it. It must be associative.
+	Combine(a, b interface{}) interface{}
+}
+
+// Group is a Monoid with inverses, which lets FenwickTree answer
+// range queries from prefix queries.
+// Combine must also be commutative.
+type Group interface {
+	Monoid
+
+	// Return y with Combine(x, y) = Identity().
+	Inverse(x interface{}) interface{}
+}
+
+// Action is an update applied to all the elements in a range,
+// for SegmentTree.RangeUpdate.
+type Action interface {
+	// Return the aggregate of n consecutive elements after update u,
+	// given their aggregate x before it.
+	Apply(u, x interface{}, n int) interface{}
+
+	// Return the update equal to applying first and then second.
+	Compose(first, second interface{}) interface{}
+}
+
+// Sum of ints. It's a Group.
+type IntSum struct{}
+
+func (IntSum) Identity() interface{} {
+	return 0
+}
+
+func (IntSum) Combine(a, b interface{}) interface{} {
+	return a.(int) + b.(int)
+}
+
+func (IntSum) Inverse(x interface{}) interface{} {
+	return -x.(int)
+}
+
+// Minimum of ints, with the maximum int as the identity.
+type IntMin struct{}
+
+func (IntMin) Identity() interface{} {
+	return maxInt
+}
+
+func (IntMin) Combine(a, b interface{}) interface{} {
+	if b.(int) < a.(int) {
+		return b
+	}
+	return a
+}
+
+// Maximum of ints, with the minimum int as the identity.
+type IntMax struct{}
+
+func (IntMax) Identity() interface{} {
+	return minInt
+}
+
+func (IntMax) Combine(a, b interface{}) interface{} {
+	if a.(int) < b.(int) {
+		return b
+	}
+	return a
+}
+
+// Add an int to each element, for aggregates of IntSum.
+type AddToIntSum struct{}
+
+func (AddToIntSum) Apply(u, x interface{}, n int) interface{} {
+	return x.(int) + u.(int)*n
+}
+
+func (AddToIntSum) Compose(first, second interface{}) interface{} {
+	return first.(int) + second.(int)
+}
+
+// Add an int to each element, for aggregates of IntMin or IntMax.
+type AddToIntMinMax struct{}
+
+func (AddToIntMinMax) Apply(u, x interface{}, n int) interface{} {
+	return x.(int) + u.(int)
+}
+
+func (AddToIntMinMax) Compose(first, second interface{}) interface{} {
+	return first.(int) + second.(int)
+}
@@ -0,0 +1,244 @@
+// Package segtree provides a segment tree and a Fenwick tree
+// (binary indexed tree) for range queries over a user-supplied monoid.
+package segtree
+
+import (
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// SegmentTree keeps a sequence of values and the aggregates of its ranges
+// under a Monoid. Set, Query and RangeUpdate take O(log(n)) time.
+type SegmentTree struct {
+	m    Monoid
+	a    Action // nil if range updates are not supported.
+	n    int
+	size int           // Number of leaves, a power of 2 not less than n.
+	log  int           // log2(size).
+	d    []interface{} // d[1] is the root, d[size:size+n] are the values.
+	lz   []interface{} // Pending updates of the internal nodes, or nil.
+	lock *sync.Mutex   // Queries also modify the tree, by pushing lz down.
+}
+
+// Create a SegmentTree over values, which are copied.
+// nil values are regarded as the identity.
+// It panics with gocontainer.ErrNilMonoid if m is nil.
+func NewSegmentTree(m Monoid, values []interface{},
+	isSync bool) *SegmentTree {
+	return NewLazySegmentTree(m, nil, values, isSync)
+}
+
+// Create a SegmentTree over values, which also supports RangeUpdate
+// with updates applied by a. Updates are applied lazily, so a range update
+// also takes O(log(n)) time.
+// It panics with gocontainer.ErrNilMonoid if m is nil.
+func NewLazySegmentTree(m Monoid, a Action, values []interface{},
+	isSync bool) *SegmentTree {
+	if m == nil {
+		panic(gocontainer.ErrNilMonoid)
+	}
+	st := &SegmentTree{m: m, a: a, n: len(values), size: 1}
+	for st.size < st.n {
+		st.size <<= 1
+		st.log++
+	}
+	st.d = make([]interface{}, 2*st.size)
+	id := m.Identity()
+	for i := range st.d {
+		st.d[i] = id
+	}
+	for i, x := range values {
+		if x != nil {
+			st.d[st.size+i] = x
+		}
+	}
+	for i := st.size - 1; i > 0; i-- {
+		st.update(i)
+	}
+	if a != nil {
+		st.lz = make([]interface{}, st.size)
+	}
+	if isSync {
+		st.lock = new(sync.Mutex)
+	}
+	return st
+}
+
+func (st *SegmentTree) Len() int {
+	if st == nil {
+		return 0
+	}
+	return st.n
+}
+
+// Return the value at index i.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if i is out of range.
+func (st *SegmentTree) Get(i int) interface{} {
+	st.checkIndex(i, i+1)
+	if st.lock != nil {
+		st.lock.Lock()
+		defer st.lock.Unlock()
+	}
+	p := i + st.size
+	st.pushPath(p)
+	return st.d[p]
+}
+
+// Set the value at index i to x. nil is regarded as the identity.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if i is out of range.
+func (st *SegmentTree) Set(i int, x interface{}) {
+	st.checkIndex(i, i+1)
+	if st.lock != nil {
+		st.lock.Lock()
+		defer st.lock.Unlock()
+	}
+	if x == nil {
+		x = st.m.Identity()
+	}
+	p := i + st.size
+	st.pushPath(p)
+	st.d[p] = x
+	for k := 1; k <= st.log; k++ {
+		st.update(p >> k)
+	}
+}
+
+// Return the aggregate of the values in [lo, hi),
+// or the identity if lo == hi.
+// It panics with an error wrapping gocontainer.ErrOutOfRange
+// if the range is invalid.
+func (st *SegmentTree) Query(lo, hi int) interface{} {
+	st.checkIndex(lo, hi)
+	if st == nil {
+		return nil
+	}
+	if st.lock != nil {
+		st.lock.Lock()
+		defer st.lock.Unlock()
+	}
+	if lo == hi {
+		return st.m.Identity()
+	}
+	lo, hi = lo+st.size, hi+st.size
+	st.pushRange(lo, hi)
+	left, right := st.m.Identity(), st.m.Identity()
+	for ; lo < hi; lo, hi = lo>>1, hi>>1 {
+		if lo&1 == 1 {
+			left = st.m.Combine(left, st.d[lo])
+			lo++
+		}
+		if hi&1 == 1 {
+			hi--
+			right = st.m.Combine(st.d[hi], right)
+		}
+	}
+	return st.m.Combine(left, right)
+}
+
+// Return the aggregate of all the values.
+func (st *SegmentTree) QueryAll() interface{} {
+	if st == nil {
+		return nil
+	}
+	if st.lock != nil {
+		st.lock.Lock()
+		defer st.lock.Unlock()
+	}
+	return st.d[1]
+}
+
+// Apply update u to each value in [lo, hi).
+// It panics with gocontainer.ErrNoAction if the tree is not created
+// with an Action, or with an error wrapping gocontainer.ErrOutOfRange
+// if the range is invalid.
+func (st *SegmentTree) RangeUpdate(lo, hi int, u interface{}) {
+	if st.a == nil {
+		panic(gocontainer.ErrNoAction)
+	}
+	st.checkIndex(lo, hi)
+	if st.lock != nil {
+		st.lock.Lock()
+		defer st.lock.Unlock()
+	}
+	if lo == hi {
+		return
+	}
+	lo, hi = lo+st.size, hi+st.size
+	st.pushRange(lo, hi)
+	for l, h := lo, hi; l < h; l, h = l>>1, h>>1 {
+		if l&1 == 1 {
+			st.applyAll(l, u)
+			l++
+		}
+		if h&1 == 1 {
+			h--
+			st.applyAll(h, u)
+		}
+	}
+	for k := 1; k <= st.log; k++ {
+		if (lo>>k)<<k != lo {
+			st.update(lo >> k)
+		}
+		if (hi>>k)<<k != hi {
+			st.update((hi - 1) >> k)
+		}
+	}
+}
+
+func (st *SegmentTree) checkIndex(lo, hi int) {
+	if lo < 0 || hi > st.Len() || lo > hi {
+		panic(fmt.Errorf("%w: [%d, %d) in a sequence of length %d",
+			gocontainer.ErrOutOfRange, lo, hi, st.Len()))
+	}
+}
+
+func (st *SegmentTree) update(k int) {
+	st.d[k] = st.m.Combine(st.d[2*k], st.d[2*k+1])
+}
+
+// Apply u to node k and record it as pending for its children.
+func (st *SegmentTree) applyAll(k int, u interface{}) {
+	// Node k covers size >> depth leaves.
+	st.d[k] = st.a.Apply(u, st.d[k], st.size>>(bits.Len(uint(k))-1))
+	if k < st.size {
+		if st.lz[k] == nil {
+			st.lz[k] = u
+		} else {
+			st.lz[k] = st.a.Compose(st.lz[k], u)
+		}
+	}
+}
+
+// Push the pending update of node k to its children.
+func (st *SegmentTree) push(k int) {
+	if st.lz == nil || st.lz[k] == nil {
+		return
+	}
+	st.applyAll(2*k, st.lz[k])
+	st.applyAll(2*k+1, st.lz[k])
+	st.lz[k] = nil
+}
+
+// Push the pending updates on the path from the root to leaf p.
+func (st *SegmentTree) pushPath(p int) {
+	for k := st.log; k > 0; k-- {
+		st.push(p >> k)
+	}
+}
+
+// Push the pending updates above the nodes covering leaves [lo, hi).
+func (st *SegmentTree) pushRange(lo, hi int) {
+	for k := st.log; k > 0; k-- {
+		if (lo>>k)<<k != lo {
+			st.push(lo >> k)
+		}
+		if (hi>>k)<<k != hi {
+			st.push((hi - 1) >> k)
+		}
+	}
+}
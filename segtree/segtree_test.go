@@ -0,0 +1,188 @@
+package segtree
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+// String concatenation, which is not commutative.
+type concat struct{}
+
+func (concat) Identity() interface{} {
+	return ""
+}
+
+func (concat) Combine(a, b interface{}) interface{} {
+	return a.(string) + b.(string)
+}
+
+func TestSegmentTreeLazy(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 7, 64, 100} {
+		ref := make([]int, n)
+		values := make([]interface{}, n)
+		for i := range ref {
+			ref[i] = r.Intn(100)
+			values[i] = ref[i]
+		}
+		sum := NewLazySegmentTree(IntSum{}, AddToIntSum{}, values, true)
+		min := NewLazySegmentTree(IntMin{}, AddToIntMinMax{}, values, false)
+		for i := 0; i < 500; i++ {
+			lo := r.Intn(n + 1)
+			hi := lo + r.Intn(n+1-lo)
+			switch r.Intn(3) {
+			case 0:
+				u := r.Intn(21) - 10
+				sum.RangeUpdate(lo, hi, u)
+				min.RangeUpdate(lo, hi, u)
+				for j := lo; j < hi; j++ {
+					ref[j] += u
+				}
+			case 1:
+				if lo < n {
+					x := r.Intn(100)
+					sum.Set(lo, x)
+					min.Set(lo, x)
+					ref[lo] = x
+				}
+			default:
+				wantSum, wantMin := 0, maxInt
+				for j := lo; j < hi; j++ {
+					wantSum += ref[j]
+					if ref[j] < wantMin {
+						wantMin = ref[j]
+					}
+				}
+				if got := sum.Query(lo, hi); got != wantSum {
+					t.Fatalf("n=%d: sum of [%d, %d) = %v, want %d",
+						n, lo, hi, got, wantSum)
+				}
+				if got := min.Query(lo, hi); got != wantMin {
+					t.Fatalf("n=%d: min of [%d, %d) = %v, want %d",
+						n, lo, hi, got, wantMin)
+				}
+			}
+		}
+		total := 0
+		for j, x := range ref {
+			if got := sum.Get(j); got != x {
+				t.Fatalf("n=%d: Get(%d) = %v, want %d", n, j, got, x)
+			}
+			total += x
+		}
+		if got := sum.QueryAll(); got != total {
+			t.Errorf("n=%d: QueryAll() = %v, want %d", n, got, total)
+		}
+	}
+}
+
+func TestSegmentTreeOrder(t *testing.T) {
+	st := NewSegmentTree(concat{},
+		[]interface{}{"a", "b", nil, "c", "d", "e"}, false)
+	if got := st.Query(1, 5); got != "bcd" {
+		t.Errorf("Query(1, 5) = %v", got)
+	}
+	st.Set(2, "x")
+	if got := st.QueryAll(); got != "abxcde" {
+		t.Errorf("QueryAll() = %v", got)
+	}
+	if got := st.Query(3, 3); got != "" {
+		t.Errorf("Query(3, 3) = %v", got)
+	}
+	st.Set(2, nil) // The identity.
+	if got := st.Query(1, 4); got != "bc" {
+		t.Errorf("Query(1, 4) = %v after Set(2, nil)", got)
+	}
+	st.Set(2, "x")
+	var nilTree *SegmentTree
+	if got := nilTree.QueryAll(); got != nil || nilTree.Len() != 0 {
+		t.Errorf("QueryAll() = %v on a nil tree", got)
+	}
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, gocontainer.ErrOutOfRange) {
+				t.Errorf("Query(0, 7) panics with %v", err)
+			}
+		}()
+		st.Query(0, 7)
+	}()
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, gocontainer.ErrNoAction) {
+				t.Errorf("RangeUpdate without an action panics with %v", err)
+			}
+		}()
+		st.RangeUpdate(0, 1, "y")
+	}()
+}
+
+func TestFenwickTree(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const n = 50
+	ref := make([]int, n)
+	values := make([]interface{}, n)
+	for i := range ref {
+		ref[i] = r.Intn(100)
+		values[i] = ref[i]
+	}
+	ft := NewFenwickTreeFrom(IntSum{}, values, true)
+	for i := 0; i < 1000; i++ {
+		lo := r.Intn(n + 1)
+		hi := lo + r.Intn(n+1-lo)
+		switch r.Intn(3) {
+		case 0:
+			if lo < n {
+				ft.Add(lo, hi)
+				ref[lo] += hi
+			}
+		case 1:
+			if lo < n {
+				ft.Set(lo, hi)
+				ref[lo] = hi
+			}
+		default:
+			want := 0
+			for j := lo; j < hi; j++ {
+				want += ref[j]
+			}
+			if got := ft.Query(lo, hi); got != want {
+				t.Fatalf("Query(%d, %d) = %v, want %d", lo, hi, got, want)
+			}
+		}
+	}
+	want := 0
+	for j := 0; j < n; j++ {
+		if got := ft.Get(j); got != ref[j] {
+			t.Fatalf("Get(%d) = %v, want %d", j, got, ref[j])
+		}
+		if got := ft.Prefix(j); got != want {
+			t.Fatalf("Prefix(%d) = %v, want %d", j, got, want)
+		}
+		want += ref[j]
+	}
+
+	// Prefix maximums without a group.
+	mx := NewFenwickTree(IntMax{}, 5, false)
+	mx.Add(3, 7)
+	mx.Add(1, 4)
+	if got := mx.Prefix(3); got != 4 {
+		t.Errorf("Prefix(3) = %v", got)
+	}
+	if got := mx.Prefix(5); got != 7 {
+		t.Errorf("Prefix(5) = %v", got)
+	}
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			if !errors.Is(err, gocontainer.ErrNotGroup) {
+				t.Errorf("Query without a group panics with %v", err)
+			}
+		}()
+		mx.Query(1, 2)
+	}()
+}
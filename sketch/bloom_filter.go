@@ -0,0 +1,222 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// BloomFilter is a set of keys with false positives but no false negatives,
+// made of m bits and k hash functions.
+type BloomFilter struct {
+	m    uint64 // Number of bits, a multiple of 64.
+	k    int
+	seed uint64
+	bits []uint64
+	lock *sync.RWMutex
+}
+
+// Return the number of bits m and hash functions k for a filter
+// of n keys with a false positive rate of p.
+// It panics if n is 0 or p is not in (0, 1).
+func BloomFilterForError(n uint64, p float64) (m uint64, k int) {
+	if n == 0 || !(p > 0 && p < 1) {
+		panic(fmt.Errorf("%w: n %d, p %v", gocontainer.ErrInvalidParam, n, p))
+	}
+	fm := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k = int(math.Round(fm / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return uint64(fm), k
+}
+
+// Create a BloomFilter with m bits, rounded up to a multiple of 64,
+// and k hash functions.
+// It panics if m or k is non-positive.
+func NewBloomFilter(m uint64, k int, seed uint64, isSync bool) *BloomFilter {
+	bf, err := NewBloomFilterE(m, k, seed, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return bf
+}
+
+// Same as NewBloomFilter, but return an error instead of panic.
+func NewBloomFilterE(m uint64, k int, seed uint64, isSync bool) (
+	*BloomFilter, error) {
+	if m == 0 || k <= 0 || m > math.MaxUint64-63 {
+		return nil, fmt.Errorf("%w: m %d, k %d",
+			gocontainer.ErrInvalidParam, m, k)
+	}
+	m = (m + 63) / 64 * 64
+	bf := &BloomFilter{m: m, k: k, seed: seed, bits: make([]uint64, m/64)}
+	if isSync {
+		bf.lock = new(sync.RWMutex)
+	}
+	return bf, nil
+}
+
+// Return the number of bits.
+func (bf *BloomFilter) M() uint64 {
+	if bf.lock != nil {
+		bf.lock.RLock()
+		defer bf.lock.RUnlock()
+	}
+	return bf.m
+}
+
+// Return the number of hash functions.
+func (bf *BloomFilter) K() int {
+	if bf.lock != nil {
+		bf.lock.RLock()
+		defer bf.lock.RUnlock()
+	}
+	return bf.k
+}
+
+func (bf *BloomFilter) Add(key []byte) {
+	bf.add(bytesKey(key))
+}
+
+func (bf *BloomFilter) AddString(key string) {
+	bf.add(stringKey(key))
+}
+
+// Return false if key is definitely not added,
+// or true if it is probably added.
+func (bf *BloomFilter) Contains(key []byte) bool {
+	return bf.contains(bytesKey(key))
+}
+
+func (bf *BloomFilter) ContainsString(key string) bool {
+	return bf.contains(stringKey(key))
+}
+
+// Return the estimated number of distinct keys added,
+// from the number of bits set, or math.MaxUint64 if all are set.
+func (bf *BloomFilter) Count() uint64 {
+	if bf.lock != nil {
+		bf.lock.RLock()
+		defer bf.lock.RUnlock()
+	}
+	var x int
+	for _, w := range bf.bits {
+		x += bits.OnesCount64(w)
+	}
+	m := float64(bf.m)
+	if uint64(x) == bf.m {
+		return math.MaxUint64
+	}
+	return uint64(math.Round(-m / float64(bf.k) * math.Log(1-float64(x)/m)))
+}
+
+// Add the keys of other to bf.
+// It returns an error wrapping gocontainer.ErrIncompatible if other has
+// a different m, k or seed.
+func (bf *BloomFilter) Merge(other *BloomFilter) error {
+	x := other.clone()
+	if bf.lock != nil {
+		bf.lock.Lock()
+		defer bf.lock.Unlock()
+	}
+	if bf.m != x.m || bf.k != x.k || bf.seed != x.seed {
+		return fmt.Errorf("%w: m %d, k %d, seed %d, and m %d, k %d, seed %d",
+			gocontainer.ErrIncompatible, bf.m, bf.k, bf.seed, x.m, x.k, x.seed)
+	}
+	for i, w := range x.bits {
+		bf.bits[i] |= w
+	}
+	return nil
+}
+
+func (bf *BloomFilter) Reset() {
+	if bf.lock != nil {
+		bf.lock.Lock()
+		defer bf.lock.Unlock()
+	}
+	for i := range bf.bits {
+		bf.bits[i] = 0
+	}
+}
+
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	if bf.lock != nil {
+		bf.lock.RLock()
+		defer bf.lock.RUnlock()
+	}
+	e := newEncoder(kindBloomFilter, 8*(3+len(bf.bits)))
+	e.u64(bf.m)
+	e.u64(uint64(bf.k))
+	e.u64(bf.seed)
+	for _, w := range bf.bits {
+		e.u64(w)
+	}
+	return e.b, nil
+}
+
+// Load the filter from data made by MarshalBinary.
+// The lock of bf, if any, is kept.
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+	d := newDecoder(kindBloomFilter, data)
+	x := &BloomFilter{m: d.u64(), k: int(d.u64()), seed: d.u64()}
+	if d.err == nil && (x.m == 0 || x.m%64 != 0 || x.k <= 0 ||
+		x.m/64 != uint64(len(d.b))/8) {
+		d.fail("bad size")
+	}
+	if d.err == nil {
+		x.bits = make([]uint64, x.m/64)
+		for i := range x.bits {
+			x.bits[i] = d.u64()
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if bf.lock != nil {
+		bf.lock.Lock()
+		defer bf.lock.Unlock()
+	}
+	bf.m, bf.k, bf.seed, bf.bits = x.m, x.k, x.seed, x.bits
+	return nil
+}
+
+func (bf *BloomFilter) add(k keyHash) {
+	if bf.lock != nil {
+		bf.lock.Lock()
+		defer bf.lock.Unlock()
+	}
+	h1, h2 := doubleHash(k(bf.seed))
+	for i := 0; i < bf.k; i++ {
+		b := (h1 + uint64(i)*h2) % bf.m
+		bf.bits[b/64] |= 1 << (b % 64)
+	}
+}
+
+func (bf *BloomFilter) contains(k keyHash) bool {
+	if bf.lock != nil {
+		bf.lock.RLock()
+		defer bf.lock.RUnlock()
+	}
+	h1, h2 := doubleHash(k(bf.seed))
+	for i := 0; i < bf.k; i++ {
+		b := (h1 + uint64(i)*h2) % bf.m
+		if bf.bits[b/64]&(1<<(b%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Return a copy of bf without the lock.
+func (bf *BloomFilter) clone() *BloomFilter {
+	if bf.lock != nil {
+		bf.lock.RLock()
+		defer bf.lock.RUnlock()
+	}
+	return &BloomFilter{m: bf.m, k: bf.k, seed: bf.seed,
+		bits: append([]uint64(nil), bf.bits...)}
+}
@@ -0,0 +1,273 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+// CountMin is a Count-Min sketch, which estimates the counts of the keys
+// in a stream with depth rows of width counters.
+// The estimates are never less than the true counts,
+// and exceed them by at most e / width * Total()
+// with probability at least 1 - exp(-depth).
+type CountMin struct {
+	width, depth   int
+	isConservative bool
+	seed           uint64
+	counts         []uint64 // depth rows of width counters.
+	total          uint64
+	lock           *sync.RWMutex
+}
+
+// Return the width and depth for a sketch whose estimates exceed
+// the true counts by at most epsilon * Total() with probability
+// at least 1 - delta.
+// It panics if epsilon or delta is not in (0, 1).
+func CountMinForError(epsilon, delta float64) (width, depth int) {
+	if !(epsilon > 0 && epsilon < 1 && delta > 0 && delta < 1) {
+		panic(fmt.Errorf("%w: epsilon %v, delta %v",
+			gocontainer.ErrInvalidParam, epsilon, delta))
+	}
+	return int(math.Ceil(math.E / epsilon)),
+		int(math.Ceil(math.Log(1 / delta)))
+}
+
+// Create a CountMin sketch. With conservative update, an addition only
+// raises the counters that are below the new estimate, which makes
+// the estimates much more accurate.
+// It panics if width or depth is non-positive.
+func NewCountMin(width, depth int, isConservative bool, seed uint64,
+	isSync bool) *CountMin {
+	cm, err := NewCountMinE(width, depth, isConservative, seed, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return cm
+}
+
+// Same as NewCountMin, but return an error instead of panic.
+func NewCountMinE(width, depth int, isConservative bool, seed uint64,
+	isSync bool) (*CountMin, error) {
+	if width <= 0 || depth <= 0 {
+		return nil, fmt.Errorf("%w: width %d, depth %d",
+			gocontainer.ErrInvalidParam, width, depth)
+	}
+	cm := &CountMin{
+		width:          width,
+		depth:          depth,
+		isConservative: isConservative,
+		seed:           seed,
+		counts:         make([]uint64, width*depth),
+	}
+	if isSync {
+		cm.lock = new(sync.RWMutex)
+	}
+	return cm, nil
+}
+
+func (cm *CountMin) Width() int {
+	if cm.lock != nil {
+		cm.lock.RLock()
+		defer cm.lock.RUnlock()
+	}
+	return cm.width
+}
+
+func (cm *CountMin) Depth() int {
+	if cm.lock != nil {
+		cm.lock.RLock()
+		defer cm.lock.RUnlock()
+	}
+	return cm.depth
+}
+
+// Return the sum of the counts added.
+func (cm *CountMin) Total() uint64 {
+	if cm.lock != nil {
+		cm.lock.RLock()
+		defer cm.lock.RUnlock()
+	}
+	return cm.total
+}
+
+// Add n to the count of key, and return the new estimate of it.
+func (cm *CountMin) Add(key []byte, n uint64) uint64 {
+	return cm.add(bytesKey(key), n)
+}
+
+func (cm *CountMin) AddString(key string, n uint64) uint64 {
+	return cm.add(stringKey(key), n)
+}
+
+// Return the estimated count of key.
+func (cm *CountMin) Estimate(key []byte) uint64 {
+	return cm.estimate(bytesKey(key))
+}
+
+func (cm *CountMin) EstimateString(key string) uint64 {
+	return cm.estimate(stringKey(key))
+}
+
+// Add the counts of other to cm.
+// It returns an error wrapping gocontainer.ErrIncompatible if other has
+// a different width, depth or seed.
+func (cm *CountMin) Merge(other *CountMin) error {
+	x := other.clone()
+	if cm.lock != nil {
+		cm.lock.Lock()
+		defer cm.lock.Unlock()
+	}
+	if cm.width != x.width || cm.depth != x.depth || cm.seed != x.seed {
+		return fmt.Errorf("%w: %dx%d with seed %d, and %dx%d with seed %d",
+			gocontainer.ErrIncompatible, cm.width, cm.depth, cm.seed,
+			x.width, x.depth, x.seed)
+	}
+	for i, c := range x.counts {
+		cm.counts[i] += c
+	}
+	cm.total += x.total
+	return nil
+}
+
+// Set all the counts to zero.
+func (cm *CountMin) Reset() {
+	if cm.lock != nil {
+		cm.lock.Lock()
+		defer cm.lock.Unlock()
+	}
+	for i := range cm.counts {
+		cm.counts[i] = 0
+	}
+	cm.total = 0
+}
+
+func (cm *CountMin) MarshalBinary() ([]byte, error) {
+	if cm.lock != nil {
+		cm.lock.RLock()
+		defer cm.lock.RUnlock()
+	}
+	e := newEncoder(kindCountMin, 8*(4+len(cm.counts)))
+	cm.encode(e)
+	return e.b, nil
+}
+
+// Load the sketch from data made by MarshalBinary.
+// The lock of cm, if any, is kept.
+func (cm *CountMin) UnmarshalBinary(data []byte) error {
+	d := newDecoder(kindCountMin, data)
+	x := new(CountMin)
+	x.decode(d)
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if cm.lock != nil {
+		cm.lock.Lock()
+		defer cm.lock.Unlock()
+	}
+	cm.set(x)
+	return nil
+}
+
+func (cm *CountMin) encode(e *encoder) {
+	e.u64(uint64(cm.width))
+	e.u64(uint64(cm.depth))
+	if cm.isConservative {
+		e.u8(1)
+	} else {
+		e.u8(0)
+	}
+	e.u64(cm.seed)
+	e.u64(cm.total)
+	for _, c := range cm.counts {
+		e.u64(c)
+	}
+}
+
+func (cm *CountMin) decode(d *decoder) {
+	width, depth := d.u64(), d.u64()
+	cm.isConservative = d.u8() != 0
+	cm.seed, cm.total = d.u64(), d.u64()
+	if width == 0 || depth == 0 || width > uint64(len(d.b))/8/depth {
+		d.fail("bad size")
+		return
+	}
+	cm.width, cm.depth = int(width), int(depth)
+	cm.counts = make([]uint64, cm.width*cm.depth)
+	for i := range cm.counts {
+		cm.counts[i] = d.u64()
+	}
+}
+
+// Copy the fields of x, except the lock, to cm.
+// Call it with the lock held.
+func (cm *CountMin) set(x *CountMin) {
+	cm.width, cm.depth = x.width, x.depth
+	cm.isConservative, cm.seed = x.isConservative, x.seed
+	cm.counts, cm.total = x.counts, x.total
+}
+
+// Return a copy of cm without the lock.
+func (cm *CountMin) clone() *CountMin {
+	if cm.lock != nil {
+		cm.lock.RLock()
+		defer cm.lock.RUnlock()
+	}
+	x := new(CountMin)
+	x.set(cm)
+	x.counts = append([]uint64(nil), cm.counts...)
+	return x
+}
+
+func (cm *CountMin) add(k keyHash, n uint64) uint64 {
+	if cm.lock != nil {
+		cm.lock.Lock()
+		defer cm.lock.Unlock()
+	}
+	cm.total += n
+	h1, h2 := doubleHash(k(cm.seed))
+	if !cm.isConservative {
+		est := uint64(math.MaxUint64)
+		for i := 0; i < cm.depth; i++ {
+			c := &cm.counts[cm.index(i, h1, h2)]
+			*c += n
+			if *c < est {
+				est = *c
+			}
+		}
+		return est
+	}
+	est := cm.min(h1, h2) + n
+	for i := 0; i < cm.depth; i++ {
+		if c := &cm.counts[cm.index(i, h1, h2)]; *c < est {
+			*c = est
+		}
+	}
+	return est
+}
+
+func (cm *CountMin) estimate(k keyHash) uint64 {
+	if cm.lock != nil {
+		cm.lock.RLock()
+		defer cm.lock.RUnlock()
+	}
+	return cm.min(doubleHash(k(cm.seed)))
+}
+
+// Call it with the lock held.
+func (cm *CountMin) min(h1, h2 uint64) uint64 {
+	est := uint64(math.MaxUint64)
+	for i := 0; i < cm.depth; i++ {
+		if c := cm.counts[cm.index(i, h1, h2)]; c < est {
+			est = c
+		}
+	}
+	return est
+}
+
+// Return the index of the counter of row i.
+func (cm *CountMin) index(i int, h1, h2 uint64) int {
+	return i*cm.width + int((h1+uint64(i)*h2)%uint64(cm.width))
+}
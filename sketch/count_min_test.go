@@ -0,0 +1,200 @@
+package sketch
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+// A Zipf-distributed stream of keys, and their true counts.
+func zipfStream(seed int64, n int) ([]string, map[string]uint64) {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), 1.2, 1, 10000)
+	keys := make([]string, n)
+	counts := make(map[string]uint64)
+	for i := range keys {
+		keys[i] = "key" + strconv.FormatUint(z.Uint64(), 10)
+		counts[keys[i]]++
+	}
+	return keys, counts
+}
+
+func TestHash(t *testing.T) {
+	if hashString("abc", 1) != hashBytes([]byte("abc"), 1) {
+		t.Error("Hashes of a string and its bytes differ")
+	}
+	if hashString("abc", 1) == hashString("abc", 2) {
+		t.Error("Hashes with different seeds are equal")
+	}
+	if hashString("", 0) == hashString("\x00", 0) ||
+		hashString("12345678", 0) == hashString("12345678\x00", 0) {
+		t.Error("Hashes of different lengths are equal")
+	}
+}
+
+func TestCountMin(t *testing.T) {
+	const eps = 0.001
+	width, depth := CountMinForError(eps, 0.01)
+	keys, counts := zipfStream(1, 100000)
+	for _, isConservative := range []bool{false, true} {
+		cm := NewCountMin(width, depth, isConservative, 7, true)
+		for _, key := range keys {
+			cm.AddString(key, 1)
+		}
+		if cm.Total() != uint64(len(keys)) {
+			t.Errorf("Total: %d", cm.Total())
+		}
+		bad := 0
+		for key, c := range counts {
+			est := cm.EstimateString(key)
+			if est < c {
+				t.Fatalf("Estimate of %s is %d < %d", key, est, c)
+			}
+			if float64(est-c) > eps*float64(len(keys)) {
+				bad++
+			}
+		}
+		if bad > len(counts)/100 {
+			t.Errorf("conservative=%t: %d of %d estimates are beyond the error",
+				isConservative, bad, len(counts))
+		}
+	}
+
+	// Merge of two halves equals the sketch of the whole stream.
+	whole := NewCountMin(width, depth, false, 7, false)
+	a := NewCountMin(width, depth, false, 7, false)
+	b := NewCountMin(width, depth, false, 7, true)
+	for i, key := range keys {
+		whole.Add([]byte(key), 2)
+		if i%2 == 0 {
+			a.Add([]byte(key), 2)
+		} else {
+			b.Add([]byte(key), 2)
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	data, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCountMin(1, 1, false, 0, true)
+	if err = c.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for key := range counts {
+		if c.EstimateString(key) != whole.EstimateString(key) {
+			t.Fatalf("Estimate of %s after Merge and Unmarshal: %d, want %d",
+				key, c.EstimateString(key), whole.EstimateString(key))
+		}
+	}
+	if c.Total() != whole.Total() {
+		t.Errorf("Total: %d, want %d", c.Total(), whole.Total())
+	}
+	err = a.Merge(NewCountMin(width, depth, false, 8, false))
+	if !errors.Is(err, gocontainer.ErrIncompatible) {
+		t.Errorf("Merge with another seed: %v", err)
+	}
+	err = c.UnmarshalBinary(data[:len(data)-1])
+	if !errors.Is(err, gocontainer.ErrInvalidData) {
+		t.Errorf("Unmarshal of truncated data: %v", err)
+	}
+}
+
+func TestCountMinUnmarshalConcurrent(t *testing.T) {
+	a := NewCountMin(100, 4, false, 1, false)
+	a.AddString("x", 5)
+	data, _ := a.MarshalBinary()
+	cm := NewCountMin(100, 4, false, 2, true)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			cm.AddString(strconv.Itoa(i), 1)
+			cm.EstimateString("x")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if err := cm.UnmarshalBinary(data); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	if err := cm.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if est := cm.EstimateString("x"); est != 5 {
+		t.Errorf("Estimate after Unmarshal: %d, want 5", est)
+	}
+}
+
+func TestHeavyHitters(t *testing.T) {
+	keys, counts := zipfStream(2, 100000)
+	width, depth := CountMinForError(0.0005, 0.01)
+	hh := NewHeavyHitters(10, width, depth, 3, true)
+	a := NewHeavyHitters(10, width, depth, 3, false)
+	b := NewHeavyHitters(10, width, depth, 3, false)
+	for i, key := range keys {
+		hh.AddString(key, 1)
+		if i%2 == 0 {
+			a.Add([]byte(key), 1)
+		} else {
+			b.AddString(key, 1)
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	// The 5 most frequent keys are far ahead of the rest.
+	for _, x := range []*HeavyHitters{hh, a} {
+		top := x.Top()
+		for i := 0; i < 5; i++ {
+			key := "key" + strconv.Itoa(i)
+			if top[i].Key != key || top[i].Count < counts[key] {
+				t.Fatalf("Top %d: %v, want %s with %d",
+					i+1, top[i], key, counts[key])
+			}
+		}
+	}
+	data, _ := hh.MarshalBinary()
+	var x HeavyHitters
+	if err := x.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	top, want := x.Top(), hh.Top()
+	if len(top) != 10 {
+		t.Fatalf("Top after Unmarshal: %v", top)
+	}
+	for i := range top {
+		if top[i] != want[i] {
+			t.Fatalf("Top after Unmarshal: %v, want %v", top, want)
+		}
+	}
+	if x.EstimateString("key0") != hh.EstimateString("key0") {
+		t.Error("Estimate after Unmarshal differs")
+	}
+	if again, _ := x.MarshalBinary(); !bytes.Equal(again, data) {
+		t.Error("MarshalBinary is not deterministic")
+	}
+
+	small := NewHeavyHitters(2, 10, 2, 3, false)
+	small.AddString("a", 2)
+	small.AddString("b", 1)
+	data, _ = small.MarshalBinary()
+	// The keys are sorted, so the last entry is "b", followed by its count.
+	data[len(data)-9] = 'a'
+	err := x.UnmarshalBinary(data)
+	if !errors.Is(err, gocontainer.ErrInvalidData) {
+		t.Errorf("Unmarshal with a duplicate key: %v", err)
+	}
+}
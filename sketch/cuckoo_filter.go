@@ -0,0 +1,393 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+const (
+	bucketSize = 4
+	maxKicks   = 500
+)
+
+// CuckooFilter is a set of keys with false positives but no false
+// negatives, which, unlike BloomFilter, supports Delete.
+// It keeps a fingerprint of each key in one of two candidate buckets,
+// and moves the fingerprints between their buckets to make room
+// ("Cuckoo Filter: Practically Better Than Bloom" by Fan et al.).
+//
+// Only delete keys that have been added, or other keys may be lost.
+type CuckooFilter struct {
+	fpBits int
+	seed   uint64
+	mask   uint64   // Number of buckets - 1.
+	slots  []uint16 // bucketSize slots per bucket. 0 is empty.
+	n      int
+	rnd    uint64 // State of the random choices of the kicks.
+
+	// A fingerprint left out by a failed insertion.
+	// The filter is full while it is set.
+	victim      uint16
+	victimIndex uint64
+
+	lock *sync.RWMutex
+}
+
+// Return the number of fingerprint bits for a false positive rate of p,
+// clamped to [4, 16].
+// It panics if p is not in (0, 1).
+func CuckooFilterForError(p float64) (fingerprintBits int) {
+	if !(p > 0 && p < 1) {
+		panic(fmt.Errorf("%w: p %v", gocontainer.ErrInvalidParam, p))
+	}
+	f := int(math.Ceil(math.Log2(2 * bucketSize / p)))
+	if f < 4 {
+		return 4
+	} else if f > 16 {
+		return 16
+	}
+	return f
+}
+
+// Create a CuckooFilter for about capacity keys,
+// with fingerprints of fingerprintBits in [4, 16].
+// The number of buckets is a power of 2, so the actual capacity
+// may be up to twice capacity.
+// It panics if capacity is non-positive, or fingerprintBits is out of range.
+func NewCuckooFilter(capacity, fingerprintBits int, seed uint64,
+	isSync bool) *CuckooFilter {
+	cf, err := NewCuckooFilterE(capacity, fingerprintBits, seed, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return cf
+}
+
+// Same as NewCuckooFilter, but return an error instead of panic.
+func NewCuckooFilterE(capacity, fingerprintBits int, seed uint64,
+	isSync bool) (*CuckooFilter, error) {
+	if capacity <= 0 || fingerprintBits < 4 || fingerprintBits > 16 {
+		return nil, fmt.Errorf("%w: capacity %d, fingerprint bits %d",
+			gocontainer.ErrInvalidParam, capacity, fingerprintBits)
+	}
+	// The load factor can reach about 95% with 4 slots per bucket.
+	nb := uint64(1)
+	for float64(nb*bucketSize)*0.95 < float64(capacity) {
+		nb <<= 1
+	}
+	cf := &CuckooFilter{
+		fpBits: fingerprintBits,
+		seed:   seed,
+		mask:   nb - 1,
+		slots:  make([]uint16, nb*bucketSize),
+		rnd:    seed | 1,
+	}
+	if isSync {
+		cf.lock = new(sync.RWMutex)
+	}
+	return cf, nil
+}
+
+// Return the number of keys in the filter.
+func (cf *CuckooFilter) Len() int {
+	if cf.lock != nil {
+		cf.lock.RLock()
+		defer cf.lock.RUnlock()
+	}
+	return cf.n
+}
+
+// Return the number of slots, the maximum number of keys.
+func (cf *CuckooFilter) Cap() int {
+	if cf.lock != nil {
+		cf.lock.RLock()
+		defer cf.lock.RUnlock()
+	}
+	return len(cf.slots)
+}
+
+// Add key, and return true if it is added.
+// It returns false if the filter is full, without adding key.
+// A key can be added more than once, and is then deleted as many times.
+func (cf *CuckooFilter) Add(key []byte) bool {
+	return cf.add(bytesKey(key))
+}
+
+func (cf *CuckooFilter) AddString(key string) bool {
+	return cf.add(stringKey(key))
+}
+
+// Return false if key is definitely not in the filter,
+// or true if it probably is.
+func (cf *CuckooFilter) Contains(key []byte) bool {
+	return cf.contains(bytesKey(key))
+}
+
+func (cf *CuckooFilter) ContainsString(key string) bool {
+	return cf.contains(stringKey(key))
+}
+
+// Delete key, and return true if it was probably in the filter.
+func (cf *CuckooFilter) Delete(key []byte) bool {
+	return cf.delete(bytesKey(key))
+}
+
+func (cf *CuckooFilter) DeleteString(key string) bool {
+	return cf.delete(stringKey(key))
+}
+
+// Add the keys of other to cf.
+// It returns an error wrapping gocontainer.ErrIncompatible if other has
+// a different size, fingerprint bits or seed,
+// or an error wrapping gocontainer.ErrFull if cf becomes full,
+// in which case some of the keys of other are not added.
+func (cf *CuckooFilter) Merge(other *CuckooFilter) error {
+	x := other.clone()
+	if cf.lock != nil {
+		cf.lock.Lock()
+		defer cf.lock.Unlock()
+	}
+	if cf.fpBits != x.fpBits || cf.mask != x.mask || cf.seed != x.seed {
+		return fmt.Errorf("%w: %d buckets, %d bits, seed %d, "+
+			"and %d buckets, %d bits, seed %d", gocontainer.ErrIncompatible,
+			cf.mask+1, cf.fpBits, cf.seed, x.mask+1, x.fpBits, x.seed)
+	}
+	insert := func(i uint64, fp uint16) error {
+		if cf.victim != 0 {
+			return fmt.Errorf("%w: cuckoo filter of %d slots",
+				gocontainer.ErrFull, len(cf.slots))
+		}
+		cf.insert(i, fp)
+		return nil
+	}
+	for j, fp := range x.slots {
+		if fp != 0 {
+			if err := insert(uint64(j/bucketSize), fp); err != nil {
+				return err
+			}
+		}
+	}
+	if x.victim != 0 {
+		return insert(x.victimIndex, x.victim)
+	}
+	return nil
+}
+
+func (cf *CuckooFilter) Reset() {
+	if cf.lock != nil {
+		cf.lock.Lock()
+		defer cf.lock.Unlock()
+	}
+	for i := range cf.slots {
+		cf.slots[i] = 0
+	}
+	cf.n, cf.victim, cf.victimIndex = 0, 0, 0
+}
+
+func (cf *CuckooFilter) MarshalBinary() ([]byte, error) {
+	if cf.lock != nil {
+		cf.lock.RLock()
+		defer cf.lock.RUnlock()
+	}
+	e := newEncoder(kindCuckooFilter, 8*6+2*len(cf.slots))
+	e.u8(uint8(cf.fpBits))
+	e.u64(cf.seed)
+	e.u64(cf.mask + 1)
+	e.u64(uint64(cf.n))
+	e.u64(cf.rnd)
+	e.u64(uint64(cf.victim))
+	e.u64(cf.victimIndex)
+	for _, fp := range cf.slots {
+		e.u8(uint8(fp))
+		e.u8(uint8(fp >> 8))
+	}
+	return e.b, nil
+}
+
+// Load the filter from data made by MarshalBinary.
+// The lock of cf, if any, is kept.
+func (cf *CuckooFilter) UnmarshalBinary(data []byte) error {
+	d := newDecoder(kindCuckooFilter, data)
+	x := &CuckooFilter{fpBits: int(d.u8()), seed: d.u64()}
+	nb := d.u64()
+	x.mask = nb - 1
+	n := d.u64()
+	x.rnd = d.u64()
+	victim := d.u64()
+	x.victim, x.victimIndex = uint16(victim), d.u64()
+	if d.err == nil && (x.fpBits < 4 || x.fpBits > 16 || nb == 0 ||
+		nb&x.mask != 0 || nb != uint64(len(d.b))/(2*bucketSize) ||
+		victim >= 1<<uint(x.fpBits) || x.victimIndex > x.mask) {
+		d.fail("bad size")
+	}
+	// xorshift64* stays at 0 forever.
+	if d.err == nil && x.rnd == 0 {
+		d.fail("bad random state")
+	}
+	if d.err == nil {
+		x.slots = make([]uint16, nb*bucketSize)
+		for i := range x.slots {
+			x.slots[i] = uint16(d.u8()) | uint16(d.u8())<<8
+			if uint64(x.slots[i]) >= 1<<uint(x.fpBits) {
+				d.fail("bad fingerprint")
+				break
+			} else if x.slots[i] != 0 {
+				x.n++
+			}
+		}
+		if x.victim != 0 {
+			x.n++
+		}
+		if d.err == nil && n != uint64(x.n) {
+			d.fail("bad number of keys")
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if cf.lock != nil {
+		cf.lock.Lock()
+		defer cf.lock.Unlock()
+	}
+	cf.fpBits, cf.seed, cf.mask, cf.slots = x.fpBits, x.seed, x.mask, x.slots
+	cf.n, cf.rnd = x.n, x.rnd
+	cf.victim, cf.victimIndex = x.victim, x.victimIndex
+	return nil
+}
+
+// Return the fingerprint and the first bucket of hash h.
+func (cf *CuckooFilter) locate(h uint64) (fp uint16, i uint64) {
+	fp = uint16(h>>48) & (1<<uint(cf.fpBits) - 1)
+	if fp == 0 {
+		fp = 1
+	}
+	return fp, h & cf.mask
+}
+
+// Return the other bucket of fp in bucket i.
+func (cf *CuckooFilter) alt(i uint64, fp uint16) uint64 {
+	return (i ^ mix(uint64(fp)^cf.seed)) & cf.mask
+}
+
+func (cf *CuckooFilter) add(k keyHash) bool {
+	if cf.lock != nil {
+		cf.lock.Lock()
+		defer cf.lock.Unlock()
+	}
+	if cf.victim != 0 {
+		return false
+	}
+	fp, i := cf.locate(k(cf.seed))
+	cf.insert(i, fp)
+	return true
+}
+
+// Insert fp into bucket i or its alternative, kicking other fingerprints
+// if both are full. If it fails, the last kicked one becomes the victim.
+// Call it with the lock held, and without a victim.
+func (cf *CuckooFilter) insert(i uint64, fp uint16) {
+	cf.n++
+	if cf.put(i, fp) || cf.put(cf.alt(i, fp), fp) {
+		return
+	}
+	if cf.nextRand()&1 == 0 {
+		i = cf.alt(i, fp)
+	}
+	for kick := 0; kick < maxKicks; kick++ {
+		j := i*bucketSize + cf.nextRand()%bucketSize
+		fp, cf.slots[j] = cf.slots[j], fp
+		i = cf.alt(i, fp)
+		if cf.put(i, fp) {
+			return
+		}
+	}
+	cf.victim, cf.victimIndex = fp, i
+}
+
+// Put fp into an empty slot of bucket i, and return true if it succeeds.
+func (cf *CuckooFilter) put(i uint64, fp uint16) bool {
+	b := cf.slots[i*bucketSize : (i+1)*bucketSize]
+	for j, x := range b {
+		if x == 0 {
+			b[j] = fp
+			return true
+		}
+	}
+	return false
+}
+
+func (cf *CuckooFilter) contains(k keyHash) bool {
+	if cf.lock != nil {
+		cf.lock.RLock()
+		defer cf.lock.RUnlock()
+	}
+	fp, i := cf.locate(k(cf.seed))
+	i2 := cf.alt(i, fp)
+	if cf.victim == fp && (cf.victimIndex == i || cf.victimIndex == i2) {
+		return true
+	}
+	return cf.find(i, fp) >= 0 || cf.find(i2, fp) >= 0
+}
+
+func (cf *CuckooFilter) delete(k keyHash) bool {
+	if cf.lock != nil {
+		cf.lock.Lock()
+		defer cf.lock.Unlock()
+	}
+	fp, i := cf.locate(k(cf.seed))
+	i2 := cf.alt(i, fp)
+	if cf.victim == fp && (cf.victimIndex == i || cf.victimIndex == i2) {
+		cf.victim = 0
+		cf.n--
+		return true
+	}
+	j := cf.find(i, fp)
+	if j < 0 {
+		j = cf.find(i2, fp)
+	}
+	if j < 0 {
+		return false
+	}
+	cf.slots[j] = 0
+	cf.n--
+	// Make room for the victim.
+	if cf.victim != 0 {
+		fp, i := cf.victim, cf.victimIndex
+		cf.victim = 0
+		cf.n--
+		cf.insert(i, fp)
+	}
+	return true
+}
+
+// Return the index in slots of fp in bucket i, or -1 if not found.
+func (cf *CuckooFilter) find(i uint64, fp uint16) int {
+	for j := i * bucketSize; j < (i+1)*bucketSize; j++ {
+		if cf.slots[j] == fp {
+			return int(j)
+		}
+	}
+	return -1
+}
+
+// xorshift64*.
+func (cf *CuckooFilter) nextRand() uint64 {
+	cf.rnd ^= cf.rnd >> 12
+	cf.rnd ^= cf.rnd << 25
+	cf.rnd ^= cf.rnd >> 27
+	return cf.rnd * 0x2545F4914F6CDD1D
+}
+
+func (cf *CuckooFilter) clone() *CuckooFilter {
+	if cf.lock != nil {
+		cf.lock.RLock()
+		defer cf.lock.RUnlock()
+	}
+	x := *cf
+	x.slots = append([]uint16(nil), cf.slots...)
+	x.lock = nil
+	return &x
+}
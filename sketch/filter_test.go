@@ -0,0 +1,169 @@
+package sketch
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestBloomFilter(t *testing.T) {
+	const n, p = 10000, 0.01
+	m, k := BloomFilterForError(n, p)
+	if k != 7 {
+		t.Errorf("k for 1%%: %d", k)
+	}
+	a := NewBloomFilter(m, k, 1, true)
+	b := NewBloomFilter(m, k, 1, false)
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			a.AddString(strconv.Itoa(i))
+		} else {
+			b.Add([]byte(strconv.Itoa(i)))
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := a.MarshalBinary()
+	var bf BloomFilter
+	if err := bf.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if !bf.ContainsString(strconv.Itoa(i)) {
+			t.Fatalf("False negative: %d", i)
+		}
+	}
+	fp := 0
+	for i := n; i < 11*n; i++ {
+		if bf.Contains([]byte(strconv.Itoa(i))) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); rate > 1.5*p {
+		t.Errorf("False positive rate: %v", rate)
+	}
+	if c := bf.Count(); c < n*95/100 || c > n*105/100 {
+		t.Errorf("Count: %d", c)
+	}
+	err := a.Merge(NewBloomFilter(m, k+1, 1, false))
+	if !errors.Is(err, gocontainer.ErrIncompatible) {
+		t.Errorf("Merge with another k: %v", err)
+	}
+}
+
+func TestCuckooFilter(t *testing.T) {
+	const n, p = 10000, 0.01
+	f := CuckooFilterForError(p)
+	if f != 10 {
+		t.Errorf("Fingerprint bits for 1%%: %d", f)
+	}
+	a := NewCuckooFilter(n, f, 1, true)
+	b := NewCuckooFilter(n, f, 1, false)
+	for i := 0; i < n; i++ {
+		x := a
+		if i%2 == 1 {
+			x = b
+		}
+		if !x.AddString(strconv.Itoa(i)) {
+			t.Fatalf("Add(%d) failed", i)
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := a.MarshalBinary()
+	var cf CuckooFilter
+	if err := cf.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if cf.Len() != n {
+		t.Errorf("Len: %d", cf.Len())
+	}
+	for i := 0; i < n; i++ {
+		if !cf.Contains([]byte(strconv.Itoa(i))) {
+			t.Fatalf("False negative: %d", i)
+		}
+	}
+	fp := 0
+	for i := n; i < 11*n; i++ {
+		if cf.ContainsString(strconv.Itoa(i)) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / (10 * n); rate > p {
+		t.Errorf("False positive rate: %v", rate)
+	}
+	for i := 0; i < n; i += 2 {
+		if !cf.DeleteString(strconv.Itoa(i)) {
+			t.Fatalf("Delete(%d) failed", i)
+		}
+	}
+	for i := 1; i < n; i += 2 {
+		if !cf.ContainsString(strconv.Itoa(i)) {
+			t.Fatalf("False negative after Delete: %d", i)
+		}
+	}
+	if cf.Len() != n/2 {
+		t.Errorf("Len after Delete: %d", cf.Len())
+	}
+	// After the header: the fingerprint bits, the seed, the number of
+	// buckets, the number of keys, the random state, the victim and
+	// its bucket, and then the slots.
+	data, _ = cf.MarshalBinary()
+	corrupt := func(i int, b ...byte) []byte {
+		x := append([]byte(nil), data...)
+		copy(x[i:], b)
+		return x
+	}
+	for name, x := range map[string][]byte{
+		"zero random state": corrupt(31, 0, 0, 0, 0, 0, 0, 0, 0),
+		"number of keys":    corrupt(23, 0xFF),
+		"fingerprint":       corrupt(55, 0xFF, 0xFF),
+		"victim":            corrupt(39, 0xFF, 0xFF),
+	} {
+		err := cf.UnmarshalBinary(x)
+		if !errors.Is(err, gocontainer.ErrInvalidData) {
+			t.Errorf("Unmarshal with a bad %s: %v", name, err)
+		}
+	}
+	if cf.Len() != n/2 {
+		t.Errorf("Len after bad Unmarshal: %d", cf.Len())
+	}
+}
+
+func TestCuckooFilterFull(t *testing.T) {
+	cf := NewCuckooFilter(100, 12, 2, false)
+	added := 0
+	for cf.Add([]byte(strconv.Itoa(added))) {
+		added++
+	}
+	if added < cf.Cap()*9/10 || added > cf.Cap() {
+		t.Errorf("Added %d keys to %d slots", added, cf.Cap())
+	}
+	// Nothing is lost, including the victim.
+	for i := 0; i < added; i++ {
+		if !cf.Contains([]byte(strconv.Itoa(i))) {
+			t.Fatalf("False negative: %d", i)
+		}
+	}
+	// Deleting makes room again.
+	cf.Delete([]byte("0"))
+	cf.Delete([]byte("1"))
+	if !cf.Add([]byte("new")) {
+		t.Error("Add after Delete failed")
+	}
+	err := cf.Merge(NewCuckooFilter(100, 12, 2, false))
+	if err != nil {
+		t.Errorf("Merge of an empty filter: %v", err)
+	}
+	other := NewCuckooFilter(100, 12, 2, false)
+	for i := 0; i < 50; i++ {
+		other.AddString("other" + strconv.Itoa(i))
+	}
+	if err = cf.Merge(other); !errors.Is(err, gocontainer.ErrFull) {
+		t.Errorf("Merge into a full filter: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package sketch
+
+import (
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	prime1 uint64 = 0x9E3779B185EBCA87
+	prime2 uint64 = 0xC2B2AE3D27D4EB4F
+	prime3 uint64 = 0x165667B19E3779F9
+)
+
+// Return a 64-bit hash of s with seed.
+func hashString(s string, seed uint64) uint64 {
+	h := seed + prime3 + uint64(len(s))*prime1
+	for ; len(s) >= 8; s = s[8:] {
+		k := uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 |
+			uint64(s[3])<<24 | uint64(s[4])<<32 | uint64(s[5])<<40 |
+			uint64(s[6])<<48 | uint64(s[7])<<56
+		h = round(h, k)
+	}
+	if len(s) > 0 {
+		var k uint64
+		for i := len(s) - 1; i >= 0; i-- {
+			k = k<<8 | uint64(s[i])
+		}
+		h = round(h, k)
+	}
+	return mix(h)
+}
+
+func hashBytes(b []byte, seed uint64) uint64 {
+	// The string is only read, and not kept.
+	return hashString(*(*string)(unsafe.Pointer(&b)), seed)
+}
+
+// A key, to be hashed with the seed of a sketch.
+// The seed is only read with the lock of the sketch held,
+// as UnmarshalBinary may change it.
+type keyHash func(seed uint64) uint64
+
+func bytesKey(b []byte) keyHash {
+	return func(seed uint64) uint64 { return hashBytes(b, seed) }
+}
+
+func stringKey(s string) keyHash {
+	return func(seed uint64) uint64 { return hashString(s, seed) }
+}
+
+func round(h, k uint64) uint64 {
+	h ^= bits.RotateLeft64(k*prime2, 31) * prime1
+	return bits.RotateLeft64(h, 27)*prime1 + prime3
+}
+
+// The finalizer of MurmurHash3, to spread the bits of h.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xFF51AFD7ED558CCD
+	h ^= h >> 33
+	h *= 0xC4CEB9FE1A85EC53
+	h ^= h >> 33
+	return h
+}
+
+// Return the two hashes for double hashing: the i-th hash is h1 + i*h2.
+func doubleHash(h uint64) (h1, h2 uint64) {
+	return h, mix(h^prime2) | 1
+}
@@ -0,0 +1,298 @@
+package sketch
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+	iheap "github.com/donyori/gocontainer/internal/heap"
+)
+
+type HeavyHitter struct {
+	Key   string
+	Count uint64 // Estimated by the Count-Min sketch.
+}
+
+// A tracked key, in the min-heap of HeavyHitters.
+type hhItem struct {
+	HeavyHitter
+	idx int
+}
+
+func (it *hhItem) Less(another interface{}) bool {
+	a := another.(*hhItem)
+	return it.Count < a.Count || it.Count == a.Count && it.Key > a.Key
+}
+
+func (it *hhItem) Index() int {
+	return it.idx
+}
+
+func (it *hhItem) UpdateIndex(idx int) {
+	it.idx = idx
+}
+
+// HeavyHitters tracks the k most frequent keys of a stream,
+// by estimating the counts with a conservative Count-Min sketch,
+// and keeping the k keys with the greatest estimates in a min-heap.
+type HeavyHitters struct {
+	k     int
+	cm    *CountMin
+	h     *iheap.MinHeap // Of *hhItem.
+	items map[string]*hhItem
+	lock  *sync.RWMutex
+}
+
+// Create a HeavyHitters tracking k keys, with a Count-Min sketch
+// of width and depth. See CountMinForError for the width and depth.
+// It panics if k, width or depth is non-positive.
+func NewHeavyHitters(k, width, depth int, seed uint64,
+	isSync bool) *HeavyHitters {
+	hh, err := NewHeavyHittersE(k, width, depth, seed, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return hh
+}
+
+// Same as NewHeavyHitters, but return an error instead of panic.
+func NewHeavyHittersE(k, width, depth int, seed uint64, isSync bool) (
+	*HeavyHitters, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("%w: k %d", gocontainer.ErrInvalidParam, k)
+	}
+	cm, err := NewCountMinE(width, depth, true, seed, false)
+	if err != nil {
+		return nil, err
+	}
+	hh := &HeavyHitters{
+		k:     k,
+		cm:    cm,
+		h:     iheap.NewMinHeap(k, true),
+		items: make(map[string]*hhItem, k),
+	}
+	if isSync {
+		hh.lock = new(sync.RWMutex)
+	}
+	return hh, nil
+}
+
+func (hh *HeavyHitters) K() int {
+	if hh.lock != nil {
+		hh.lock.RLock()
+		defer hh.lock.RUnlock()
+	}
+	return hh.k
+}
+
+// Add n to the count of key, and return the new estimate of it.
+func (hh *HeavyHitters) Add(key []byte, n uint64) uint64 {
+	if hh.lock != nil {
+		hh.lock.Lock()
+		defer hh.lock.Unlock()
+	}
+	est := hh.cm.Add(key, n)
+	if it := hh.items[string(key)]; it != nil {
+		it.Count = est
+		heap.Fix(hh.h, it.idx)
+	} else {
+		hh.offer(string(key), est)
+	}
+	return est
+}
+
+func (hh *HeavyHitters) AddString(key string, n uint64) uint64 {
+	if hh.lock != nil {
+		hh.lock.Lock()
+		defer hh.lock.Unlock()
+	}
+	est := hh.cm.AddString(key, n)
+	if it := hh.items[key]; it != nil {
+		it.Count = est
+		heap.Fix(hh.h, it.idx)
+	} else {
+		hh.offer(key, est)
+	}
+	return est
+}
+
+// Return the estimated count of key, tracked or not.
+func (hh *HeavyHitters) Estimate(key []byte) uint64 {
+	if hh.lock != nil {
+		hh.lock.RLock()
+		defer hh.lock.RUnlock()
+	}
+	return hh.cm.Estimate(key)
+}
+
+func (hh *HeavyHitters) EstimateString(key string) uint64 {
+	if hh.lock != nil {
+		hh.lock.RLock()
+		defer hh.lock.RUnlock()
+	}
+	return hh.cm.EstimateString(key)
+}
+
+// Return the sum of the counts added.
+func (hh *HeavyHitters) Total() uint64 {
+	if hh.lock != nil {
+		hh.lock.RLock()
+		defer hh.lock.RUnlock()
+	}
+	return hh.cm.Total()
+}
+
+// Return the tracked keys in descending order of their counts.
+func (hh *HeavyHitters) Top() []HeavyHitter {
+	if hh.lock != nil {
+		hh.lock.RLock()
+		defer hh.lock.RUnlock()
+	}
+	top := make([]HeavyHitter, 0, len(hh.items))
+	for _, it := range hh.items {
+		top = append(top, it.HeavyHitter)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].Count > top[j].Count ||
+			top[i].Count == top[j].Count && top[i].Key < top[j].Key
+	})
+	return top
+}
+
+// Add the counts of other to hh, and re-select the top k keys
+// among the keys tracked by both.
+// It returns an error wrapping gocontainer.ErrIncompatible if other has
+// a different k, or its sketch is incompatible.
+func (hh *HeavyHitters) Merge(other *HeavyHitters) error {
+	if hh.k != other.k {
+		return fmt.Errorf("%w: k %d and %d",
+			gocontainer.ErrIncompatible, hh.k, other.k)
+	}
+	x := other.clone()
+	if hh.lock != nil {
+		hh.lock.Lock()
+		defer hh.lock.Unlock()
+	}
+	if err := hh.cm.Merge(x.cm); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(hh.items)+len(x.items))
+	for key := range hh.items {
+		keys = append(keys, key)
+	}
+	for key := range x.items {
+		if hh.items[key] == nil {
+			keys = append(keys, key)
+		}
+	}
+	hh.h.Clear()
+	hh.items = make(map[string]*hhItem, hh.k)
+	for _, key := range keys {
+		hh.offer(key, hh.cm.EstimateString(key))
+	}
+	return nil
+}
+
+func (hh *HeavyHitters) Reset() {
+	if hh.lock != nil {
+		hh.lock.Lock()
+		defer hh.lock.Unlock()
+	}
+	hh.cm.Reset()
+	hh.h.Clear()
+	hh.items = make(map[string]*hhItem, hh.k)
+}
+
+func (hh *HeavyHitters) MarshalBinary() ([]byte, error) {
+	if hh.lock != nil {
+		hh.lock.RLock()
+		defer hh.lock.RUnlock()
+	}
+	e := newEncoder(kindHeavyHitters, 8*(5+len(hh.cm.counts)))
+	e.u64(uint64(hh.k))
+	hh.cm.encode(e)
+	// Sorted, for a deterministic output.
+	keys := make([]string, 0, len(hh.items))
+	for key := range hh.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	e.u64(uint64(len(keys)))
+	for _, key := range keys {
+		e.bytes([]byte(key))
+		e.u64(hh.items[key].Count)
+	}
+	return e.b, nil
+}
+
+// Load the sketch from data made by MarshalBinary.
+// The lock of hh, if any, is kept.
+func (hh *HeavyHitters) UnmarshalBinary(data []byte) error {
+	d := newDecoder(kindHeavyHitters, data)
+	k := d.u64()
+	cm := new(CountMin)
+	cm.decode(d)
+	n := d.u64()
+	if d.err == nil && (k == 0 || n > k || k > uint64(len(data))) {
+		d.fail("bad number of keys")
+	}
+	x := &HeavyHitters{
+		k:     int(k),
+		cm:    cm,
+		h:     iheap.NewMinHeap(int(k), true),
+		items: make(map[string]*hhItem, int(k)),
+	}
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		key := string(d.bytes())
+		count := d.u64()
+		if x.items[key] != nil {
+			d.fail("duplicate key")
+			break
+		}
+		x.offer(key, count)
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if hh.lock != nil {
+		hh.lock.Lock()
+		defer hh.lock.Unlock()
+	}
+	hh.k, hh.cm, hh.h, hh.items = x.k, x.cm, x.h, x.items
+	return nil
+}
+
+// Track key if it is among the top k. Call it with the lock held.
+func (hh *HeavyHitters) offer(key string, count uint64) {
+	it := &hhItem{HeavyHitter: HeavyHitter{key, count}}
+	if hh.h.Len() < hh.k {
+		heap.Push(hh.h, it)
+		hh.items[key] = it
+		return
+	}
+	min := hh.h.Top().(*hhItem)
+	if !min.Less(it) {
+		return
+	}
+	delete(hh.items, min.Key)
+	hh.h.UpdateTop(it)
+	hh.items[key] = it
+}
+
+// Return a copy of hh, to merge it without holding its lock.
+func (hh *HeavyHitters) clone() *HeavyHitters {
+	if hh.lock != nil {
+		hh.lock.RLock()
+		defer hh.lock.RUnlock()
+	}
+	cm := *hh.cm
+	cm.counts = append([]uint64(nil), cm.counts...)
+	x := &HeavyHitters{k: hh.k, cm: &cm,
+		items: make(map[string]*hhItem, len(hh.items))}
+	for key, it := range hh.items {
+		x.items[key] = &hhItem{HeavyHitter: it.HeavyHitter}
+	}
+	return x
+}
@@ -0,0 +1,357 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+
+	"github.com/donyori/gocontainer"
+)
+
+const (
+	minPrecision    = 4
+	maxPrecision    = 18
+	sparsePrecision = 25 // Precision of the sparse representation.
+)
+
+// HyperLogLog estimates the number of distinct keys in a stream,
+// with a relative standard error of about 1.04 / sqrt(2^precision),
+// in 2^precision bytes.
+//
+// As HyperLogLog++, it uses 64-bit hashes, and a sparse representation
+// with a higher precision for small cardinalities, which is accurate
+// and takes less memory. Instead of the empirical bias correction
+// of HyperLogLog++, the dense estimate uses the improved estimator
+// by Otmar Ertl, which is unbiased over the whole range without any table.
+type HyperLogLog struct {
+	p      uint8
+	seed   uint64
+	sparse map[uint32]uint8 // Index to rank at sparsePrecision, or nil.
+	regs   []uint8          // Dense registers, or nil if sparse.
+	lock   *sync.RWMutex
+}
+
+// Return the precision for a relative standard error of stdErr,
+// clamped to [4, 18].
+func HyperLogLogPrecisionForError(stdErr float64) uint8 {
+	if !(stdErr > 0) {
+		panic(fmt.Errorf("%w: standard error %v",
+			gocontainer.ErrInvalidParam, stdErr))
+	}
+	p := math.Ceil(2 * math.Log2(1.04/stdErr))
+	if p < minPrecision {
+		return minPrecision
+	} else if p > maxPrecision {
+		return maxPrecision
+	}
+	return uint8(p)
+}
+
+// Create a HyperLogLog with precision in [4, 18].
+// It panics if precision is out of range.
+func NewHyperLogLog(precision uint8, seed uint64,
+	isSync bool) *HyperLogLog {
+	hll, err := NewHyperLogLogE(precision, seed, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return hll
+}
+
+// Same as NewHyperLogLog, but return an error instead of panic.
+func NewHyperLogLogE(precision uint8, seed uint64, isSync bool) (
+	*HyperLogLog, error) {
+	if precision < minPrecision || precision > maxPrecision {
+		return nil, fmt.Errorf("%w: precision %d",
+			gocontainer.ErrInvalidParam, precision)
+	}
+	hll := &HyperLogLog{
+		p:      precision,
+		seed:   seed,
+		sparse: make(map[uint32]uint8),
+	}
+	if isSync {
+		hll.lock = new(sync.RWMutex)
+	}
+	return hll, nil
+}
+
+func (hll *HyperLogLog) Precision() uint8 {
+	if hll.lock != nil {
+		hll.lock.RLock()
+		defer hll.lock.RUnlock()
+	}
+	return hll.p
+}
+
+func (hll *HyperLogLog) Add(key []byte) {
+	hll.add(bytesKey(key))
+}
+
+func (hll *HyperLogLog) AddString(key string) {
+	hll.add(stringKey(key))
+}
+
+// Return the estimated number of distinct keys added.
+func (hll *HyperLogLog) Count() uint64 {
+	if hll.lock != nil {
+		hll.lock.RLock()
+		defer hll.lock.RUnlock()
+	}
+	if hll.regs == nil {
+		// Linear counting at the sparse precision.
+		m := float64(uint64(1) << sparsePrecision)
+		return uint64(math.Round(m * math.Log(m/(m-float64(len(hll.sparse))))))
+	}
+	return uint64(math.Round(ertlEstimate(hll.regs, 64-int(hll.p))))
+}
+
+// Add the keys of other to hll.
+// It returns an error wrapping gocontainer.ErrIncompatible if other has
+// a different precision or seed.
+func (hll *HyperLogLog) Merge(other *HyperLogLog) error {
+	x := other.clone()
+	if hll.lock != nil {
+		hll.lock.Lock()
+		defer hll.lock.Unlock()
+	}
+	if hll.p != x.p || hll.seed != x.seed {
+		return fmt.Errorf("%w: precision %d with seed %d, "+
+			"and precision %d with seed %d",
+			gocontainer.ErrIncompatible, hll.p, hll.seed, x.p, x.seed)
+	}
+	if x.regs != nil {
+		hll.toDense()
+		for i, r := range x.regs {
+			if r > hll.regs[i] {
+				hll.regs[i] = r
+			}
+		}
+		return nil
+	}
+	for k, r := range x.sparse {
+		hll.addSparse(k, r)
+	}
+	return nil
+}
+
+func (hll *HyperLogLog) Reset() {
+	if hll.lock != nil {
+		hll.lock.Lock()
+		defer hll.lock.Unlock()
+	}
+	hll.sparse = make(map[uint32]uint8)
+	hll.regs = nil
+}
+
+func (hll *HyperLogLog) MarshalBinary() ([]byte, error) {
+	if hll.lock != nil {
+		hll.lock.RLock()
+		defer hll.lock.RUnlock()
+	}
+	e := newEncoder(kindHyperLogLog, 18+5*len(hll.sparse)+len(hll.regs))
+	e.u8(hll.p)
+	e.u64(hll.seed)
+	if hll.regs != nil {
+		e.u8(1)
+		e.bytes(hll.regs)
+		return e.b, nil
+	}
+	e.u8(0)
+	// Sorted, for a deterministic output.
+	keys := make([]uint32, 0, len(hll.sparse))
+	for k := range hll.sparse {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	e.u64(uint64(len(keys)))
+	for _, k := range keys {
+		e.u64(uint64(k)<<8 | uint64(hll.sparse[k]))
+	}
+	return e.b, nil
+}
+
+// Load the sketch from data made by MarshalBinary.
+// The lock of hll, if any, is kept.
+func (hll *HyperLogLog) UnmarshalBinary(data []byte) error {
+	d := newDecoder(kindHyperLogLog, data)
+	x := &HyperLogLog{p: d.u8(), seed: d.u64()}
+	if d.err == nil && (x.p < minPrecision || x.p > maxPrecision) {
+		d.fail("bad precision")
+	}
+	if d.u8() != 0 {
+		x.regs = append([]uint8(nil), d.bytes()...)
+		if d.err == nil && len(x.regs) != 1<<x.p {
+			d.fail("bad number of registers")
+		}
+		for _, r := range x.regs {
+			// A rank is at most q + 1, with q = 64 - p.
+			if int(r) > 65-int(x.p) {
+				d.fail("bad register")
+				break
+			}
+		}
+	} else {
+		n := d.u64()
+		if n > uint64(len(d.b))/8 {
+			d.fail("bad number of entries")
+		}
+		x.sparse = make(map[uint32]uint8, int(n))
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			v := d.u64()
+			k, r := v>>8, uint8(v)
+			if k >= 1<<sparsePrecision || r == 0 || r > 65-sparsePrecision {
+				d.fail("bad entry")
+				break
+			}
+			x.sparse[uint32(k)] = r
+		}
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if hll.lock != nil {
+		hll.lock.Lock()
+		defer hll.lock.Unlock()
+	}
+	hll.p, hll.seed, hll.sparse, hll.regs = x.p, x.seed, x.sparse, x.regs
+	return nil
+}
+
+func (hll *HyperLogLog) add(k keyHash) {
+	if hll.lock != nil {
+		hll.lock.Lock()
+		defer hll.lock.Unlock()
+	}
+	h := k(hll.seed)
+	if hll.regs == nil {
+		hll.addSparse(uint32(h>>(64-sparsePrecision)),
+			rank(h<<sparsePrecision, 64-sparsePrecision))
+		return
+	}
+	i := h >> (64 - hll.p)
+	if r := rank(h<<hll.p, 64-int(hll.p)); r > hll.regs[i] {
+		hll.regs[i] = r
+	}
+}
+
+// Call it with the lock held.
+func (hll *HyperLogLog) addSparse(k uint32, r uint8) {
+	if hll.regs != nil {
+		hll.addDense(k, r)
+		return
+	}
+	if r > hll.sparse[k] {
+		hll.sparse[k] = r
+	}
+	// Each entry takes several bytes, while a dense register takes one.
+	if len(hll.sparse) > 1<<hll.p/4 {
+		hll.toDense()
+	}
+}
+
+// Add an entry of the sparse representation to the registers.
+// Call it with the lock held.
+func (hll *HyperLogLog) addDense(k uint32, r uint8) {
+	extra := sparsePrecision - int(hll.p)
+	i := k >> uint(extra)
+	// The bits of k beyond the dense index come first in the rank.
+	if low := uint64(k) & (1<<uint(extra) - 1); low != 0 {
+		r = uint8(bits.LeadingZeros64(low<<uint(64-extra))) + 1
+	} else {
+		r += uint8(extra)
+	}
+	if r > hll.regs[i] {
+		hll.regs[i] = r
+	}
+}
+
+// Call it with the lock held.
+func (hll *HyperLogLog) toDense() {
+	if hll.regs != nil {
+		return
+	}
+	hll.regs = make([]uint8, 1<<hll.p)
+	for k, r := range hll.sparse {
+		hll.addDense(k, r)
+	}
+	hll.sparse = nil
+}
+
+// Return a copy of hll without the lock.
+func (hll *HyperLogLog) clone() *HyperLogLog {
+	if hll.lock != nil {
+		hll.lock.RLock()
+		defer hll.lock.RUnlock()
+	}
+	x := &HyperLogLog{p: hll.p, seed: hll.seed}
+	if hll.regs != nil {
+		x.regs = append([]uint8(nil), hll.regs...)
+		return x
+	}
+	x.sparse = make(map[uint32]uint8, len(hll.sparse))
+	for k, r := range hll.sparse {
+		x.sparse[k] = r
+	}
+	return x
+}
+
+// Return the position of the first 1 bit in the first q bits of w,
+// or q + 1 if they are all 0.
+func rank(w uint64, q int) uint8 {
+	r := bits.LeadingZeros64(w) + 1
+	if r > q+1 {
+		r = q + 1
+	}
+	return uint8(r)
+}
+
+// The improved raw estimator, from "New cardinality estimation algorithms
+// for HyperLogLog sketches" by Otmar Ertl, with registers of q + 1 bits.
+func ertlEstimate(regs []uint8, q int) float64 {
+	counts := make([]int, q+2)
+	for _, r := range regs {
+		counts[r]++
+	}
+	m := float64(len(regs))
+	z := m * ertlTau(1-float64(counts[q+1])/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(counts[k]))
+	}
+	z += m * ertlSigma(float64(counts[0])/m)
+	return m * m / (2 * math.Ln2 * z)
+}
+
+func ertlSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+func ertlTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
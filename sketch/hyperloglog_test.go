@@ -0,0 +1,120 @@
+package sketch
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	p := HyperLogLogPrecisionForError(0.01)
+	if p != 14 {
+		t.Errorf("Precision for 1%%: %d", p)
+	}
+	stdErr := 1.04 / math.Sqrt(float64(uint64(1)<<p))
+	hll := NewHyperLogLog(p, 1, true)
+	n := 0
+	for _, target := range []int{0, 10, 100, 1000, 5000, 20000, 100000, 1000000} {
+		for ; n < target; n++ {
+			hll.AddString("item" + strconv.Itoa(n))
+		}
+		hll.AddString("item0") // Duplicates are not counted.
+		if n == 0 {
+			n = 1
+		}
+		est := float64(hll.Count())
+		if math.Abs(est-float64(n)) > 3*stdErr*float64(n) {
+			t.Errorf("Count of %d: %v", n, est)
+		}
+		if n <= 1000 && hll.regs != nil {
+			t.Errorf("Dense with %d keys", n)
+		}
+	}
+	if hll.regs == nil {
+		t.Error("Sparse with many keys")
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	for _, sizes := range [][2]int{{100, 200}, {100, 50000}, {50000, 100},
+		{30000, 40000}} {
+		a := NewHyperLogLog(12, 1, false)
+		b := NewHyperLogLog(12, 1, true)
+		// Overlapping ranges [0, sizes[0]) and [sizes[0]/2, sizes[0]/2+sizes[1]).
+		for i := 0; i < sizes[0]; i++ {
+			a.Add([]byte(strconv.Itoa(i)))
+		}
+		for i := sizes[0] / 2; i < sizes[0]/2+sizes[1]; i++ {
+			b.Add([]byte(strconv.Itoa(i)))
+		}
+		if err := a.Merge(b); err != nil {
+			t.Fatal(err)
+		}
+		want := float64(sizes[0]/2 + sizes[1])
+		if sizes[1] < sizes[0]/2 {
+			want = float64(sizes[0])
+		}
+		est := float64(a.Count())
+		if math.Abs(est-want) > 0.05*want {
+			t.Errorf("Merge of %v: %v, want %v", sizes, est, want)
+		}
+		data, err := a.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c HyperLogLog
+		if err = c.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if c.Count() != a.Count() {
+			t.Errorf("Count after Unmarshal: %d, want %d", c.Count(), a.Count())
+		}
+	}
+	err := NewHyperLogLog(12, 1, false).Merge(NewHyperLogLog(13, 1, false))
+	if !errors.Is(err, gocontainer.ErrIncompatible) {
+		t.Errorf("Merge with another precision: %v", err)
+	}
+	_, err = NewHyperLogLogE(3, 0, false)
+	if !errors.Is(err, gocontainer.ErrInvalidParam) {
+		t.Errorf("NewHyperLogLogE(3): %v", err)
+	}
+}
+
+func TestHyperLogLogUnmarshalCorrupt(t *testing.T) {
+	sparse := NewHyperLogLog(12, 1, false)
+	dense := NewHyperLogLog(12, 1, false)
+	for i := 0; i < 10000; i++ {
+		if i < 10 {
+			sparse.AddString(strconv.Itoa(i))
+		}
+		dense.AddString(strconv.Itoa(i))
+	}
+	sparseData, _ := sparse.MarshalBinary()
+	denseData, _ := dense.MarshalBinary()
+	corrupt := func(data []byte, i int, b byte) []byte {
+		data = append([]byte(nil), data...)
+		data[i] = b
+		return data
+	}
+	n := len(sparseData)
+	for name, data := range map[string][]byte{
+		// The last entry is little endian, with the rank in its first byte.
+		"sparse key":  corrupt(sparseData, n-1, 0xFF),
+		"sparse rank": corrupt(sparseData, n-8, 200),
+		"zero rank":   corrupt(sparseData, n-8, 0),
+		"register":    corrupt(denseData, len(denseData)-1, 200),
+	} {
+		hll := NewHyperLogLog(12, 1, true)
+		err := hll.UnmarshalBinary(data)
+		if !errors.Is(err, gocontainer.ErrInvalidData) {
+			t.Errorf("Unmarshal with a bad %s: %v", name, err)
+		}
+		if hll.Count() != 0 {
+			t.Errorf("Count after Unmarshal with a bad %s: %d",
+				name, hll.Count())
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package sketch provides probabilistic data structures for streams:
+// Count-Min sketch with heavy hitters, HyperLogLog++,
+// Bloom filter and cuckoo filter.
+//
+// The keys are byte slices or strings, hashed with a seedable hash,
+// so the results are deterministic for a given seed.
+// Sketches can be merged only if they have the same parameters and seed.
+package sketch
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Kinds of the sketches in their binary forms.
+const (
+	kindCountMin byte = iota + 1
+	kindHeavyHitters
+	kindHyperLogLog
+	kindBloomFilter
+	kindCuckooFilter
+)
+
+const (
+	magic   = "GCSK"
+	version = 1
+)
+
+// Appends the fields of a binary form in little endian.
+type encoder struct {
+	b []byte
+}
+
+func newEncoder(kind byte, size int) *encoder {
+	e := &encoder{b: make([]byte, 0, len(magic)+2+size)}
+	e.b = append(e.b, magic...)
+	e.b = append(e.b, kind, version)
+	return e
+}
+
+func (e *encoder) u8(x uint8) {
+	e.b = append(e.b, x)
+}
+
+func (e *encoder) u64(x uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], x)
+	e.b = append(e.b, buf[:]...)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.u64(uint64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// Reads the fields of a binary form. After an error,
+// it returns zero values, and err keeps the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func newDecoder(kind byte, data []byte) *decoder {
+	d := &decoder{b: data}
+	if len(data) < len(magic)+2 || string(data[:len(magic)]) != magic ||
+		data[len(magic)] != kind || data[len(magic)+1] != version {
+		d.fail("bad header")
+		return d
+	}
+	d.b = data[len(magic)+2:]
+	return d
+}
+
+func (d *decoder) fail(msg string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", gocontainer.ErrInvalidData, msg)
+	}
+	d.b = nil
+}
+
+func (d *decoder) u8() uint8 {
+	if len(d.b) < 1 {
+		d.fail("too short")
+		return 0
+	}
+	x := d.b[0]
+	d.b = d.b[1:]
+	return x
+}
+
+func (d *decoder) u64() uint64 {
+	if len(d.b) < 8 {
+		d.fail("too short")
+		return 0
+	}
+	x := binary.LittleEndian.Uint64(d.b)
+	d.b = d.b[8:]
+	return x
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.u64()
+	if uint64(len(d.b)) < n {
+		d.fail("too short")
+		return nil
+	}
+	b := d.b[:n:n]
+	d.b = d.b[n:]
+	return b
+}
+
+// Return the error, also if there is unread data.
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.b) > 0 {
+		d.fail("trailing data")
+	}
+	return d.err
+}
@@ -0,0 +1,87 @@
+package skiplist
+
+import (
+	"sync/atomic"
+
+	"github.com/donyori/gocontainer"
+)
+
+// PriorityQueue is a concurrent priority queue based on SkipList.
+// It is safe for concurrent use, and its zero value is NOT ready to use.
+//
+// Equal items are dequeued in the order they are enqueued.
+type PriorityQueue struct {
+	sl  *SkipList
+	seq uint64 // Accessed atomically.
+}
+
+// The key in the skip list. seq distinguishes equal items.
+type pqKey struct {
+	x   gocontainer.Comparable
+	seq uint64
+}
+
+func NewPriorityQueue(isTopMax bool) *PriorityQueue {
+	less := func(a, b interface{}) bool {
+		ka, kb := a.(*pqKey), b.(*pqKey)
+		if ka.x.Less(kb.x) {
+			return !isTopMax
+		} else if kb.x.Less(ka.x) {
+			return isTopMax
+		}
+		return ka.seq < kb.seq
+	}
+	return &PriorityQueue{sl: NewSkipListFunc(less)}
+}
+
+// Return the number of items. It is exact only when there is
+// no concurrent modification.
+func (pq *PriorityQueue) Len() int {
+	if pq == nil {
+		return 0
+	}
+	return pq.sl.Len()
+}
+
+// Return the top item, or nil if the queue is empty. Lock-free.
+func (pq *PriorityQueue) Top() gocontainer.Comparable {
+	if pq == nil {
+		return nil
+	}
+	k, _, ok := pq.sl.Min()
+	if !ok {
+		return nil
+	}
+	return k.(*pqKey).x
+}
+
+// Enqueue x. It panics if x is nil.
+func (pq *PriorityQueue) Enqueue(x gocontainer.Comparable) {
+	if x == nil {
+		panic(gocontainer.ErrNilItem)
+	}
+	pq.sl.Put(&pqKey{x: x, seq: atomic.AddUint64(&pq.seq, 1)}, nil)
+}
+
+func (pq *PriorityQueue) Dequeue() (x gocontainer.Comparable, ok bool) {
+	if pq == nil {
+		return // nil, false
+	}
+	k, _, ok := pq.sl.PopMin()
+	if !ok {
+		return // nil, false
+	}
+	return k.(*pqKey).x, true
+}
+
+// Call f with the items in the order they would be dequeued,
+// until f returns true.
+// It is lock-free and weakly consistent, like SkipList.Range.
+func (pq *PriorityQueue) Scan(f func(x gocontainer.Comparable) (doesStop bool)) {
+	if pq == nil || f == nil {
+		return
+	}
+	pq.sl.Range(nil, nil, func(key, value interface{}) bool {
+		return f(key.(*pqKey).x)
+	})
+}
@@ -0,0 +1,148 @@
+package skiplist
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/pqueue"
+)
+
+type testOrderedElement struct {
+	p, i int
+}
+
+func (te *testOrderedElement) Less(another interface{}) bool {
+	return te.p < another.(*testOrderedElement).p
+}
+
+func TestPriorityQueue(t *testing.T) {
+	for _, isTopMax := range []bool{false, true} {
+		pq := NewPriorityQueue(isTopMax)
+		inputs := []int{3, 0, 9, -4, 3, -5, 8, 1, 6, 3}
+		for i, p := range inputs {
+			pq.Enqueue(&testOrderedElement{p: p, i: i})
+		}
+		if pq.Len() != len(inputs) {
+			t.Fatalf("Len(%d) != %d", pq.Len(), len(inputs))
+		}
+		var prev *testOrderedElement
+		for pq.Len() > 0 {
+			top := pq.Top()
+			x, ok := pq.Dequeue()
+			if !ok || x != top {
+				t.Fatalf("Dequeue() = %v, %t, Top() = %v", x, ok, top)
+			}
+			te := x.(*testOrderedElement)
+			if prev != nil {
+				if isTopMax && te.p > prev.p || !isTopMax && te.p < prev.p {
+					t.Fatalf("isTopMax: %t, %d after %d", isTopMax, te.p, prev.p)
+				}
+				if te.p == prev.p && te.i < prev.i {
+					t.Fatalf("Equal items are out of order: %d after %d",
+						te.i, prev.i)
+				}
+			}
+			prev = te
+		}
+		if _, ok := pq.Dequeue(); ok {
+			t.Fatal("Dequeue on an empty queue is ok")
+		}
+	}
+}
+
+func TestPriorityQueueConcurrent(t *testing.T) {
+	const numProducers, numConsumers, numItems = 4, 4, 5000
+	pq := NewPriorityQueue(false)
+	var wg sync.WaitGroup
+	for g := 0; g < numProducers; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < numItems; i++ {
+				pq.Enqueue(testElement(i*numProducers + g))
+			}
+		}(g)
+	}
+	results := make(chan []testElement, numConsumers)
+	done := make(chan struct{})
+	for g := 0; g < numConsumers; g++ {
+		go func() {
+			var xs []testElement
+			for {
+				x, ok := pq.Dequeue()
+				if ok {
+					xs = append(xs, x.(testElement))
+					continue
+				}
+				select {
+				case <-done:
+					results <- xs
+					return
+				default:
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	seen := make(map[testElement]bool)
+	for g := 0; g < numConsumers; g++ {
+		for _, x := range <-results {
+			if seen[x] {
+				t.Fatalf("%d is dequeued twice", x)
+			}
+			seen[x] = true
+		}
+	}
+	for {
+		x, ok := pq.Dequeue()
+		if !ok {
+			break
+		}
+		seen[x.(testElement)] = true
+	}
+	if len(seen) != numProducers*numItems {
+		t.Errorf("%d items are lost", numProducers*numItems-len(seen))
+	}
+}
+
+// Each goroutine enqueues and dequeues alternately.
+// All the dequeuers contend for the front of the skip list,
+// so PriorityQueue is not expected to outperform the mutex-based
+// pqueue.PriorityQueue in this workload. It is a concurrent alternative
+// whose Top and Scan never block.
+func BenchmarkSkipListPriorityQueue(b *testing.B) {
+	pq := NewPriorityQueue(false)
+	benchmarkConcurrentQueue(b, func(x gocontainer.Comparable) {
+		pq.Enqueue(x)
+	}, func() {
+		pq.Dequeue()
+	})
+}
+
+func BenchmarkMutexPriorityQueue(b *testing.B) {
+	pq := pqueue.NewPriorityQueue(0, false, true)
+	benchmarkConcurrentQueue(b, func(x gocontainer.Comparable) {
+		pq.Enqueue(x)
+	}, func() {
+		pq.Dequeue()
+	})
+}
+
+func benchmarkConcurrentQueue(b *testing.B,
+	enqueue func(x gocontainer.Comparable), dequeue func()) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1<<14; i++ {
+		enqueue(testElement(r.Int()))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			enqueue(testElement(r.Int()))
+			dequeue()
+		}
+	})
+}
@@ -0,0 +1,366 @@
+// Package skiplist provides a concurrent ordered map and a concurrent
+// priority queue, based on a lazy skip list.
+//
+// Writers lock only the nodes around the position to update,
+// and readers never block.
+//
+// See "A Simple Optimistic Skiplist Algorithm"
+// by Herlihy, Lev, Luchangco and Shavit.
+package skiplist
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/donyori/gocontainer"
+)
+
+const maxLevel = 32
+
+type node struct {
+	key    interface{}
+	value  atomic.Value     // Of valueBox.
+	next   []unsafe.Pointer // Of *node. Accessed atomically.
+	lock   sync.Mutex       // Locked to link or unlink its successors.
+	marked int32            // 1 if logically deleted. Accessed atomically.
+	linked int32            // 1 if fully linked. Accessed atomically.
+}
+
+// atomic.Value requires a consistent type.
+type valueBox struct {
+	v interface{}
+}
+
+func (n *node) loadNext(level int) *node {
+	return (*node)(atomic.LoadPointer(&n.next[level]))
+}
+
+func (n *node) storeNext(level int, next *node) {
+	atomic.StorePointer(&n.next[level], unsafe.Pointer(next))
+}
+
+func (n *node) isMarked() bool {
+	return atomic.LoadInt32(&n.marked) != 0
+}
+
+func (n *node) isLinked() bool {
+	return atomic.LoadInt32(&n.linked) != 0
+}
+
+func (n *node) topLevel() int {
+	return len(n.next) - 1
+}
+
+// SkipList is a concurrent ordered map. It is safe for concurrent use,
+// and its zero value is NOT ready to use.
+//
+// Two keys a and b are regarded as equal
+// if neither less(a, b) nor less(b, a).
+type SkipList struct {
+	head *node // Sentinel. Its key is never compared.
+	less func(a, b interface{}) bool
+	n    int64 // Accessed atomically.
+
+	// The highest level that may be non-empty. Accessed atomically.
+	// Searches start from it instead of maxLevel - 1.
+	level int32
+}
+
+// Create a SkipList whose keys are gocontainer.Comparable,
+// ordered by their Less method.
+func NewSkipList() *SkipList {
+	return NewSkipListFunc(func(a, b interface{}) bool {
+		return a.(gocontainer.Comparable).Less(b)
+	})
+}
+
+// Create a SkipList whose keys are ordered by less.
+// It panics with gocontainer.ErrNilLess if less is nil.
+func NewSkipListFunc(less func(a, b interface{}) bool) *SkipList {
+	if less == nil {
+		panic(gocontainer.ErrNilLess)
+	}
+	head := &node{next: make([]unsafe.Pointer, maxLevel)}
+	head.linked = 1
+	return &SkipList{head: head, less: less}
+}
+
+// Return the number of keys. It is exact only when there is
+// no concurrent modification.
+func (sl *SkipList) Len() int {
+	return int(atomic.LoadInt64(&sl.n))
+}
+
+// Fill preds and succs with the nodes around key on each level,
+// and return the highest level on which key is found, or -1 if not found.
+func (sl *SkipList) find(key interface{}, preds, succs []*node) int {
+	found := -1
+	pred := sl.head
+	top := sl.topLevel()
+	for level := maxLevel - 1; level > top; level-- {
+		preds[level], succs[level] = pred, nil
+	}
+	for level := top; level >= 0; level-- {
+		curr := pred.loadNext(level)
+		for curr != nil && sl.less(curr.key, key) {
+			pred = curr
+			curr = pred.loadNext(level)
+		}
+		if found < 0 && curr != nil && !sl.less(key, curr.key) {
+			found = level
+		}
+		preds[level] = pred
+		succs[level] = curr
+	}
+	return found
+}
+
+// Return the node of key, or nil if not found. Lock-free.
+func (sl *SkipList) get(key interface{}) *node {
+	pred := sl.head
+	for level := sl.topLevel(); level >= 0; level-- {
+		curr := pred.loadNext(level)
+		for curr != nil && sl.less(curr.key, key) {
+			pred = curr
+			curr = pred.loadNext(level)
+		}
+		if curr != nil && !sl.less(key, curr.key) {
+			if curr.isLinked() && !curr.isMarked() {
+				return curr
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func (sl *SkipList) Get(key interface{}) (value interface{}, ok bool) {
+	if n := sl.get(key); n != nil {
+		return n.value.Load().(valueBox).v, true
+	}
+	return
+}
+
+func (sl *SkipList) Contains(key interface{}) bool {
+	return sl.get(key) != nil
+}
+
+// Set the value of key, and return true if key is new.
+func (sl *SkipList) Put(key, value interface{}) (isAdded bool) {
+	return sl.put(key, value, true)
+}
+
+// Add key with value if key is not in the list, and return true if added.
+// Otherwise, it doesn't modify the value of key.
+func (sl *SkipList) PutIfAbsent(key, value interface{}) (isAdded bool) {
+	return sl.put(key, value, false)
+}
+
+func (sl *SkipList) put(key, value interface{}, doesReplace bool) bool {
+	var preds, succs [maxLevel]*node
+	topLevel := randomLevel()
+	sl.raiseLevel(topLevel)
+	for {
+		if found := sl.find(key, preds[:], succs[:]); found >= 0 {
+			n := succs[found]
+			if !n.isMarked() {
+				for !n.isLinked() {
+					runtime.Gosched() // Wait until it's added.
+				}
+				if doesReplace {
+					n.value.Store(valueBox{v: value})
+				}
+				return false
+			}
+			runtime.Gosched() // It's being deleted. Retry.
+			continue
+		}
+		highestLocked, isValid := lockPreds(preds[:], succs[:], topLevel,
+			func(pred, succ *node) bool {
+				return succ == nil || !succ.isMarked()
+			})
+		if !isValid {
+			unlockPreds(preds[:], highestLocked)
+			continue
+		}
+		n := &node{key: key, next: make([]unsafe.Pointer, topLevel+1)}
+		n.value.Store(valueBox{v: value})
+		for level := 0; level <= topLevel; level++ {
+			n.next[level] = unsafe.Pointer(succs[level])
+		}
+		for level := 0; level <= topLevel; level++ {
+			preds[level].storeNext(level, n)
+		}
+		atomic.StoreInt32(&n.linked, 1)
+		unlockPreds(preds[:], highestLocked)
+		atomic.AddInt64(&sl.n, 1)
+		return true
+	}
+}
+
+// Delete key, and return its value. ok is false if key is not in the list.
+func (sl *SkipList) Delete(key interface{}) (value interface{}, ok bool) {
+	var preds, succs [maxLevel]*node
+	found := sl.find(key, preds[:], succs[:])
+	if found < 0 {
+		return
+	}
+	n := succs[found]
+	if !n.isLinked() || n.topLevel() != found || !sl.mark(n) {
+		return
+	}
+	sl.unlink(n, preds[:], succs[:])
+	return n.value.Load().(valueBox).v, true
+}
+
+// Return the least key and its value. Lock-free.
+func (sl *SkipList) Min() (key, value interface{}, ok bool) {
+	for n := sl.head.loadNext(0); n != nil; n = n.loadNext(0) {
+		if n.isLinked() && !n.isMarked() {
+			return n.key, n.value.Load().(valueBox).v, true
+		}
+	}
+	return
+}
+
+// Delete the least key, and return it and its value.
+// The node is first marked as deleted, which decides the winner
+// among the concurrent callers, and then unlinked.
+func (sl *SkipList) PopMin() (key, value interface{}, ok bool) {
+	var preds, succs [maxLevel]*node
+	for n := sl.head.loadNext(0); n != nil; n = n.loadNext(0) {
+		if !n.isLinked() || n.isMarked() || !sl.mark(n) {
+			continue // Not added yet, or taken by others.
+		}
+		sl.find(n.key, preds[:], succs[:])
+		sl.unlink(n, preds[:], succs[:])
+		return n.key, n.value.Load().(valueBox).v, true
+	}
+	return
+}
+
+// Call f with the keys in [lo, hi) and their values, in ascending order,
+// until f returns true. nil lo or hi means unbounded.
+// It is lock-free and weakly consistent: it reflects some,
+// but not necessarily all, of the concurrent modifications.
+func (sl *SkipList) Range(lo, hi interface{},
+	f func(key, value interface{}) (doesStop bool)) {
+	if f == nil {
+		return
+	}
+	var n *node
+	if lo == nil {
+		n = sl.head.loadNext(0)
+	} else {
+		pred := sl.head
+		for level := sl.topLevel(); level >= 0; level-- {
+			n = pred.loadNext(level)
+			for n != nil && sl.less(n.key, lo) {
+				pred = n
+				n = pred.loadNext(level)
+			}
+		}
+	}
+	for ; n != nil && (hi == nil || sl.less(n.key, hi)); n = n.loadNext(0) {
+		if n.isLinked() && !n.isMarked() &&
+			f(n.key, n.value.Load().(valueBox).v) {
+			return
+		}
+	}
+}
+
+func (sl *SkipList) topLevel() int {
+	return int(atomic.LoadInt32(&sl.level))
+}
+
+// Make sure sl.level >= level, before linking a node of the level.
+func (sl *SkipList) raiseLevel(level int) {
+	for {
+		old := atomic.LoadInt32(&sl.level)
+		if int(old) >= level ||
+			atomic.CompareAndSwapInt32(&sl.level, old, int32(level)) {
+			return
+		}
+	}
+}
+
+// Mark n as deleted, and return true if this call marks it.
+// It doesn't lock n: an insertion after n, which validated that n
+// was not marked, is seen by unlink, which locks n
+// before reading its successors.
+func (sl *SkipList) mark(n *node) bool {
+	if !atomic.CompareAndSwapInt32(&n.marked, 0, 1) {
+		return false
+	}
+	atomic.AddInt64(&sl.n, -1)
+	return true
+}
+
+// Unlink n, which is marked by this goroutine.
+// preds and succs are filled by find(n.key) and may be stale.
+func (sl *SkipList) unlink(n *node, preds, succs []*node) {
+	topLevel := n.topLevel()
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for {
+		highestLocked, isValid := lockPreds(preds, succs, topLevel,
+			func(pred, succ *node) bool {
+				return succ == n
+			})
+		if isValid {
+			for level := topLevel; level >= 0; level-- {
+				preds[level].storeNext(level, n.loadNext(level))
+			}
+			unlockPreds(preds, highestLocked)
+			return
+		}
+		unlockPreds(preds, highestLocked)
+		sl.find(n.key, preds, succs)
+	}
+}
+
+// Lock the distinct predecessors on levels [0, topLevel] from bottom up,
+// and check that they are not marked, still linked to their successors,
+// and that isValidSucc holds.
+// Return the highest locked level and the result of the check.
+func lockPreds(preds, succs []*node, topLevel int,
+	isValidSucc func(pred, succ *node) bool) (highestLocked int,
+	isValid bool) {
+	highestLocked = -1
+	var prev *node
+	for level := 0; level <= topLevel; level++ {
+		pred, succ := preds[level], succs[level]
+		if pred != prev {
+			pred.lock.Lock()
+			highestLocked = level
+			prev = pred
+		}
+		if pred.isMarked() || pred.loadNext(level) != succ ||
+			!isValidSucc(pred, succ) {
+			return highestLocked, false
+		}
+	}
+	return highestLocked, true
+}
+
+func unlockPreds(preds []*node, highestLocked int) {
+	var prev *node
+	for level := 0; level <= highestLocked; level++ {
+		if preds[level] != prev {
+			preds[level].lock.Unlock()
+			prev = preds[level]
+		}
+	}
+}
+
+// Return a random level in [0, maxLevel), with P(level >= i) = 2^-i.
+func randomLevel() int {
+	level := 0
+	for r := rand.Int63(); r&1 == 1 && level < maxLevel-1; r >>= 1 {
+		level++
+	}
+	return level
+}
@@ -0,0 +1,208 @@
+package skiplist
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/ordmap"
+)
+
+// Check that the levels are sorted, each level is a sublist of the level
+// below, and that no marked node is left after the modifications.
+func checkList(t *testing.T, sl *SkipList) {
+	for level := maxLevel - 1; level >= 0; level-- {
+		var prev *node
+		for n := sl.head.loadNext(level); n != nil; n = n.loadNext(level) {
+			if n.isMarked() || !n.isLinked() {
+				t.Fatalf("Level %d: node %v is marked or not linked", level, n.key)
+			}
+			if prev != nil && !sl.less(prev.key, n.key) {
+				t.Fatalf("Level %d: %v is not less than %v", level, prev.key, n.key)
+			}
+			if level > 0 && sl.get(n.key) != n {
+				t.Fatalf("Level %d: %v is not in the bottom level", level, n.key)
+			}
+			prev = n
+		}
+	}
+}
+
+func TestSkipList(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	sl := NewSkipList()
+	ref := make(map[testElement]int)
+	for i := 0; i < 5000; i++ {
+		k := testElement(r.Intn(500))
+		switch r.Intn(4) {
+		case 0:
+			v, ok := sl.Delete(k)
+			rv, rok := ref[k]
+			if ok != rok || ok && v != rv {
+				t.Fatalf("Delete(%d) = %v, %t, want %v, %t", k, v, ok, rv, rok)
+			}
+			delete(ref, k)
+		case 1:
+			_, exists := ref[k]
+			if isAdded := sl.PutIfAbsent(k, -1); isAdded == exists {
+				t.Fatalf("PutIfAbsent(%d) = %t", k, isAdded)
+			}
+			if !exists {
+				ref[k] = -1
+			}
+		default:
+			_, exists := ref[k]
+			if isAdded := sl.Put(k, i); isAdded == exists {
+				t.Fatalf("Put(%d) = %t", k, isAdded)
+			}
+			ref[k] = i
+		}
+	}
+	checkList(t, sl)
+	if sl.Len() != len(ref) {
+		t.Fatalf("Len(%d) != %d", sl.Len(), len(ref))
+	}
+	keys := make([]int, 0, len(ref))
+	for k := range ref {
+		keys = append(keys, int(k))
+		if v, ok := sl.Get(k); !ok || v != ref[k] {
+			t.Fatalf("Get(%d) = %v, %t", k, v, ok)
+		}
+	}
+	sort.Ints(keys)
+	var scanned []int
+	sl.Range(testElement(100), testElement(200),
+		func(key, value interface{}) bool {
+			scanned = append(scanned, int(key.(testElement)))
+			return false
+		})
+	lo, hi := sort.SearchInts(keys, 100), sort.SearchInts(keys, 200)
+	if len(scanned) != hi-lo {
+		t.Fatalf("Range: %d keys, want %d", len(scanned), hi-lo)
+	}
+	for i, k := range scanned {
+		if k != keys[lo+i] {
+			t.Fatalf("Range: %v, want %v", scanned, keys[lo:hi])
+		}
+	}
+	for _, k := range keys {
+		mk, _, ok := sl.PopMin()
+		if !ok || mk != testElement(k) {
+			t.Fatalf("PopMin() = %v, %t, want %d", mk, ok, k)
+		}
+	}
+	if _, _, ok := sl.Min(); ok || sl.Len() != 0 {
+		t.Fatal("The list is not empty.")
+	}
+}
+
+func TestNewSkipListFuncNil(t *testing.T) {
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, gocontainer.ErrNilLess) {
+			t.Errorf("NewSkipListFunc(nil) panics with %v", err)
+		}
+	}()
+	NewSkipListFunc(nil)
+}
+
+func TestSkipListConcurrent(t *testing.T) {
+	const numGoroutines, numKeys = 8, 2000
+	sl := NewSkipList()
+	var wg sync.WaitGroup
+	// Each key is taken by exactly one of Delete and PopMin, or left.
+	taken := make([][]testElement, numGoroutines)
+	for g := 0; g < numGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < numKeys; i++ {
+				k := testElement(i*numGoroutines + g) // Unique for each goroutine.
+				sl.Put(k, g)
+				switch r.Intn(4) {
+				case 0:
+					if _, ok := sl.Delete(k); ok {
+						taken[g] = append(taken[g], k)
+					}
+				case 1:
+					sl.Get(testElement(r.Intn(numKeys * numGoroutines)))
+					sl.Range(k, nil, func(key, value interface{}) bool {
+						return r.Intn(8) == 0
+					})
+				case 2:
+					if k, _, ok := sl.PopMin(); ok {
+						taken[g] = append(taken[g], k.(testElement))
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	checkList(t, sl)
+	seen := make(map[testElement]bool)
+	for _, ks := range taken {
+		for _, k := range ks {
+			if seen[k] {
+				t.Fatalf("%d is taken twice", k)
+			}
+			seen[k] = true
+		}
+	}
+	left := 0
+	sl.Range(nil, nil, func(key, value interface{}) bool {
+		if seen[key.(testElement)] {
+			t.Fatalf("%d is taken but still in the list", key)
+		}
+		seen[key.(testElement)] = true
+		left++
+		return false
+	})
+	if len(seen) != numGoroutines*numKeys {
+		t.Errorf("%d keys are lost", numGoroutines*numKeys-len(seen))
+	}
+	if n := sl.Len(); n != left {
+		t.Errorf("Len(%d) != %d", n, left)
+	}
+}
+
+// 90% Get and 10% Put, on 2^16 keys.
+func BenchmarkSkipListReadMostly(b *testing.B) {
+	sl := NewSkipList()
+	benchmarkReadMostly(b, func(k testElement) {
+		sl.Get(k)
+	}, func(k testElement) {
+		sl.Put(k, nil)
+	})
+}
+
+func BenchmarkOrderedMapReadMostly(b *testing.B) {
+	m := ordmap.NewOrderedMap(true)
+	benchmarkReadMostly(b, func(k testElement) {
+		m.Get(k)
+	}, func(k testElement) {
+		m.Put(k, nil)
+	})
+}
+
+func benchmarkReadMostly(b *testing.B, get, put func(k testElement)) {
+	const numKeys = 1 << 16
+	for i := 0; i < numKeys; i += 2 {
+		put(testElement(i))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			k := testElement(r.Intn(numKeys))
+			if r.Intn(10) == 0 {
+				put(k)
+			} else {
+				get(k)
+			}
+		}
+	})
+}
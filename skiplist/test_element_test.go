@@ -0,0 +1,7 @@
+package skiplist
+
+type testElement int
+
+func (te testElement) Less(another interface{}) bool {
+	return te < another.(testElement)
+}
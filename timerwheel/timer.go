@@ -0,0 +1,108 @@
+package timerwheel
+
+import "time"
+
+const (
+	stateIdle     int8 = iota // Fired or canceled.
+	stateWheel                // In a slot of the wheel.
+	stateOverflow             // In the overflow heap.
+	stateReady                // Due, waiting for Advance.
+)
+
+// Timer is a handle of a scheduled payload.
+type Timer struct {
+	deadline time.Time
+	payload  interface{}
+	tw       *TimerWheel
+	tick     uint64 // The tick at which it is due.
+	seq      uint64 // Scheduling order, to break ties.
+	state    int8
+
+	// In a slot, or the ready list.
+	prev, next *Timer
+	list       *timerList
+	level      int
+
+	// In the overflow heap.
+	idx int
+}
+
+func (t *Timer) Deadline() time.Time {
+	return t.deadline
+}
+
+func (t *Timer) Payload() interface{} {
+	return t.payload
+}
+
+// Cancel the timer, and return true if it was scheduled,
+// i.e., it had neither fired nor been canceled.
+func (t *Timer) Cancel() bool {
+	if t == nil {
+		return false
+	}
+	return t.tw.cancel(t)
+}
+
+// Order by the due tick, and then by the deadline and scheduling order,
+// for the overflow heap.
+func (t *Timer) Less(another interface{}) bool {
+	a := another.(*Timer)
+	if t.tick != a.tick {
+		return t.tick < a.tick
+	}
+	return timerLess(t, a)
+}
+
+func (t *Timer) Index() int {
+	return t.idx
+}
+
+func (t *Timer) UpdateIndex(idx int) {
+	t.idx = idx
+}
+
+func timerLess(a, b *Timer) bool {
+	return a.deadline.Before(b.deadline) ||
+		a.deadline.Equal(b.deadline) && a.seq < b.seq
+}
+
+// Intrusive doubly linked list of timers.
+type timerList struct {
+	root Timer // Sentinel.
+	n    int
+}
+
+func (l *timerList) init() {
+	l.root.next = &l.root
+	l.root.prev = &l.root
+	l.n = 0
+}
+
+func (l *timerList) pushBack(t *Timer) {
+	if l.root.next == nil {
+		l.init()
+	}
+	t.prev = l.root.prev
+	t.next = &l.root
+	t.prev.next = t
+	t.next.prev = t
+	t.list = l
+	l.n++
+}
+
+func (l *timerList) remove(t *Timer) {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev, t.next, t.list = nil, nil, nil
+	l.n--
+}
+
+// Remove all the timers, and call f with them.
+func (l *timerList) drain(f func(t *Timer)) {
+	for l.n > 0 {
+		t := l.root.next
+		l.remove(t)
+		f(t)
+	}
+}
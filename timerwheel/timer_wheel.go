@@ -0,0 +1,337 @@
+// Package timerwheel provides a hierarchical timing wheel,
+// for scheduling a large number of timers with O(1) time per operation.
+package timerwheel
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/internal/expiry"
+	iheap "github.com/donyori/gocontainer/internal/heap"
+)
+
+type Options struct {
+	// The resolution. Timers fire up to one Tick after their deadlines.
+	// Default: 1 millisecond.
+	Tick time.Duration
+
+	// Number of slots of each level, rounded up to a power of 2.
+	// Default: 256.
+	WheelSize int
+
+	// Number of levels. Level i covers WheelSize^(i+1) ticks.
+	// Timers beyond the last level are kept in a min-heap,
+	// and moved into the wheel when they come into its range.
+	// Default: 4.
+	Levels int
+
+	// The clock. Default: time.Now.
+	Now func() time.Time
+
+	// Ticker mode: advance the wheel to Now in a background goroutine
+	// on every receive from AdvanceTick, or every AdvanceInterval
+	// if AdvanceTick is nil, and call OnExpire with each due timer,
+	// without the lock held.
+	// It requires the wheel to be created with isSync = true,
+	// and OnExpire to be set. Stop it by StopTicker.
+	AdvanceInterval time.Duration
+	AdvanceTick     <-chan time.Time
+	OnExpire        func(t *Timer)
+}
+
+// TimerWheel is a hierarchical timing wheel.
+//
+// Schedule, Cancel, and firing a timer take amortized O(1) time,
+// plus O(log(n)) for the timers in the overflow heap.
+type TimerWheel struct {
+	tick  time.Duration
+	bits  uint   // log2(WheelSize).
+	mask  uint64 // WheelSize - 1.
+	now   func() time.Time
+	start time.Time
+	cur   uint64 // Ticks since start that have been processed.
+	seq   uint64
+
+	levels   [][]timerList
+	counts   []int // Number of timers in each level.
+	overflow *iheap.MinHeap
+	ready    timerList // Timers due when they are scheduled.
+	n        int
+
+	onExpire func(t *Timer)
+	stop     func() // Stop the ticker.
+	lock     *sync.Mutex
+}
+
+func New(opts *Options, isSync bool) *TimerWheel {
+	tw, err := NewE(opts, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return tw
+}
+
+// Same as New, but return an error instead of panic.
+// opts can be nil, for the default options.
+func NewE(opts *Options, isSync bool) (*TimerWheel, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	tw := &TimerWheel{
+		tick:     opts.Tick,
+		now:      opts.Now,
+		overflow: iheap.NewMinHeap(0, true),
+		onExpire: opts.OnExpire,
+		stop:     func() {},
+	}
+	if tw.tick == 0 {
+		tw.tick = time.Millisecond
+	}
+	size, nLevels := opts.WheelSize, opts.Levels
+	if size == 0 {
+		size = 256
+	}
+	if nLevels == 0 {
+		nLevels = 4
+	}
+	for 1<<tw.bits < size {
+		tw.bits++
+	}
+	if tw.tick < 0 || size < 2 || nLevels < 1 || int(tw.bits)*nLevels > 62 {
+		return nil, fmt.Errorf("%w: tick %v, wheel size %d, %d levels",
+			gocontainer.ErrInvalidOptions, opts.Tick, opts.WheelSize,
+			opts.Levels)
+	}
+	hasTicker := opts.AdvanceTick != nil || opts.AdvanceInterval > 0
+	if hasTicker && !isSync {
+		return nil, fmt.Errorf("%w: ticker mode needs a lock",
+			gocontainer.ErrNotSync)
+	} else if hasTicker && opts.OnExpire == nil {
+		return nil, fmt.Errorf("%w: ticker mode needs OnExpire",
+			gocontainer.ErrInvalidOptions)
+	}
+	tw.mask = 1<<tw.bits - 1
+	tw.levels = make([][]timerList, nLevels)
+	for i := range tw.levels {
+		tw.levels[i] = make([]timerList, 1<<tw.bits)
+	}
+	tw.counts = make([]int, nLevels)
+	if tw.now == nil {
+		tw.now = time.Now
+	}
+	tw.start = tw.now()
+	if isSync {
+		tw.lock = new(sync.Mutex)
+	}
+	if hasTicker {
+		tw.stop = expiry.StartSweeper(opts.AdvanceTick, opts.AdvanceInterval,
+			func() {
+				for _, t := range tw.Advance(tw.now()) {
+					tw.onExpire(t)
+				}
+			})
+	}
+	return tw, nil
+}
+
+// Return the number of scheduled timers.
+func (tw *TimerWheel) Len() int {
+	if tw == nil {
+		return 0
+	}
+	if tw.lock != nil {
+		tw.lock.Lock()
+		defer tw.lock.Unlock()
+	}
+	return tw.n
+}
+
+// Schedule payload at deadline, and return its timer.
+// A deadline not after the current time of the wheel
+// is returned by the next Advance.
+func (tw *TimerWheel) Schedule(deadline time.Time,
+	payload interface{}) *Timer {
+	if tw.lock != nil {
+		tw.lock.Lock()
+		defer tw.lock.Unlock()
+	}
+	tw.seq++
+	t := &Timer{
+		deadline: deadline,
+		payload:  payload,
+		tw:       tw,
+		tick:     tw.tickOf(deadline),
+		seq:      tw.seq,
+		idx:      -1,
+	}
+	tw.add(t)
+	tw.n++
+	return t
+}
+
+// Schedule payload after d from now, by the clock.
+func (tw *TimerWheel) ScheduleAfter(d time.Duration,
+	payload interface{}) *Timer {
+	return tw.Schedule(tw.now().Add(d), payload)
+}
+
+// Advance the wheel to now, and return the timers due,
+// in the order of their deadlines.
+// Advancing to a time before the current time of the wheel
+// only returns the timers scheduled in the past.
+func (tw *TimerWheel) Advance(now time.Time) []*Timer {
+	if tw == nil {
+		return nil
+	}
+	if tw.lock != nil {
+		tw.lock.Lock()
+		defer tw.lock.Unlock()
+	}
+	var due []*Timer
+	fire := func(t *Timer) {
+		t.state = stateIdle
+		due = append(due, t)
+	}
+	tw.ready.drain(fire)
+	target := uint64(0)
+	if d := now.Sub(tw.start); d > 0 {
+		target = uint64(d / tw.tick)
+	}
+	for tw.cur < target {
+		next := tw.nextEvent()
+		if next > target {
+			tw.cur = target
+			break
+		}
+		tw.cur = next
+		tw.step(fire)
+	}
+	tw.n -= len(due)
+	sort.Slice(due, func(i, j int) bool {
+		return timerLess(due[i], due[j])
+	})
+	return due
+}
+
+// Stop the background goroutine of the ticker mode.
+func (tw *TimerWheel) StopTicker() {
+	if tw == nil {
+		return
+	}
+	tw.stop()
+}
+
+// Return the tick at which deadline is due, rounding up.
+func (tw *TimerWheel) tickOf(deadline time.Time) uint64 {
+	d := deadline.Sub(tw.start)
+	if d <= 0 {
+		return 0
+	}
+	return uint64((d + tw.tick - 1) / tw.tick)
+}
+
+// Put t into the ready list, a slot, or the overflow heap,
+// by how far its tick is. Call it with the lock held.
+func (tw *TimerWheel) add(t *Timer) {
+	if t.tick <= tw.cur {
+		t.state = stateReady
+		tw.ready.pushBack(t)
+		return
+	}
+	diff := t.tick - tw.cur
+	for level := range tw.levels {
+		shift := uint(level) * tw.bits
+		if diff>>shift <= tw.mask {
+			t.state, t.level = stateWheel, level
+			tw.levels[level][(t.tick>>shift)&tw.mask].pushBack(t)
+			tw.counts[level]++
+			return
+		}
+	}
+	t.state = stateOverflow
+	heap.Push(tw.overflow, t)
+}
+
+// Return the first tick after cur at which a slot may have timers
+// to fire or cascade, or a timer may leave the overflow heap.
+// Call it with the lock held.
+func (tw *TimerWheel) nextEvent() uint64 {
+	next := ^uint64(0)
+	// Levels below the first non-empty one need not be stepped through:
+	// jump to the next time the non-empty level cascades.
+	for level, c := range tw.counts {
+		if c > 0 {
+			step := uint64(1) << (uint(level) * tw.bits)
+			next = (tw.cur/step + 1) * step
+			break
+		}
+	}
+	if tw.overflow.Len() > 0 {
+		// It comes into the range of the top level at this tick.
+		top := tw.overflow.Top().(*Timer).tick
+		span := uint64(1) << (uint(len(tw.levels)) * tw.bits)
+		if top < span || top-span+1 <= tw.cur {
+			return tw.cur + 1
+		} else if top-span+1 < next {
+			next = top - span + 1
+		}
+	}
+	return next
+}
+
+// Process tick cur. Call it with the lock held.
+func (tw *TimerWheel) step(fire func(t *Timer)) {
+	span := uint64(1) << (uint(len(tw.levels)) * tw.bits)
+	for tw.overflow.Len() > 0 {
+		t := tw.overflow.Top().(*Timer)
+		if t.tick-tw.cur >= span {
+			break
+		}
+		heap.Pop(tw.overflow)
+		tw.add(t)
+	}
+	// Cascade the slots whose periods begin now, from the top level down,
+	// since the timers from a higher level may land in a lower one
+	// that also begins now.
+	top := 0
+	for top+1 < len(tw.levels) &&
+		tw.cur&(uint64(1)<<(uint(top+1)*tw.bits)-1) == 0 {
+		top++
+	}
+	for level := top; level > 0; level-- {
+		shift := uint(level) * tw.bits
+		slot := &tw.levels[level][(tw.cur>>shift)&tw.mask]
+		tw.counts[level] -= slot.n
+		slot.drain(tw.add)
+	}
+	slot := &tw.levels[0][tw.cur&tw.mask]
+	tw.counts[0] -= slot.n
+	slot.drain(fire)
+	// Timers cascaded into the ready list are due now as well.
+	tw.ready.drain(fire)
+}
+
+func (tw *TimerWheel) cancel(t *Timer) bool {
+	if tw.lock != nil {
+		tw.lock.Lock()
+		defer tw.lock.Unlock()
+	}
+	switch t.state {
+	case stateWheel:
+		tw.counts[t.level]--
+		t.list.remove(t)
+	case stateReady:
+		t.list.remove(t)
+	case stateOverflow:
+		heap.Remove(tw.overflow, t.idx)
+	default:
+		return false
+	}
+	t.state = stateIdle
+	tw.n--
+	return true
+}
@@ -0,0 +1,178 @@
+package timerwheel
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Add(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+	return c.t
+}
+
+func TestTimerWheelRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	// A small wheel of 2 levels covering 16 ticks, to exercise
+	// the cascading and the overflow heap.
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	tw := New(&Options{
+		Tick:      time.Millisecond,
+		WheelSize: 4,
+		Levels:    2,
+		Now:       clock.Now,
+	}, false)
+	start := clock.Now()
+	pending := make(map[*Timer]bool)
+	now := start
+	for i := 0; i < 2000; i++ {
+		switch r.Intn(4) {
+		case 0:
+			now = clock.Add(time.Duration(r.Intn(5000)) * time.Microsecond)
+			due := tw.Advance(now)
+			for j, tm := range due {
+				if !pending[tm] {
+					t.Fatalf("Timer %v fired twice or after Cancel", tm.Payload())
+				}
+				if tm.Deadline().After(now) {
+					t.Fatalf("Timer %v fired at %v, before its deadline %v",
+						tm.Payload(), now, tm.Deadline())
+				}
+				if j > 0 && tm.Deadline().Before(due[j-1].Deadline()) {
+					t.Fatalf("Due timers are not ordered")
+				}
+				delete(pending, tm)
+			}
+			// The rest are not due: at most one tick late.
+			for tm := range pending {
+				if !tm.Deadline().After(now.Add(-time.Millisecond)) {
+					t.Fatalf("Timer %v at %v is not fired at %v",
+						tm.Payload(), tm.Deadline(), now)
+				}
+			}
+		case 1:
+			for tm := range pending {
+				if !tm.Cancel() || tm.Cancel() {
+					t.Fatalf("Cancel of timer %v is wrong", tm.Payload())
+				}
+				delete(pending, tm)
+				break
+			}
+		default:
+			// Some in the past, most in the future, some beyond the wheel.
+			d := time.Duration(r.Intn(100)-5) * time.Millisecond
+			if r.Intn(10) == 0 {
+				d *= 30
+			}
+			pending[tw.Schedule(now.Add(d), i)] = true
+		}
+		if tw.Len() != len(pending) {
+			t.Fatalf("Len: %d, want %d", tw.Len(), len(pending))
+		}
+	}
+	due := tw.Advance(now.Add(time.Hour))
+	if len(due) != len(pending) || tw.Len() != 0 {
+		t.Errorf("%d timers fired at last, want %d", len(due), len(pending))
+	}
+}
+
+func TestTimerWheelLongJump(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	tw := New(&Options{Now: clock.Now}, true)
+	now := clock.Now()
+	a := tw.Schedule(now.Add(time.Second), "a")
+	b := tw.Schedule(now.Add(100*24*time.Hour), "b") // In the overflow.
+	tw.Schedule(now.Add(100*24*time.Hour+time.Millisecond), "c")
+	if due := tw.Advance(now.Add(time.Second)); len(due) != 1 || due[0] != a {
+		t.Fatalf("Due: %v", due)
+	}
+	if !b.Cancel() {
+		t.Fatal("Cancel of b failed")
+	}
+	if due := tw.Advance(now.Add(50 * 24 * time.Hour)); len(due) != 0 {
+		t.Fatalf("Due: %v", due)
+	}
+	due := tw.Advance(now.Add(100*24*time.Hour + time.Millisecond))
+	if len(due) != 1 || due[0].Payload() != "c" {
+		t.Fatalf("Due: %v", due)
+	}
+}
+
+func TestTimerWheelTicker(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	tick := make(chan time.Time)
+	fired := make(chan interface{}, 10)
+	tw, err := NewE(&Options{
+		Tick:        10 * time.Millisecond,
+		Now:         clock.Now,
+		AdvanceTick: tick,
+		OnExpire: func(t *Timer) {
+			fired <- t.Payload()
+		},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tw.StopTicker()
+	tw.ScheduleAfter(20*time.Millisecond, 1)
+	tw.ScheduleAfter(50*time.Millisecond, 2)
+	clock.Add(30 * time.Millisecond)
+	tick <- time.Time{}
+	if p := <-fired; p != 1 {
+		t.Errorf("Fired %v, want 1", p)
+	}
+	clock.Add(30 * time.Millisecond)
+	tick <- time.Time{}
+	if p := <-fired; p != 2 {
+		t.Errorf("Fired %v, want 2", p)
+	}
+
+	_, err = NewE(&Options{AdvanceTick: tick}, false)
+	if !errors.Is(err, gocontainer.ErrNotSync) {
+		t.Errorf("NewE without lock: %v", err)
+	}
+	_, err = NewE(&Options{WheelSize: 1}, false)
+	if !errors.Is(err, gocontainer.ErrInvalidOptions) {
+		t.Errorf("NewE with wheel size 1: %v", err)
+	}
+	_, err = NewE(&Options{AdvanceTick: tick}, true)
+	if !errors.Is(err, gocontainer.ErrInvalidOptions) {
+		t.Errorf("NewE with ticker but no OnExpire: %v", err)
+	}
+}
+
+func BenchmarkTimerWheel(b *testing.B) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	tw := New(&Options{Now: clock.Now}, false)
+	r := rand.New(rand.NewSource(1))
+	now := clock.Now()
+	// Keep about 100k timers pending.
+	for i := 0; i < 100000; i++ {
+		tw.Schedule(now.Add(time.Duration(r.Intn(10000))*time.Millisecond), i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tw.Schedule(now.Add(time.Duration(r.Intn(10000))*time.Millisecond), i)
+		if i%10 == 0 {
+			now = now.Add(time.Millisecond)
+			tw.Advance(now)
+		}
+	}
+}
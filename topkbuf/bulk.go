@@ -0,0 +1,84 @@
+package topkbuf
+
+import "github.com/donyori/gocontainer"
+
+// Remove all the items for which pred returns true, and return them.
+// The buffer is re-heapified only once, in O(n) time.
+func (tkb *TopKBuffer) RemoveIf(
+	pred func(x gocontainer.Comparable) bool) []gocontainer.Comparable {
+	if tkb == nil || pred == nil {
+		return nil
+	}
+	return tkb.Retain(func(x gocontainer.Comparable) bool {
+		return !pred(x)
+	})
+}
+
+// Keep only the items for which pred returns true,
+// and return the removed items.
+// The buffer is re-heapified only once, in O(n) time.
+func (tkb *TopKBuffer) Retain(
+	pred func(x gocontainer.Comparable) bool) []gocontainer.Comparable {
+	if tkb == nil || pred == nil {
+		return nil
+	}
+	tkb.purgeExpired()
+	if tkb.lock != nil {
+		tkb.lock.Lock()
+		defer tkb.lock.Unlock()
+	}
+	removed := tkb.h.Filter(func(x gocontainer.Comparable) bool {
+		return pred(unwrap(x))
+	})
+	for i, x := range removed {
+		tkb.forget(x)
+		removed[i] = unwrap(x)
+	}
+	tkb.checkIfDebug()
+	for _, x := range removed {
+		tkb.notify(gocontainer.EventRemove, x, 0, nil)
+	}
+	return removed
+}
+
+// Replace each item x with f(x), and re-heapify the buffer once,
+// in O(n) time. If f returns nil, x is kept.
+// f can also modify x in place (and return nil) to change its order.
+func (tkb *TopKBuffer) UpdateAll(
+	f func(x gocontainer.Comparable) gocontainer.Comparable) {
+	if tkb == nil || f == nil {
+		return
+	}
+	tkb.purgeExpired()
+	if tkb.lock != nil {
+		tkb.lock.Lock()
+		defer tkb.lock.Unlock()
+	}
+	if tkb.exp == nil && tkb.obs == nil {
+		tkb.h.ReplaceAll(f)
+	} else {
+		tkb.h.ReplaceAll(func(x gocontainer.Comparable) gocontainer.Comparable {
+			ei, isWrapped := x.(*expiringItem)
+			if isWrapped {
+				x = ei.x
+			}
+			newX := f(x)
+			if tkb.obs != nil {
+				e := gocontainer.Event{Kind: gocontainer.EventUpdate, Item: x}
+				if newX != nil {
+					e.Item, e.Prev = newX, x
+				}
+				e.Len = tkb.h.Len()
+				tkb.obs.Observe(e)
+			}
+			if isWrapped {
+				if newX != nil {
+					ei.x = newX
+				}
+				return nil // Keep the wrapper.
+			}
+			return newX
+		})
+	}
+	tkb.checkIfDebug()
+}
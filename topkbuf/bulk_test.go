@@ -0,0 +1,46 @@
+package topkbuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestTopKBufferBulk(t *testing.T) {
+	for _, withExpiry := range []bool{false, true} {
+		var tkb *TopKBuffer
+		if withExpiry {
+			tkb = NewTopKBufferWithExpiry(5, true, nil)
+		} else {
+			tkb = NewTopKBuffer(5, true)
+		}
+		tkb.SetDebug(true)
+		inputs := []testElement1{3, 0, 9, -4, 3, -5, 8, 1, 6}
+		for i := range inputs {
+			if withExpiry {
+				tkb.AddWithTTL(&inputs[i], time.Hour)
+			} else {
+				tkb.Add(&inputs[i])
+			}
+		}
+		removed := tkb.RemoveIf(func(x gocontainer.Comparable) bool {
+			return *x.(*testElement1) == 8
+		})
+		if len(removed) != 1 || removed[0] != &inputs[6] {
+			t.Fatalf("withExpiry: %t, Removed(%v) != [8]", withExpiry, removed)
+		}
+		tkb.Retain(func(x gocontainer.Comparable) bool {
+			return *x.(*testElement1) > 3
+		})
+		tkb.UpdateAll(func(x gocontainer.Comparable) gocontainer.Comparable {
+			*x.(*testElement1) *= -1
+			return nil
+		})
+		xs := tkb.Flush()
+		if len(xs) != 2 || *xs[0].(*testElement1) != -6 ||
+			*xs[1].(*testElement1) != -9 {
+			t.Fatalf("withExpiry: %t, Flush: %v", withExpiry, xs)
+		}
+	}
+}
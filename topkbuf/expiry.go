@@ -0,0 +1,192 @@
+package topkbuf
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/internal/expiry"
+)
+
+type ExpiryOptions struct {
+	// The clock. Default: time.Now.
+	Now func() time.Time
+
+	// Called with each expired item, without the lock held. Optional.
+	OnExpire func(x gocontainer.Comparable)
+
+	// Sweep the expired items in a background goroutine
+	// on every receive from SweepTick, or every SweepInterval
+	// if SweepTick is nil.
+	// If both are unset, expired items are only removed lazily,
+	// when the buffer is accessed, or when Sweep is called.
+	// The sweeper needs the buffer to be created with isSync = true.
+	SweepInterval time.Duration
+	SweepTick     <-chan time.Time
+}
+
+type tkbExpiry struct {
+	now      func() time.Time
+	onExpire func(x gocontainer.Comparable)
+	index    *expiry.Index // Deadlines, in a min-heap.
+	stop     func()        // Stop the sweeper.
+}
+
+// Item in the heap of a TopKBuffer with expiry enabled.
+type expiringItem struct {
+	x     gocontainer.Comparable
+	idx   int
+	entry *expiry.Entry // nil if no deadline.
+}
+
+func (ei *expiringItem) Less(another interface{}) bool {
+	return ei.x.Less(another.(*expiringItem).x)
+}
+
+func (ei *expiringItem) Index() int {
+	return ei.idx
+}
+
+func (ei *expiringItem) UpdateIndex(idx int) {
+	ei.idx = idx
+}
+
+func (ei *expiringItem) String() string {
+	if ei.entry == nil {
+		return fmt.Sprintf("%v", ei.x)
+	}
+	return fmt.Sprintf("%v (deadline: %v)", ei.x, ei.entry.Deadline)
+}
+
+// Return the item added by the user.
+func unwrap(x gocontainer.Comparable) gocontainer.Comparable {
+	if ei, ok := x.(*expiringItem); ok {
+		return ei.x
+	}
+	return x
+}
+
+// A background sweeper needs a lock, so it returns an error wrapping
+// gocontainer.ErrNotSync if opts sets one and isSync is false.
+func (tkb *TopKBuffer) enableExpiry(opts *ExpiryOptions, isSync bool) error {
+	if (opts.SweepTick != nil || opts.SweepInterval > 0) && !isSync {
+		return fmt.Errorf("%w: background sweeper needs a lock",
+			gocontainer.ErrNotSync)
+	}
+	tkb.exp = &tkbExpiry{
+		now:      opts.Now,
+		onExpire: opts.OnExpire,
+		index:    expiry.NewIndex(),
+	}
+	if tkb.exp.now == nil {
+		tkb.exp.now = time.Now
+	}
+	tkb.exp.stop = expiry.StartSweeper(opts.SweepTick, opts.SweepInterval,
+		func() { tkb.Sweep() })
+	return nil
+}
+
+// Same as Add, but x expires at deadline.
+// The buffer must be created with expiry enabled.
+func (tkb *TopKBuffer) AddWithDeadline(x gocontainer.Comparable,
+	deadline time.Time) {
+	if err := tkb.TryAddWithDeadline(x, deadline); err != nil {
+		panic(err)
+	}
+}
+
+// Same as AddWithDeadline, but return an error instead of panic.
+func (tkb *TopKBuffer) TryAddWithDeadline(x gocontainer.Comparable,
+	deadline time.Time) error {
+	if tkb.exp == nil {
+		return gocontainer.ErrExpiryDisabled
+	}
+	return tkb.add(x, &deadline)
+}
+
+// Same as Add, but x expires after ttl.
+// The buffer must be created with expiry enabled.
+func (tkb *TopKBuffer) AddWithTTL(x gocontainer.Comparable,
+	ttl time.Duration) {
+	if err := tkb.TryAddWithTTL(x, ttl); err != nil {
+		panic(err)
+	}
+}
+
+// Same as AddWithTTL, but return an error instead of panic.
+func (tkb *TopKBuffer) TryAddWithTTL(x gocontainer.Comparable,
+	ttl time.Duration) error {
+	if tkb.exp == nil {
+		return gocontainer.ErrExpiryDisabled
+	}
+	return tkb.TryAddWithDeadline(x, tkb.exp.now().Add(ttl))
+}
+
+// Remove the expired items, call ExpiryOptions.OnExpire with them,
+// and return the number of them.
+// Expired items are also removed lazily when the buffer is accessed,
+// so it's unnecessary to call it in most cases.
+func (tkb *TopKBuffer) Sweep() int {
+	if tkb == nil || tkb.exp == nil {
+		return 0
+	}
+	expired := tkb.popExpired()
+	if tkb.exp.onExpire != nil {
+		for _, x := range expired {
+			tkb.exp.onExpire(x)
+		}
+	}
+	return len(expired)
+}
+
+// Stop the background sweeper. Do nothing if there is no sweeper.
+func (tkb *TopKBuffer) StopSweeper() {
+	if tkb == nil || tkb.exp == nil {
+		return
+	}
+	tkb.exp.stop()
+}
+
+func (tkb *TopKBuffer) purgeExpired() {
+	if tkb.exp != nil && tkb.hasExpired() {
+		tkb.Sweep()
+	}
+}
+
+// Report whether an item has expired, with the read lock only.
+func (tkb *TopKBuffer) hasExpired() bool {
+	if tkb.lock != nil {
+		tkb.lock.RLock()
+		defer tkb.lock.RUnlock()
+	}
+	return tkb.exp.index.HasExpired(tkb.exp.now())
+}
+
+func (tkb *TopKBuffer) popExpired() []gocontainer.Comparable {
+	if tkb.lock != nil {
+		tkb.lock.Lock()
+		defer tkb.lock.Unlock()
+	}
+	var expired []gocontainer.Comparable
+	tkb.exp.index.PopExpired(tkb.exp.now(), func(item interface{}) {
+		ei := item.(*expiringItem)
+		ei.entry = nil
+		heap.Remove(tkb.h, ei.idx)
+		tkb.notify(gocontainer.EventEvict, ei.x, 0, nil)
+		expired = append(expired, ei.x)
+	})
+	if len(expired) > 0 {
+		tkb.checkIfDebug()
+	}
+	return expired
+}
+
+// Remove the deadline of x, which has left the heap.
+// Call it with the lock held.
+func (tkb *TopKBuffer) forget(x gocontainer.Comparable) {
+	if ei, ok := x.(*expiringItem); ok && ei.entry != nil {
+		tkb.exp.index.Remove(ei.entry)
+		ei.entry = nil
+	}
+}
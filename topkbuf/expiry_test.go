@@ -0,0 +1,82 @@
+package topkbuf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+func TestTopKBufferExpiry(t *testing.T) {
+	err := NewTopKBuffer(2, false).TryAddWithTTL(new(testElement1),
+		time.Second)
+	if !errors.Is(err, gocontainer.ErrExpiryDisabled) {
+		t.Fatalf("err(%v) is not ErrExpiryDisabled", err)
+	}
+
+	now := time.Unix(1000, 0)
+	var expired []gocontainer.Comparable
+	tkb := NewTopKBufferWithExpiry(3, true, &ExpiryOptions{
+		Now: func() time.Time {
+			return now
+		},
+		OnExpire: func(x gocontainer.Comparable) {
+			expired = append(expired, x)
+		},
+	})
+	tkb.SetDebug(true)
+	inputs := []testElement1{3, 0, 9, -4, 5}
+	tkb.AddWithTTL(&inputs[0], time.Second)
+	tkb.AddWithTTL(&inputs[1], time.Second) // Evicted below.
+	tkb.Add(&inputs[2])
+	tkb.AddWithTTL(&inputs[3], time.Second) // Not added.
+	tkb.AddWithTTL(&inputs[4], 2*time.Second)
+	t.Log("\n" + tkb.DebugString())
+	now = now.Add(time.Second)
+	if n := tkb.Len(); n != 2 {
+		t.Fatalf("Len(%d) != 2", n)
+	}
+	if len(expired) != 1 || expired[0] != &inputs[0] {
+		t.Fatalf("Expired items are wrong: %v", expired)
+	}
+	var n int
+	tkb.Scan(func(x gocontainer.Comparable) bool {
+		if _, ok := x.(*testElement1); !ok {
+			t.Errorf("Scan an item of type %T", x)
+		}
+		n++
+		return false
+	})
+	if n != 2 {
+		t.Fatalf("Scan %d items, want 2", n)
+	}
+	tkb.ResetK(1) // Pop 5, the smaller one.
+	now = now.Add(time.Hour)
+	outputs := tkb.Flush()
+	if len(outputs) != 1 || outputs[0] != &inputs[2] {
+		t.Fatalf("Flush: %v", outputs)
+	}
+	if len(expired) != 1 {
+		t.Fatalf("Popped item expired: %v", expired)
+	}
+}
+
+func TestTopKBufferExpiryDefaultOptions(t *testing.T) {
+	tkb := NewTopKBufferWithExpiry(2, false, nil)
+	if err := tkb.TryAddWithTTL(new(testElement1), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if n := tkb.Len(); n != 1 {
+		t.Fatalf("Len(%d) != 1", n)
+	}
+}
+
+func TestTopKBufferSweeperNotSync(t *testing.T) {
+	_, err := NewTopKBufferWithExpiryE(2, false, &ExpiryOptions{
+		SweepTick: make(chan time.Time),
+	})
+	if !errors.Is(err, gocontainer.ErrNotSync) {
+		t.Fatalf("err(%v) is not ErrNotSync", err)
+	}
+}
@@ -0,0 +1,54 @@
+package topkbuf
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+// Set the observer receiving the events of the buffer.
+// Set it to nil to stop observing.
+// Items pushed out by better ones, or expired, are reported as evicted.
+// Items not good enough to enter the buffer are reported as rejected.
+// Flush reports each item as dequeued.
+// See gocontainer.Observer for details.
+func (tkb *TopKBuffer) SetObserver(obs gocontainer.Observer) {
+	if tkb.lock != nil {
+		tkb.lock.Lock()
+		defer tkb.lock.Unlock()
+	}
+	tkb.obs = obs
+	var hasObs int32
+	if obs != nil {
+		hasObs = 1
+	}
+	atomic.StoreInt32(&tkb.hasObs, hasObs)
+}
+
+// Lock the buffer for writing, and return the time spent waiting for
+// the lock if there is an observer. tkb.lock must not be nil.
+func (tkb *TopKBuffer) writeLock() (wait time.Duration) {
+	if atomic.LoadInt32(&tkb.hasObs) == 0 {
+		tkb.lock.Lock()
+		return 0
+	}
+	start := time.Now()
+	tkb.lock.Lock()
+	return time.Since(start)
+}
+
+// Report an event to the observer if any. Call it with the lock held.
+// x must be unwrapped.
+func (tkb *TopKBuffer) notify(kind gocontainer.EventKind,
+	x gocontainer.Comparable, wait time.Duration, err error) {
+	if tkb.obs != nil {
+		tkb.obs.Observe(gocontainer.Event{
+			Kind:     kind,
+			Item:     x,
+			Len:      tkb.h.Len(),
+			LockWait: wait,
+			Err:      err,
+		})
+	}
+}
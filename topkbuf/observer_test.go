@@ -0,0 +1,57 @@
+package topkbuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/donyori/gocontainer"
+)
+
+type testObserver struct {
+	events []gocontainer.Event
+}
+
+func (to *testObserver) Observe(e gocontainer.Event) {
+	to.events = append(to.events, e)
+}
+
+func TestTopKBufferObserver(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tkb := NewTopKBufferWithExpiry(2, true, &ExpiryOptions{
+		Now: func() time.Time {
+			return now
+		},
+	})
+	obs := new(testObserver)
+	tkb.SetObserver(obs)
+	inputs := []testElement1{3, 0, 9, -4, 5}
+	tkb.AddWithTTL(&inputs[0], time.Second)
+	tkb.Add(&inputs[1])
+	tkb.Add(&inputs[2]) // Evict 0.
+	tkb.Add(&inputs[3]) // Rejected.
+	now = now.Add(time.Second)
+	tkb.Add(&inputs[4]) // 3 expires first.
+	tkb.Flush()
+	want := []struct {
+		kind gocontainer.EventKind
+		x    *testElement1
+	}{
+		{gocontainer.EventEnqueue, &inputs[0]},
+		{gocontainer.EventEnqueue, &inputs[1]},
+		{gocontainer.EventEvict, &inputs[1]},
+		{gocontainer.EventEnqueue, &inputs[2]},
+		{gocontainer.EventReject, &inputs[3]},
+		{gocontainer.EventEvict, &inputs[0]},
+		{gocontainer.EventEnqueue, &inputs[4]},
+		{gocontainer.EventDequeue, &inputs[4]},
+		{gocontainer.EventDequeue, &inputs[2]},
+	}
+	if len(obs.events) != len(want) {
+		t.Fatalf("Got %d events, want %d", len(obs.events), len(want))
+	}
+	for i, w := range want {
+		if e := obs.events[i]; e.Kind != w.kind || e.Item != w.x {
+			t.Errorf("Event %d: %v %v, want %v %v", i, e.Kind, e.Item, w.kind, *w.x)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package topkbuf
+
+import (
+	"github.com/donyori/gocontainer"
+	"github.com/donyori/gocontainer/psort"
+)
+
+// Return the n greatest items in descending order, like Flush,
+// or all the items if n > tkb.Len(). It doesn't modify the buffer.
+// The greatest items are at the bottom of the underlying min-heap,
+// so it takes O(k + n*log(n)) time.
+func (tkb *TopKBuffer) PeekN(n int) []gocontainer.Comparable {
+	if tkb == nil || n <= 0 {
+		return nil
+	}
+	xs := tkb.items()
+	if n > len(xs) {
+		n = len(xs)
+	}
+	xs = psort.TopK(xs, n)
+	psort.HeapSort(xs)
+	// Reverse to descending order.
+	for i, j := 0, len(xs)-1; i < j; i, j = i+1, j-1 {
+		xs[i], xs[j] = xs[j], xs[i]
+	}
+	return xs
+}
+
+// Return the k-th greatest item, and ok = false if k is out of
+// [1, tkb.Len()]. It doesn't modify the buffer.
+// O(k) average time, where k is the one of the buffer.
+func (tkb *TopKBuffer) Kth(k int) (x gocontainer.Comparable, ok bool) {
+	if tkb == nil || k <= 0 {
+		return
+	}
+	xs := tkb.items()
+	if k > len(xs) {
+		return // nil, false
+	}
+	psort.NthElement(xs, len(xs)-k)
+	return xs[len(xs)-k], true
+}
+
+// Return a copy of the items, unwrapped.
+func (tkb *TopKBuffer) items() []gocontainer.Comparable {
+	tkb.purgeExpired()
+	if tkb.lock != nil {
+		tkb.lock.RLock()
+		defer tkb.lock.RUnlock()
+	}
+	xs := make([]gocontainer.Comparable, 0, tkb.h.Len())
+	tkb.h.Scan(func(x gocontainer.Comparable) bool {
+		xs = append(xs, unwrap(x))
+		return false
+	})
+	return xs
+}
@@ -0,0 +1,35 @@
+package topkbuf
+
+import "testing"
+
+func TestTopKBufferPeekNAndKth(t *testing.T) {
+	tkb := NewTopKBufferWithExpiry(5, true, nil)
+	inputs := []testElement1{3, 0, 9, -4, 3, -5, 8, 1, 6}
+	for i := range inputs {
+		tkb.Add(&inputs[i])
+	}
+	want := []testElement1{9, 8, 6, 3, 3}
+	xs := tkb.PeekN(4)
+	if len(xs) != 4 {
+		t.Fatalf("len(PeekN(4)) = %d", len(xs))
+	}
+	for i, x := range xs {
+		if *x.(*testElement1) != want[i] {
+			t.Fatalf("PeekN(4)[%d] = %v != %v", i, *x.(*testElement1), want[i])
+		}
+	}
+	if xs = tkb.PeekN(10); len(xs) != 5 {
+		t.Fatalf("len(PeekN(10)) = %d", len(xs))
+	}
+	for k := 1; k <= 5; k++ {
+		if x, ok := tkb.Kth(k); !ok || *x.(*testElement1) != want[k-1] {
+			t.Errorf("Kth(%d) = %v, %t", k, x, ok)
+		}
+	}
+	if _, ok := tkb.Kth(6); ok {
+		t.Error("Kth(6) is ok")
+	}
+	if err := tkb.Validate(); err != nil || tkb.Len() != 5 {
+		t.Fatalf("The buffer is modified, err: %v", err)
+	}
+}
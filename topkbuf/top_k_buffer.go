@@ -4,36 +4,85 @@ import (
 	"container/heap"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/donyori/gocontainer"
 	iheap "github.com/donyori/gocontainer/internal/heap"
 )
 
 type TopKBuffer struct {
-	h    *iheap.MinHeap
-	k    int
-	lock *sync.RWMutex
+	h       *iheap.MinHeap
+	k       int
+	lock    *sync.RWMutex
+	isDebug bool
+	exp     *tkbExpiry // nil if expiry is not enabled.
+
+	obs    gocontainer.Observer // Optional. Set by SetObserver.
+	hasObs int32                // 1 if obs is not nil. Accessed atomically.
 }
 
 func NewTopKBuffer(k int, isSync bool) *TopKBuffer {
+	tkb, err := NewTopKBufferE(k, isSync)
+	if err != nil {
+		panic(err)
+	}
+	return tkb
+}
+
+// Same as NewTopKBuffer, but return an error instead of panic.
+func NewTopKBufferE(k int, isSync bool) (*TopKBuffer, error) {
+	return newTopKBuffer(k, isSync, nil)
+}
+
+// Same as NewTopKBuffer, with per-item expiry enabled.
+// opts can be nil for the default options.
+// Call StopSweeper to stop the background sweeper if opts sets one.
+func NewTopKBufferWithExpiry(k int, isSync bool,
+	opts *ExpiryOptions) *TopKBuffer {
+	tkb, err := NewTopKBufferWithExpiryE(k, isSync, opts)
+	if err != nil {
+		panic(err)
+	}
+	return tkb
+}
+
+// Same as NewTopKBufferWithExpiry, but return an error instead of panic.
+func NewTopKBufferWithExpiryE(k int, isSync bool, opts *ExpiryOptions) (
+	*TopKBuffer, error) {
+	if opts == nil {
+		opts = new(ExpiryOptions)
+	}
+	return newTopKBuffer(k, isSync, opts)
+}
+
+// Create a TopKBuffer. Expiry is enabled if opts is not nil.
+func newTopKBuffer(k int, isSync bool, opts *ExpiryOptions) (
+	*TopKBuffer, error) {
 	if k <= 0 {
-		panic(fmt.Errorf("gocontainer: k(%d) is non-positive", k))
+		return nil, newInvalidKError(k)
 	}
 	tkb := new(TopKBuffer)
 	if isSync {
 		tkb.lock = new(sync.RWMutex)
 	}
 	// Not necessary to lock during init.
-	tkb.h = iheap.NewMinHeap(k, false)
+	// With expiry, items are wrapped with their positions in the heap.
+	tkb.h = iheap.NewMinHeap(k, opts != nil)
 	tkb.k = k
 	heap.Init(tkb.h)
-	return tkb
+	if opts != nil {
+		if err := tkb.enableExpiry(opts, isSync); err != nil {
+			return nil, err
+		}
+	}
+	return tkb, nil
 }
 
 func (tkb *TopKBuffer) Len() int {
 	if tkb == nil {
 		return 0
 	}
+	tkb.purgeExpired()
 	if tkb.lock != nil {
 		tkb.lock.RLock()
 		defer tkb.lock.RUnlock()
@@ -64,36 +113,101 @@ func (tkb *TopKBuffer) K() int {
 }
 
 func (tkb *TopKBuffer) ResetK(k int) {
+	if err := tkb.TryResetK(k); err != nil {
+		panic(err)
+	}
+}
+
+// Same as ResetK, but return an error instead of panic.
+func (tkb *TopKBuffer) TryResetK(k int) error {
 	if k <= 0 {
-		panic(fmt.Errorf("gocontainer: k(%d) is non-positive", k))
+		return newInvalidKError(k)
 	}
 	if tkb.lock != nil {
 		tkb.lock.Lock()
 		defer tkb.lock.Unlock()
 	}
 	// Pop excess items.
-	for i := tkb.k - k; i > 0; i-- {
-		heap.Pop(tkb.h)
+	for tkb.h.Len() > k {
+		x := heap.Pop(tkb.h).(gocontainer.Comparable)
+		tkb.forget(x)
+		tkb.notify(gocontainer.EventEvict, unwrap(x), 0, nil)
 	}
 	// Set K.
 	tkb.k = k
+	tkb.checkIfDebug()
+	return nil
 }
 
 func (tkb *TopKBuffer) Add(x gocontainer.Comparable) {
+	if err := tkb.TryAdd(x); err != nil {
+		panic(err)
+	}
+}
+
+// Same as Add, but return an error instead of panic.
+// The buffer is not modified if it returns an error.
+func (tkb *TopKBuffer) TryAdd(x gocontainer.Comparable) error {
+	return tkb.add(x, nil)
+}
+
+// Add x. If deadline is not nil, x expires at *deadline.
+func (tkb *TopKBuffer) add(x gocontainer.Comparable,
+	deadline *time.Time) error {
+	if x == nil {
+		return gocontainer.ErrNilItem
+	}
+	tkb.purgeExpired()
+	var wait time.Duration
 	if tkb.lock != nil {
-		tkb.lock.Lock()
+		wait = tkb.writeLock()
 		defer tkb.lock.Unlock()
 	}
+	item := x
+	var ei *expiringItem
+	if tkb.exp != nil {
+		ei = &expiringItem{x: x}
+		item = ei
+	}
+	isAdded := true
+	var old gocontainer.Comparable
 	if tkb.h.Len() >= tkb.k {
-		if (*tkb.h).Top().Less(x) {
-			tkb.h.UpdateTop(x)
+		isLess, err := tryLess(tkb.h.Top(), item)
+		if err != nil {
+			tkb.notify(gocontainer.EventReject, x, wait, err)
+			return err
+		}
+		if isLess {
+			old = tkb.h.Top()
+			if err = iheap.TryUpdateTop(tkb.h, item); err != nil {
+				tkb.notify(gocontainer.EventReject, x, wait, err)
+				return err
+			}
+			tkb.forget(old)
+		} else {
+			isAdded = false
 		}
+	} else if err := iheap.TryPush(tkb.h, item); err != nil {
+		tkb.notify(gocontainer.EventReject, x, wait, err)
+		return err
+	}
+	if isAdded && ei != nil && deadline != nil {
+		ei.entry = tkb.exp.index.Add(ei, *deadline)
+	}
+	tkb.checkIfDebug()
+	if old != nil {
+		tkb.notify(gocontainer.EventEvict, unwrap(old), 0, nil)
+	}
+	if isAdded {
+		tkb.notify(gocontainer.EventEnqueue, x, wait, nil)
 	} else {
-		heap.Push(tkb.h, x)
+		tkb.notify(gocontainer.EventReject, x, wait, nil)
 	}
+	return nil
 }
 
 func (tkb *TopKBuffer) Flush() []gocontainer.Comparable {
+	tkb.purgeExpired()
 	if tkb.lock != nil {
 		tkb.lock.Lock()
 		defer tkb.lock.Unlock()
@@ -105,8 +219,13 @@ func (tkb *TopKBuffer) Flush() []gocontainer.Comparable {
 	xs := make([]gocontainer.Comparable, n)
 	// Output in reverse order, in order to let the biggest item at 0 position.
 	for i := n - 1; i >= 0; i-- {
-		xs[i] = heap.Pop(tkb.h).(gocontainer.Comparable)
+		xs[i] = unwrap(heap.Pop(tkb.h).(gocontainer.Comparable))
+		tkb.notify(gocontainer.EventDequeue, xs[i], 0, nil)
+	}
+	if tkb.exp != nil {
+		tkb.exp.index.Clear()
 	}
+	tkb.checkIfDebug()
 	return xs
 }
 
@@ -114,11 +233,18 @@ func (tkb *TopKBuffer) Scan(f func(x gocontainer.Comparable) (doesStop bool)) {
 	if tkb == nil || f == nil {
 		return
 	}
+	tkb.purgeExpired()
 	if tkb.lock != nil {
 		tkb.lock.RLock()
 		defer tkb.lock.RUnlock()
 	}
-	tkb.h.Scan(f)
+	if tkb.exp == nil {
+		tkb.h.Scan(f)
+		return
+	}
+	tkb.h.Scan(func(x gocontainer.Comparable) bool {
+		return f(unwrap(x))
+	})
 }
 
 func (tkb *TopKBuffer) Clear() {
@@ -126,6 +252,98 @@ func (tkb *TopKBuffer) Clear() {
 		tkb.lock.Lock()
 		defer tkb.lock.Unlock()
 	}
+	if tkb.obs != nil {
+		tkb.h.Scan(func(x gocontainer.Comparable) bool {
+			tkb.obs.Observe(gocontainer.Event{
+				Kind: gocontainer.EventRemove,
+				Item: unwrap(x),
+			})
+			return false
+		})
+	}
 	tkb.h.Reset(tkb.k)
 	heap.Init(tkb.h)
+	if tkb.exp != nil {
+		tkb.exp.index.Clear()
+	}
+	tkb.checkIfDebug()
+}
+
+// Set the debug mode. In debug mode, the buffer checks itself by Validate()
+// after every mutation, and panics if the check fails.
+func (tkb *TopKBuffer) SetDebug(isDebug bool) {
+	if tkb.lock != nil {
+		tkb.lock.Lock()
+		defer tkb.lock.Unlock()
+	}
+	tkb.isDebug = isDebug
+}
+
+// Check the heap property of the buffer. Return nil if the buffer is valid.
+func (tkb *TopKBuffer) Validate() error {
+	if tkb == nil {
+		return nil
+	}
+	if tkb.lock != nil {
+		tkb.lock.RLock()
+		defer tkb.lock.RUnlock()
+	}
+	return tkb.validate()
+}
+
+// Call it with the lock held.
+func (tkb *TopKBuffer) validate() error {
+	if n := tkb.h.Len(); n > tkb.k {
+		return fmt.Errorf("gocontainer: length(%d) is greater than k(%d)",
+			n, tkb.k)
+	}
+	return tkb.h.Validate()
+}
+
+// Return a human-readable dump of the underlying heap tree.
+func (tkb *TopKBuffer) DebugString() string {
+	if tkb == nil {
+		return "<nil>"
+	}
+	if tkb.lock != nil {
+		tkb.lock.RLock()
+		defer tkb.lock.RUnlock()
+	}
+	return tkb.h.DebugString()
+}
+
+// Return the underlying heap tree in Graphviz DOT language.
+func (tkb *TopKBuffer) DOT() string {
+	if tkb == nil {
+		return "digraph heap {\n}\n"
+	}
+	if tkb.lock != nil {
+		tkb.lock.RLock()
+		defer tkb.lock.RUnlock()
+	}
+	return tkb.h.DOT()
+}
+
+// Call it after each mutation, with the lock held.
+func (tkb *TopKBuffer) checkIfDebug() {
+	if !tkb.isDebug {
+		return
+	}
+	if err := tkb.validate(); err != nil {
+		panic(err)
+	}
+}
+
+// Return a.Less(b), or an error if it panics.
+func tryLess(a, b gocontainer.Comparable) (isLess bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", gocontainer.ErrIncomparable, r)
+		}
+	}()
+	return a.Less(b), nil
+}
+
+func newInvalidKError(k int) error {
+	return fmt.Errorf("%w: %d", gocontainer.ErrInvalidK, k)
 }
@@ -1,14 +1,19 @@
 package topkbuf
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/donyori/gocontainer"
 	"github.com/donyori/gorecover"
 )
 
 type testElement1 int
 type testElement2 float32
 
+// testPicky panics when 2 is compared with 100.
+type testPicky int
+
 func TestTopKBuffer(t *testing.T) {
 	err := gorecover.Recover(func() {
 		NewTopKBuffer(-2, false)
@@ -73,3 +78,108 @@ func (te *testElement2) Less(another interface{}) bool {
 	a := another.(*testElement2)
 	return a != nil && (te == nil || *te < *a)
 }
+
+func TestTopKBufferValidate(t *testing.T) {
+	inputs := []testElement1{3, 0, 9, -4, 3, -5, 8}
+	tkb := NewTopKBuffer(4, false)
+	tkb.SetDebug(true)
+	for i := range inputs {
+		tkb.Add(&inputs[i])
+	}
+	if err := tkb.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	t.Log("\n" + tkb.DebugString())
+	// Modify the smallest item in place after added.
+	var smallest *testElement1
+	tkb.Scan(func(x gocontainer.Comparable) bool {
+		if v := x.(*testElement1); smallest == nil || *v < *smallest {
+			smallest = v
+		}
+		return false
+	})
+	*smallest = 100
+	if err := tkb.Validate(); err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+	x := testElement1(50)
+	err := gorecover.Recover(func() {
+		tkb.Add(&x)
+	})
+	if err != nil {
+		t.Log(err)
+	} else {
+		t.Fatal("No error but should have one.")
+	}
+}
+
+func TestTopKBufferErrors(t *testing.T) {
+	_, err := NewTopKBufferE(0, false)
+	if !errors.Is(err, gocontainer.ErrInvalidK) {
+		t.Fatalf("err(%v) is not ErrInvalidK", err)
+	}
+	tkb, err := NewTopKBufferE(2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []testElement1{3, 0, 9}
+	var wrongInput testElement2 = 1.2
+	for i := range inputs {
+		if err = tkb.TryAdd(&inputs[i]); err != nil {
+			t.Fatal(err)
+		}
+		err = tkb.TryAdd(&wrongInput)
+		if !errors.Is(err, gocontainer.ErrIncomparable) {
+			t.Fatalf("err(%v) is not ErrIncomparable", err)
+		}
+	}
+	if err = tkb.TryAdd(nil); !errors.Is(err, gocontainer.ErrNilItem) {
+		t.Fatalf("err(%v) is not ErrNilItem", err)
+	}
+	if err = tkb.TryResetK(-1); !errors.Is(err, gocontainer.ErrInvalidK) {
+		t.Fatalf("err(%v) is not ErrInvalidK", err)
+	}
+	if err = tkb.TryResetK(1); err != nil {
+		t.Fatal(err)
+	}
+	if n := tkb.Len(); n != 1 {
+		t.Fatalf("Len(%d) != 1", n)
+	}
+	// Shrink K when the buffer is not full.
+	tkb.Clear()
+	tkb.ResetK(5)
+	tkb.Add(&inputs[0])
+	if err = tkb.TryResetK(3); err != nil {
+		t.Fatal(err)
+	}
+	if n := tkb.Len(); n != 1 {
+		t.Errorf("Len(%d) != 1 after shrinking K", n)
+	}
+}
+
+func (tp testPicky) Less(another interface{}) bool {
+	a := another.(testPicky)
+	if tp == 2 && a == 100 || tp == 100 && a == 2 {
+		panic("2 and 100 are incomparable")
+	}
+	return tp < a
+}
+
+func TestTopKBufferTryAddRecover(t *testing.T) {
+	tkb := NewTopKBuffer(3, false)
+	for _, x := range []testPicky{1, 2, 3} {
+		tkb.Add(x)
+	}
+	// 1 < 100, but 100 panics when it is moved down past 2.
+	err := tkb.TryAdd(testPicky(100))
+	if !errors.Is(err, gocontainer.ErrIncomparable) {
+		t.Fatalf("err(%v) is not ErrIncomparable", err)
+	}
+	xs := tkb.Flush()
+	if len(xs) != 3 || xs[0] != testPicky(3) || xs[1] != testPicky(2) ||
+		xs[2] != testPicky(1) {
+		t.Errorf("Flush: %v, want [3 2 1]", xs)
+	}
+}